@@ -0,0 +1,18 @@
+// Package cache stores encoded values by key for a limited time. The
+// in-memory LRU serves a single instance; a shared backend such as Redis
+// can be plugged in by implementing Cache.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache is a key value store with expiry. Implementations must be safe for
+// concurrent use. A backend that fails reports a miss rather than an error,
+// callers fall back to the source of the value.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration)
+	Delete(ctx context.Context, keys ...string)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	exportUsage = "export [-dir path] [collection ...]"
+	importUsage = "import [-drop] file.json ..."
+	// importBatch is how many documents import writes per round trip
+	importBatch = 500
+)
+
+// runExport writes each collection to <dir>/<collection>.json as an array of
+// canonical Extended JSON documents, which keeps ObjectIDs, dates and binary
+// data intact for import. Without collection names it exports every
+// collection.
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	dir := flags.String("dir", ".", "directory to write the files to")
+	flags.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	names := flags.Args()
+	if len(names) == 0 {
+		all, err := db.DB.ListCollectionNames(ctx, bson.M{"name": bson.M{"$not": bson.M{"$regex": "^system\\."}}})
+		if err != nil {
+			return err
+		}
+		names = all
+	}
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		path := filepath.Join(*dir, name+".json")
+		count, err := exportCollection(ctx, db.DB.Collection(name), path)
+		if err != nil {
+			return fmt.Errorf("export %s: %w", name, err)
+		}
+		fmt.Printf("exported %d documents from %s to %s\n", count, name, path)
+	}
+	return nil
+}
+
+func exportCollection(ctx context.Context, collection *mongo.Collection, path string) (int, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	w := bufio.NewWriter(file)
+	w.WriteString("[")
+	count := 0
+	for cursor.Next(ctx) {
+		doc, err := bson.MarshalExtJSON(cursor.Current, true, false)
+		if err != nil {
+			return count, err
+		}
+		if count > 0 {
+			w.WriteString(",")
+		}
+		w.WriteString("\n")
+		w.Write(doc)
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		return count, err
+	}
+	w.WriteString("\n]\n")
+	if err := w.Flush(); err != nil {
+		return count, err
+	}
+	return count, file.Close()
+}
+
+// runImport loads files written by export into the collection named after
+// each file. Documents replace those with the same _id, so importing twice
+// is harmless; -drop empties the collection first.
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	drop := flags.Bool("drop", false, "drop each collection before importing into it")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		return errors.New("usage: jobly " + importUsage)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	for _, path := range flags.Args() {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		collection := db.DB.Collection(name)
+		if *drop {
+			if err := collection.Drop(ctx); err != nil {
+				return fmt.Errorf("drop %s: %w", name, err)
+			}
+		}
+		count, err := importCollection(ctx, collection, path)
+		if err != nil {
+			return fmt.Errorf("import %s into %s: %w", path, name, err)
+		}
+		fmt.Printf("imported %d documents into %s\n", count, name)
+	}
+	return nil
+}
+
+func importCollection(ctx context.Context, collection *mongo.Collection, path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return 0, errors.New("expected a JSON array of documents")
+	}
+
+	count := 0
+	var writes []mongo.WriteModel
+	flush := func() error {
+		if len(writes) == 0 {
+			return nil
+		}
+		_, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return err
+		}
+		count += len(writes)
+		writes = writes[:0]
+		return nil
+	}
+
+	for decoder.More() {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return count, err
+		}
+		var doc bson.D
+		if err := bson.UnmarshalExtJSON(raw, true, &doc); err != nil {
+			return count, fmt.Errorf("document %d: %w", count+len(writes)+1, err)
+		}
+		id, ok := documentID(doc)
+		if !ok {
+			writes = append(writes, mongo.NewInsertOneModel().SetDocument(doc))
+		} else {
+			writes = append(writes, mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": id}).SetReplacement(doc).SetUpsert(true))
+		}
+		if len(writes) == importBatch {
+			if err := flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return count, err
+	}
+	return count, flush()
+}
+
+func documentID(doc bson.D) (interface{}, bool) {
+	for _, elem := range doc {
+		if elem.Key == "_id" {
+			return elem.Value, true
+		}
+	}
+	return nil, false
+}
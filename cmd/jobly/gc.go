@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/services"
+)
+
+const gcUsage = "gc files [-older-than duration]"
+
+func runGC(args []string) error {
+	if len(args) == 0 || args[0] != "files" {
+		return errors.New("usage: jobly " + gcUsage)
+	}
+
+	flags := flag.NewFlagSet("gc files", flag.ExitOnError)
+	// younger files may still be waiting for their document to be written
+	olderThan := flags.Duration("older-than", time.Hour, "only delete files uploaded longer ago than this")
+	flags.Parse(args[1:])
+	if *olderThan < 0 {
+		return errors.New("-older-than must not be negative")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	deleted, err := services.CollectOrphanFiles(ctx, time.Now().Add(-*olderThan))
+	fmt.Printf("deleted %d orphaned files\n", deleted)
+	return err
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/services"
+)
+
+const indexesUsage = "indexes ensure | rebuild"
+
+func runIndexes(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: jobly " + indexesUsage)
+	}
+
+	// building an index over a large collection takes a while
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	switch args[0] {
+	case "ensure":
+		if err := services.EnsureIndexes(ctx); err != nil {
+			return err
+		}
+		fmt.Printf("ensured %d indexes\n", len(services.Indexes))
+		return nil
+
+	case "rebuild":
+		if err := services.RebuildIndexes(ctx); err != nil {
+			return err
+		}
+		fmt.Printf("rebuilt %d indexes\n", len(services.Indexes))
+		return nil
+	}
+
+	return fmt.Errorf("unknown indexes command %q", args[0])
+}
@@ -0,0 +1,69 @@
+// Command jobly runs administrative and maintenance tasks against the Jobly
+// database.
+//
+//	jobly migrate up|down|status
+//	jobly user create|promote|reset-password
+//	jobly seed
+//	jobly indexes ensure|rebuild
+//	jobly gc files
+//	jobly export|import
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"migrate": {migrateUsage, runMigrate},
+	"user":    {userUsage, runUser},
+	"seed":    {seedUsage, runSeed},
+	"indexes": {indexesUsage, runIndexes},
+	"gc":      {gcUsage, runGC},
+	"export":  {exportUsage, runExport},
+	"import":  {importUsage, runImport},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: jobly <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(os.Stderr, "  jobly "+commands[name].usage)
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := db.DbConnection(); err != nil {
+		log.Fatalf("Error connecting to the database: %v", err)
+	}
+	err := cmd.run(os.Args[2:])
+	db.DbDisconnect()
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/migrations"
+)
+
+const migrateUsage = "migrate up [-to version] | down [-steps n] | status"
+
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: jobly " + migrateUsage)
+	}
+
+	// migrations stop between documents on Ctrl-C and release the lock
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	switch args[0] {
+	case "up":
+		flags := flag.NewFlagSet("migrate up", flag.ExitOnError)
+		to := flags.Int("to", 0, "apply migrations up to this version, all when 0")
+		flags.Parse(args[1:])
+
+		ran, err := migrations.Up(ctx, db.DB, *to)
+		for _, migration := range ran {
+			fmt.Printf("applied %d %s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(ran) == 0 {
+			fmt.Println("nothing to apply")
+		}
+		return err
+
+	case "down":
+		flags := flag.NewFlagSet("migrate down", flag.ExitOnError)
+		steps := flags.Int("steps", 1, "number of migrations to roll back")
+		flags.Parse(args[1:])
+		if *steps < 1 {
+			return errors.New("-steps must be at least 1")
+		}
+
+		ran, err := migrations.Down(ctx, db.DB, *steps)
+		for _, migration := range ran {
+			fmt.Printf("rolled back %d %s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(ran) == 0 {
+			fmt.Println("nothing to roll back")
+		}
+		return err
+
+	case "status":
+		statuses, err := migrations.GetStatus(ctx, db.DB)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, status := range statuses {
+			applied := "pending"
+			if status.Applied {
+				applied = status.AppliedAt.Local().Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", status.Version, status.Name, applied)
+		}
+		return w.Flush()
+	}
+
+	return fmt.Errorf("unknown migrate command %q", args[0])
+}
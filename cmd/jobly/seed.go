@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"github.com/weldonkipchirchir/job-listing-server/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const seedUsage = "seed [-jobs n] [-candidates n] [-password p]"
+
+type demoCompany struct {
+	Name      string
+	Industry  string
+	Location  string
+	Domain    string
+	Recruiter string
+	Currency  utils.Currency
+}
+
+type demoRole struct {
+	Title       string
+	Type        string
+	Description string
+	SalaryLow   int
+	SalaryHigh  int
+	Mandatory   []string
+	Optional    []string
+}
+
+var demoCompanies = []demoCompany{
+	{"Savannah Analytics", "Technology", "Nairobi, Kenya", "savannah-analytics.example", "Amina Otieno", utils.USD},
+	{"Lakeside Health", "Healthcare", "Kisumu, Kenya", "lakeside-health.example", "Brian Mwangi", utils.USD},
+	{"Northwind Logistics", "Logistics", "Rotterdam, Netherlands", "northwind-logistics.example", "Sophie de Vries", utils.EUR},
+	{"Bluepeak Finance", "Finance", "London, United Kingdom", "bluepeak-finance.example", "Oliver Hughes", utils.GBP},
+}
+
+var demoRoles = []demoRole{
+	{"Backend Engineer", "Full-time", "Design and run the services behind our web and mobile products.", 90000, 130000,
+		[]string{"Go", "MongoDB", "REST APIs"}, []string{"Docker", "Kubernetes"}},
+	{"Frontend Developer", "Full-time", "Build fast, accessible interfaces together with design and product.", 70000, 110000,
+		[]string{"JavaScript", "React", "CSS"}, []string{"TypeScript", "Accessibility"}},
+	{"Data Analyst", "Full-time", "Turn operational data into reports and insights for the leadership team.", 55000, 85000,
+		[]string{"SQL", "Python", "Excel"}, []string{"Tableau", "Statistics"}},
+	{"DevOps Engineer", "Contract", "Automate our infrastructure and keep deployments boring.", 95000, 140000,
+		[]string{"Linux", "Docker", "Terraform"}, []string{"AWS", "Prometheus"}},
+	{"Product Designer", "Full-time", "Own the user experience of a product area from research to release.", 65000, 100000,
+		[]string{"Figma", "Prototyping", "User research"}, []string{"HTML", "Design systems"}},
+	{"Customer Support Specialist", "Part-time", "Help customers get the most out of the product over chat and email.", 30000, 42000,
+		[]string{"Customer service", "Communication", "Zendesk"}, []string{"French", "CRM"}},
+	{"Android Developer", "Full-time", "Ship features to the Android app used by thousands of customers daily.", 75000, 115000,
+		[]string{"Kotlin", "Android", "Git"}, []string{"Firebase", "Jetpack Compose"}},
+	{"QA Engineer", "Contract", "Build the automated test suites that guard every release.", 50000, 80000,
+		[]string{"Selenium", "API testing", "Test automation"}, []string{"Go", "CI/CD"}},
+}
+
+var (
+	demoFirstNames = []string{"Wanjiru", "Kevin", "Fatuma", "Daniel", "Grace", "Samuel", "Aisha", "Peter", "Mercy", "Joseph", "Lucy", "Ahmed", "Esther", "David", "Nadia", "Tom"}
+	demoLastNames  = []string{"Kamau", "Ochieng", "Hassan", "Njoroge", "Achieng", "Kiprop", "Mohamed", "Wambui", "Mutua", "Cherono", "Smith", "Jansen"}
+	demoStatuses   = []string{models.StatusPending, models.StatusPending, "Reviewed", "Interview", models.StatusRejected}
+)
+
+// runSeed fills an empty database with demo recruiters, candidates, jobs,
+// applications and bookmarks. Every seeded account uses the same password.
+func runSeed(args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	jobCount := flags.Int("jobs", 20, "number of jobs to post")
+	candidateCount := flags.Int("candidates", 12, "number of candidate accounts")
+	password := flags.String("password", "demo-password", "password of every seeded account")
+	flags.Parse(args)
+	if *jobCount < 1 || *candidateCount < 1 {
+		return errors.New("-jobs and -candidates must be at least 1")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	seeded, err := db.DB.Collection("users").CountDocuments(ctx, bson.M{"email": "recruiter@" + demoCompanies[0].Domain})
+	if err != nil {
+		return err
+	}
+	if seeded > 0 {
+		return errors.New("the demo data is already seeded")
+	}
+
+	// hashing once instead of per account keeps seeding fast at bcrypt's cost
+	hashedPassword, err := services.HashPassword(*password)
+	if err != nil {
+		return err
+	}
+
+	random := rand.New(rand.NewSource(time.Now().UnixNano()))
+	now := time.Now().UTC()
+
+	recruiters := make([]models.User, len(demoCompanies))
+	for i, company := range demoCompanies {
+		recruiters[i] = models.User{
+			ID:       primitive.NewObjectID(),
+			Name:     company.Recruiter,
+			Email:    "recruiter@" + company.Domain,
+			Password: hashedPassword,
+			Role:     "admin",
+		}
+	}
+	candidates := make([]models.User, *candidateCount)
+	for i := range candidates {
+		first := demoFirstNames[i%len(demoFirstNames)]
+		last := demoLastNames[random.Intn(len(demoLastNames))]
+		candidates[i] = models.User{
+			ID:       primitive.NewObjectID(),
+			Name:     first + " " + last,
+			Email:    fmt.Sprintf("%s.%s%d@example.com", strings.ToLower(first), strings.ToLower(last), i+1),
+			Phone:    fmt.Sprintf("+2547%08d", random.Intn(100000000)),
+			Address:  demoCompanies[random.Intn(len(demoCompanies))].Location,
+			Password: hashedPassword,
+			Role:     "user",
+		}
+	}
+	users := make([]interface{}, 0, len(recruiters)+len(candidates))
+	for _, user := range append(recruiters, candidates...) {
+		users = append(users, user)
+	}
+	if _, err := db.DB.Collection("users").InsertMany(ctx, users); err != nil {
+		return err
+	}
+
+	jobs := make([]models.Job, *jobCount)
+	jobDocs := make([]interface{}, len(jobs))
+	revisions := make([]models.JobRevision, len(jobs))
+	for i := range jobs {
+		companyIndex := i % len(demoCompanies)
+		company := demoCompanies[companyIndex]
+		role := demoRoles[random.Intn(len(demoRoles))]
+		posted := now.Add(-time.Duration(random.Intn(30*24)) * time.Hour)
+		low := role.SalaryLow + random.Intn(10)*1000
+		high := role.SalaryHigh + random.Intn(10)*1000
+
+		job := models.Job{
+			ID:                    primitive.NewObjectIDFromTimestamp(posted),
+			JobName:               role.Title,
+			Type:                  role.Type,
+			Location:              company.Location,
+			SalaryHigh:            fmt.Sprint(high),
+			SalaryLow:             fmt.Sprint(low),
+			Company:               company.Name,
+			ImageLink:             "https://" + company.Domain + "/logo.png",
+			Sponsored:             i%5 == 0,
+			UserID:                recruiters[companyIndex].ID,
+			Currency:              company.Currency,
+			MandatoryRequirements: role.Mandatory,
+			OptionalRequirements:  role.Optional,
+			JobDescription:        company.Name + " is hiring. " + role.Description,
+			Industry:              company.Industry,
+			Version:               1,
+			UpdatedAt:             posted,
+		}
+		job.SalaryHighAmount = services.SalaryAmount(job.SalaryHigh)
+		job.SalaryLowAmount = services.SalaryAmount(job.SalaryLow)
+
+		revision, err := services.NewJobRevision(job, nil, models.RevisionCreated, job.UserID)
+		if err != nil {
+			return err
+		}
+		revision.CreatedAt = posted
+		jobs[i] = job
+		jobDocs[i] = job
+		revisions[i] = revision
+	}
+	if _, err := db.DB.Collection("jobs").InsertMany(ctx, jobDocs); err != nil {
+		return err
+	}
+	if err := services.RecordJobRevisions(ctx, revisions...); err != nil {
+		return err
+	}
+
+	var applications, bookmarks []interface{}
+	for _, candidate := range candidates {
+		// candidates apply to a few jobs that fit their background and
+		// bookmark a few others
+		role := demoRoles[random.Intn(len(demoRoles))]
+		skills := append(append([]string{}, role.Mandatory...), role.Optional[random.Intn(len(role.Optional))])
+		if random.Intn(3) == 0 {
+			// some candidates miss a requirement, which shows in their match
+			skills = skills[1:]
+		}
+		resume := demoResume(candidate, role, skills)
+
+		picked := random.Perm(len(jobs))
+		applyCount := 1 + random.Intn(3)
+		for _, index := range picked[:min(applyCount, len(jobs))] {
+			job := jobs[index]
+			applied := job.ID.Timestamp().Add(time.Duration(1+random.Intn(72)) * time.Hour)
+			if applied.After(now) {
+				applied = now
+			}
+
+			stored, err := services.StoreResume(ctx, models.PDF{
+				Filename:    strings.ReplaceAll(candidate.Name, " ", "_") + "_resume.pdf",
+				ContentType: "application/pdf",
+				Data:        resume,
+			})
+			if err != nil {
+				return err
+			}
+			application := models.Application{
+				ID:              primitive.NewObjectIDFromTimestamp(applied),
+				JobID:           job.ID,
+				JobName:         job.JobName,
+				UserID:          candidate.ID,
+				Name:            candidate.Name,
+				Status:          demoStatuses[random.Intn(len(demoStatuses))],
+				Resume:          stored,
+				Email:           candidate.Email,
+				Company:         job.Company,
+				JobVersion:      job.Version,
+				Version:         1,
+				StatusChangedAt: &applied,
+			}
+			if text, match, err := services.AnalyzeResume(resume, job); err == nil {
+				application.ResumeText = text
+				application.Match = &match
+			}
+			applications = append(applications, application)
+		}
+
+		for _, index := range picked[min(applyCount, len(jobs)):min(applyCount+random.Intn(4), len(jobs))] {
+			bookmarks = append(bookmarks, models.Bookmark{
+				ID:     primitive.NewObjectID(),
+				JobID:  jobs[index].ID,
+				UserID: candidate.ID,
+			})
+		}
+	}
+	if _, err := db.DB.Collection("applications").InsertMany(ctx, applications); err != nil {
+		return err
+	}
+	if len(bookmarks) > 0 {
+		if _, err := db.DB.Collection("bookmarks").InsertMany(ctx, bookmarks); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("seeded %d recruiters, %d candidates, %d jobs, %d applications and %d bookmarks\n",
+		len(recruiters), len(candidates), len(jobs), len(applications), len(bookmarks))
+	for _, recruiter := range recruiters {
+		fmt.Printf("  admin %s\n", recruiter.Email)
+	}
+	fmt.Printf("  candidates %s ... %s\n", candidates[0].Email, candidates[len(candidates)-1].Email)
+	fmt.Printf("  password %s\n", *password)
+	return nil
+}
+
+// demoResume renders a short resume for candidate as a one page PDF that
+// the resume text extraction can read.
+func demoResume(candidate models.User, role demoRole, skills []string) []byte {
+	lines := []string{
+		candidate.Name,
+		candidate.Email + "  " + candidate.Phone,
+		candidate.Address,
+		"",
+		role.Title,
+		"Skills: " + strings.Join(skills, ", "),
+		"",
+		"Experience",
+		"2021 - present  " + role.Title + " at a growing startup",
+		"2018 - 2021  Junior " + strings.ToLower(role.Title) + " at a consultancy",
+		"",
+		"Education",
+		"BSc, University of Nairobi",
+	}
+
+	var content bytes.Buffer
+	content.WriteString("BT /F1 11 Tf 14 TL 50 760 Td\n")
+	for _, line := range lines {
+		escaped := strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(line)
+		fmt.Fprintf(&content, "(%s) Tj T*\n", escaped)
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = pdf.Len()
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return pdf.Bytes()
+}
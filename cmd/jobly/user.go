@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const userUsage = "user create -name n -email e [-role user|admin|operator] [-password p] | promote -email e | reset-password -email e [-password p]"
+
+// cliAudit is who the audit log names for changes made with jobly.
+var cliAudit = services.AuditMeta{Actor: models.AuditActor{Role: "cli"}}
+
+// roles are the account roles: candidates are users, recruiters admins.
+// Operators run the platform and read the whole audit log.
+var roles = map[string]bool{"admin": true, "user": true, "operator": true}
+
+func runUser(args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: jobly " + userUsage)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch args[0] {
+	case "create":
+		flags := flag.NewFlagSet("user create", flag.ExitOnError)
+		name := flags.String("name", "", "full name")
+		email := flags.String("email", "", "email address used to log in")
+		role := flags.String("role", "user", "user, admin or operator")
+		password := flags.String("password", "", "password, generated and printed when empty")
+		flags.Parse(args[1:])
+
+		if len(strings.TrimSpace(*name)) < 3 || *email == "" {
+			return errors.New("-name of at least 3 characters and -email are required")
+		}
+		if !roles[*role] {
+			return fmt.Errorf("unknown role %q, use user, admin or operator", *role)
+		}
+		generated, err := passwordOrGenerate(password)
+		if err != nil {
+			return err
+		}
+
+		user := models.User{
+			ID:       primitive.NewObjectID(),
+			Name:     strings.TrimSpace(*name),
+			Email:    *email,
+			Password: *password,
+			Role:     *role,
+		}
+		if err := services.RegisterUser(&user); err != nil {
+			if err == services.ErrEmailTaken {
+				return fmt.Errorf("%s is already registered", *email)
+			}
+			return err
+		}
+		fmt.Printf("created %s %s (%s)\n", user.Role, user.Email, user.ID.Hex())
+		if generated {
+			fmt.Printf("password: %s\n", *password)
+		}
+		return nil
+
+	case "promote":
+		flags := flag.NewFlagSet("user promote", flag.ExitOnError)
+		email := flags.String("email", "", "email address of the account")
+		flags.Parse(args[1:])
+		if *email == "" {
+			return errors.New("-email is required")
+		}
+
+		before, err := services.SetUserRole(ctx, *email, "admin")
+		if err != nil {
+			return userError(err, *email)
+		}
+		if before.Role == "admin" {
+			fmt.Printf("%s is already an admin\n", *email)
+			return nil
+		}
+		recordAudit(ctx, models.AuditUserRoleChange, before.ID,
+			bson.M{"role": before.Role}, bson.M{"role": "admin"})
+		fmt.Printf("promoted %s to admin\n", *email)
+		return nil
+
+	case "reset-password":
+		flags := flag.NewFlagSet("user reset-password", flag.ExitOnError)
+		email := flags.String("email", "", "email address of the account")
+		password := flags.String("password", "", "new password, generated and printed when empty")
+		flags.Parse(args[1:])
+		if *email == "" {
+			return errors.New("-email is required")
+		}
+		generated, err := passwordOrGenerate(password)
+		if err != nil {
+			return err
+		}
+
+		before, err := services.ResetPassword(ctx, *email, *password)
+		if err != nil {
+			return userError(err, *email)
+		}
+		// the audit log records that the password changed, never the hash
+		recordAudit(ctx, models.AuditUserUpdate, before.ID, nil, bson.M{"passwordChanged": true})
+		fmt.Printf("reset the password of %s\n", *email)
+		if generated {
+			fmt.Printf("password: %s\n", *password)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown user command %q", args[0])
+}
+
+func userError(err error, email string) error {
+	if err == services.ErrUserNotFound {
+		return fmt.Errorf("no account with email %s", email)
+	}
+	return err
+}
+
+// passwordOrGenerate fills in a random password when none was given and
+// reports whether it did.
+func passwordOrGenerate(password *string) (bool, error) {
+	if *password != "" {
+		return false, nil
+	}
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return false, err
+	}
+	*password = base64.RawURLEncoding.EncodeToString(buf)
+	return true, nil
+}
+
+func recordAudit(ctx context.Context, action string, userID primitive.ObjectID, before, after interface{}) {
+	if err := services.RecordAudit(ctx, cliAudit, action, models.AuditTargetUser, userID, before, after); err != nil {
+		log.Println("error writing audit log", action, userID.Hex(), err)
+	}
+}
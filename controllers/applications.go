@@ -3,6 +3,7 @@ package controllers
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"github.com/weldonkipchirchir/job-listing-server/db"
 	"github.com/weldonkipchirchir/job-listing-server/handler"
 	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
 	"github.com/weldonkipchirchir/job-listing-server/utils"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -70,55 +72,224 @@ func (ah *ApplicationHandler) CreateApplications(c *gin.Context) {
 		return
 	}
 
-	var application models.Application
-	if err := c.BindJSON(&application); err != nil {
+	var request models.ApplicationRequest
+	if err := c.BindJSON(&request); err != nil {
 		ah.errorHandler.HandleBadRequest(c)
 		return
 	}
+	application := models.Application{
+		JobID:   request.JobID,
+		Resume:  request.Resume,
+		Answers: request.Answers,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var job models.Job
+	err = db.DB.Collection("jobs").FindOne(ctx, bson.M{"_id": application.JobID}).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ah.errorHandler.HandleNotFound(c)
+			return
+		}
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	if job.DeletedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Job is no longer accepting applications"})
+		return
+	}
+
+	// Check for an earlier application before storing the resume; the
+	// unique index catches concurrent ones at insert
+	count, err := ah.Collection.CountDocuments(ctx, blockingApplications(objectId, job.ID))
+	if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	if count > 0 {
+		ah.applicationConflict(ctx, c, objectId, job.ID)
+		return
+	}
+
+	// Make sure the posting has a revision the application can point at
+	if err := services.RecordJobBaseline(ctx, &job); err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
 
 	application.ID = primitive.NewObjectID()
 	application.UserID = objectId
 	application.Email = email
 	application.Name = name
+	application.JobName = job.JobName
+	application.Company = job.Company
+	application.JobVersion = job.Version
+	application.Version = 1
+	application.Status = models.StatusPending
+
+	// Check the screening answers and apply knockouts
+	answers, screening := services.EvaluateScreening(job.ScreeningQuestions, application.Answers)
+	if len(screening.Problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid screening answers", "details": screening.Problems})
+		return
+	}
+	application.Answers = answers
+	application.KnockoutReasons = screening.KnockoutReasons
+	if len(screening.KnockoutReasons) > 0 {
+		if job.KnockoutAction == models.KnockoutFlag {
+			application.Status = models.StatusFlagged
+			application.Flagged = true
+		} else {
+			application.Status = models.StatusRejected
+		}
+	}
+	now := time.Now().UTC()
+	application.StatusChangedAt = &now
+
+	resumeStored := false
+	if request.UseProfile {
+		var profile models.Profile
+		err = db.DB.Collection("profiles").FindOne(ctx, bson.M{"userId": objectId}).Decode(&profile)
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Create a profile before applying with it"})
+			return
+		} else if err != nil {
+			ah.errorHandler.HandleInternalServerError(c)
+			return
+		}
 
-	statusCapitalize := utils.CapitalizeFirstLetter(application.Status)
-	application.Status = statusCapitalize
+		application.ProfileSnapshot = &models.ProfileSnapshot{
+			ProfileID:   profile.ID,
+			Headline:    profile.Headline,
+			Summary:     profile.Summary,
+			Skills:      profile.Skills,
+			WorkHistory: profile.WorkHistory,
+			Education:   profile.Education,
+			Links:       profile.Links,
+			TakenAt:     time.Now().UTC(),
+		}
 
-	// Store the resume PDF in GridFS
-	bucket, err := gridfs.NewBucket(ah.Collection.Database())
+		// Without an uploaded resume, apply with a copy of the profile's default
+		// resume so the application keeps it even if the profile changes
+		if len(application.Resume.Data) == 0 {
+			if profile.DefaultResume == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Upload a resume or add a default resume to your profile"})
+				return
+			}
+			profileFileID, err := services.ResumeFileID(*profile.DefaultResume)
+			if err != nil {
+				ah.errorHandler.HandleInternalServerError(c)
+				return
+			}
+			fileID, err := services.CopyFile(ctx, profileFileID, profile.DefaultResume.Filename)
+			if err != nil {
+				ah.errorHandler.HandleInternalServerError(c)
+				return
+			}
+			application.Resume = models.PDF{
+				Filename:    profile.DefaultResume.Filename,
+				ContentType: profile.DefaultResume.ContentType,
+				Data:        []byte(fileID.Hex()), // Storing the ObjectID as a string
+			}
+			resumeStored = true
+		}
+	}
+
+	// Store the resume PDF in GridFS unless it was copied from the profile
+	if !resumeStored {
+		if len(application.Resume.Data) == 0 {
+			ah.errorHandler.HandleBadRequest(c)
+			return
+		}
+		application.Resume, err = services.StoreResume(ctx, application.Resume)
+		if err != nil {
+			ah.errorHandler.HandleInternalServerError(c)
+			return
+		}
+	}
+
+	// The stored file is removed again if the application is never inserted
+	resumeFileID, err := services.ResumeFileID(application.Resume)
 	if err != nil {
 		ah.errorHandler.HandleInternalServerError(c)
 		return
 	}
-
-	uploadStream, err := bucket.OpenUploadStream(application.Resume.Filename)
-	if err != nil {
+	if err := services.GuardUpload(ctx, resumeFileID); err != nil {
+		services.DeleteFile(ctx, resumeFileID)
 		ah.errorHandler.HandleInternalServerError(c)
 		return
 	}
-	defer uploadStream.Close()
 
-	_, err = uploadStream.Write(application.Resume.Data)
+	err = services.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		if _, err := ah.Collection.InsertOne(sc, application); err != nil {
+			return err
+		}
+		if err := services.NotifyNewApplication(sc, job, application); err != nil {
+			return err
+		}
+		// the resume is read and scored against the job in the background
+		if err := services.QueueResumeAnalysis(sc, application.ID, resumeFileID); err != nil {
+			return err
+		}
+		return services.QueueWebhookEvent(sc, job.UserID, models.EventApplicationSubmitted, services.ApplicationWebhookData(&job, application))
+	})
 	if err != nil {
+		services.DeleteFile(ctx, resumeFileID)
+		if mongo.IsDuplicateKeyError(err) {
+			ah.applicationConflict(ctx, c, objectId, job.ID)
+			return
+		}
 		ah.errorHandler.HandleInternalServerError(c)
 		return
 	}
 
-	application.Resume = models.PDF{
-		Filename:    application.Resume.Filename,
-		ContentType: application.Resume.ContentType,
-		Data:        []byte(uploadStream.FileID.(primitive.ObjectID).Hex()), // Storing the ObjectID as a string
-	}
+	c.JSON(http.StatusCreated, applicationUserResponse(application))
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+// blockingApplications matches the applications that keep a candidate from
+// applying to a job again: a live one, or one they deleted themselves and
+// can restore. One a recruiter deleted does not count.
+func blockingApplications(userID, jobID primitive.ObjectID) bson.M {
+	return bson.M{
+		"userId": userID,
+		"jobId":  jobID,
+		"$or": bson.A{
+			bson.M{"deletedAt": bson.M{"$exists": false}},
+			bson.M{"deletedBy": userID},
+		},
+	}
+}
 
-	_, err = ah.Collection.InsertOne(ctx, application)
+// applicationConflict answers a duplicate application with the existing
+// one. An application in the trash has to be restored instead.
+func (ah *ApplicationHandler) applicationConflict(ctx context.Context, c *gin.Context, userID, jobID primitive.ObjectID) {
+	var existing models.Application
+	err := ah.Collection.FindOne(ctx, blockingApplications(userID, jobID)).Decode(&existing)
 	if err != nil {
 		ah.errorHandler.HandleInternalServerError(c)
 		return
 	}
-	c.JSON(http.StatusCreated, application)
+
+	message := "You already applied to this job"
+	if existing.DeletedAt != nil {
+		message = "You deleted your application to this job, restore it instead"
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": message, "application": applicationUserResponse(existing)})
+}
+
+// applicationUserResponse is what a candidate sees of their application,
+// without the recruiters' notes, ratings and screening results.
+func applicationUserResponse(application models.Application) models.ApplicationUserResponse {
+	return models.ApplicationUserResponse{
+		ID:         application.ID,
+		JobID:      application.JobID,
+		Status:     application.Status,
+		JobName:    application.JobName,
+		Company:    application.Company,
+		JobVersion: application.JobVersion,
+	}
 }
 
 func (ah *ApplicationHandler) GetAdminApplications(c *gin.Context) {
@@ -152,7 +323,7 @@ func (ah *ApplicationHandler) GetAdminApplications(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
 
-	filter := bson.M{"userId": objectId}
+	filter := services.NotDeleted(bson.M{"userId": objectId})
 	cursor, err := db.DB.Collection("jobs").Find(ctx, filter)
 	if err != nil {
 		ah.errorHandler.HandleInternalServerError(c)
@@ -174,13 +345,19 @@ func (ah *ApplicationHandler) GetAdminApplications(c *gin.Context) {
 
 	// Extract job IDs
 	var jobIDs []primitive.ObjectID
-	for _, job := range jobs {
+	jobsByID := map[primitive.ObjectID]*models.Job{}
+	for i, job := range jobs {
 		jobIDs = append(jobIDs, job.ID)
+		jobsByID[job.ID] = &jobs[i]
 	}
 
 	// Query applications for the user's jobs
-	appFilter := bson.M{"jobId": bson.M{"$in": jobIDs}}
-	appCursor, err := ah.Collection.Find(ctx, appFilter)
+	appFilter := services.NotDeleted(bson.M{"jobId": bson.M{"$in": jobIDs}})
+	opts, ok := ah.applicationListOptions(c, appFilter)
+	if !ok {
+		return
+	}
+	appCursor, err := ah.Collection.Find(ctx, appFilter, opts)
 	if err != nil {
 		ah.errorHandler.HandleInternalServerError(c)
 		return
@@ -208,6 +385,11 @@ func (ah *ApplicationHandler) GetAdminApplications(c *gin.Context) {
 		return
 	}
 	for _, application := range applications {
+		// masked applications come without the resume file
+		if services.IdentityHidden(jobsByID[application.JobID], application) {
+			application = services.MaskApplication(application)
+		}
+
 		var resumeData []byte
 		if application.Resume.Data != nil {
 			resumeID, err := primitive.ObjectIDFromHex(string(application.Resume.Data))
@@ -234,13 +416,24 @@ func (ah *ApplicationHandler) GetAdminApplications(c *gin.Context) {
 		}
 
 		applicationResponse := models.ApplicationAdminResponse{
-			ID:      application.ID,
-			JobID:   application.JobID,
-			Resume:  models.PDF{Filename: application.Resume.Filename, ContentType: application.Resume.ContentType, Data: resumeData}, // Directly assign PDF data
-			Name:    application.Name,
-			Email:   application.Email,
-			Status:  application.Status,
-			JobName: application.JobName,
+			ID:              application.ID,
+			JobID:           application.JobID,
+			Resume:          models.PDF{Filename: application.Resume.Filename, ContentType: application.Resume.ContentType, Data: resumeData}, // Directly assign PDF data
+			Name:            application.Name,
+			Email:           application.Email,
+			Status:          application.Status,
+			JobName:         application.JobName,
+			Answers:         application.Answers,
+			KnockoutReasons: application.KnockoutReasons,
+			Flagged:         application.Flagged,
+			ProfileSnapshot: application.ProfileSnapshot,
+			Match:           application.Match,
+			Notes:           application.Notes,
+			Tags:            application.Tags,
+			Scorecards:      application.Scorecards,
+			AverageRating:   application.AverageRating,
+			Version:         application.Version,
+			Blind:           application.Blind,
 		}
 		applicationResponses = append(applicationResponses, applicationResponse)
 	}
@@ -276,7 +469,7 @@ func (ah *ApplicationHandler) GetApplications(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
 
-	filter := bson.M{"userId": objectId}
+	filter := services.NotDeleted(bson.M{"userId": objectId})
 	cursor, err := ah.Collection.Find(ctx, filter)
 	if err != nil {
 		ah.errorHandler.HandleInternalServerError(c)
@@ -293,14 +486,7 @@ func (ah *ApplicationHandler) GetApplications(c *gin.Context) {
 	var applicationResponses []models.ApplicationUserResponse
 
 	for _, application := range applications {
-		applicationResponse := models.ApplicationUserResponse{
-			ID:      application.ID,
-			JobID:   application.JobID,
-			Status:  application.Status,
-			JobName: application.JobName,
-			Company: application.Company,
-		}
-		applicationResponses = append(applicationResponses, applicationResponse)
+		applicationResponses = append(applicationResponses, applicationUserResponse(application))
 	}
 
 	c.JSON(http.StatusOK, applicationResponses)
@@ -337,14 +523,14 @@ func (ah *ApplicationHandler) EditApplication(c *gin.Context) {
 		return
 	}
 
-	_, err = primitive.ObjectIDFromHex(userIdStr)
+	adminObjectId, err := primitive.ObjectIDFromHex(userIdStr)
 	if err != nil {
 		ah.errorHandler.HandleBadRequest(c)
 		return
 	}
 
 	var application models.Application
-	filter := bson.M{"_id": objectID}
+	filter := services.NotDeleted(bson.M{"_id": objectID})
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -358,6 +544,23 @@ func (ah *ApplicationHandler) EditApplication(c *gin.Context) {
 		return
 	}
 
+	// recruiters only edit the applications to their own jobs
+	count, err := db.DB.Collection("jobs").CountDocuments(ctx, bson.M{"_id": application.JobID, "userId": adminObjectId})
+	if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	if count == 0 {
+		ah.errorHandler.HandleUnauthorized(c)
+		return
+	}
+
+	// If-Match makes sure the edit is based on the version being replaced
+	if !ifMatch(c, application.Version) {
+		respondPreconditionFailed(c, application.Version)
+		return
+	}
+
 	var updateApplication models.Application
 	if err := c.BindJSON(&updateApplication); err != nil {
 		ah.errorHandler.HandleBadRequest(c)
@@ -378,28 +581,24 @@ func (ah *ApplicationHandler) EditApplication(c *gin.Context) {
 	if updateApplication.Resume.ContentType != "" {
 		updateFields["resume.contentType"] = updateApplication.Resume.ContentType
 	}
+	// The new resume file and the old one, which is deleted once the new
+	// one is in place
+	var newFileID, oldFileID primitive.ObjectID
 	if len(updateApplication.Resume.Data) > 0 {
-		// If new resume data is provided, update it
-		bucket, err := gridfs.NewBucket(ah.Collection.Database())
+		newFileID, err = services.UploadFile(ctx, updateApplication.Resume.Filename, updateApplication.Resume.Data)
 		if err != nil {
 			ah.errorHandler.HandleInternalServerError(c)
 			return
 		}
-
-		uploadStream, err := bucket.OpenUploadStream(updateApplication.Resume.Filename)
-		if err != nil {
+		if err := services.GuardUpload(ctx, newFileID); err != nil {
+			services.DeleteFile(ctx, newFileID)
 			ah.errorHandler.HandleInternalServerError(c)
 			return
 		}
-		defer uploadStream.Close()
-
-		_, err = uploadStream.Write(updateApplication.Resume.Data)
-		if err != nil {
-			ah.errorHandler.HandleInternalServerError(c)
-			return
+		updateFields["resume.data"] = []byte(newFileID.Hex())
+		if fileID, err := services.ResumeFileID(application.Resume); err == nil {
+			oldFileID = fileID
 		}
-
-		updateFields["resume.data"] = uploadStream.FileID.(primitive.ObjectID).Hex()
 	}
 	if updateApplication.Email != "" {
 		updateFields["email"] = updateApplication.Email
@@ -411,14 +610,139 @@ func (ah *ApplicationHandler) EditApplication(c *gin.Context) {
 		updateFields["jobName"] = updateApplication.JobName
 	}
 
-	update := bson.M{"$set": updateFields}
-	_, err = ah.Collection.UpdateOne(ctx, filter, update)
+	status, statusChanged := updateFields["status"].(string)
+	statusChanged = statusChanged && status != application.Status
+	if statusChanged {
+		updateFields["statusChangedAt"] = time.Now().UTC()
+	}
+
+	var job models.Job
+	if statusChanged {
+		err = db.DB.Collection("jobs").FindOne(ctx, bson.M{"_id": application.JobID}).Decode(&job)
+		if err != nil && err != mongo.ErrNoDocuments {
+			services.DeleteFile(ctx, newFileID)
+			ah.errorHandler.HandleInternalServerError(c)
+			return
+		}
+	}
+
+	// reaching or leaving the reveal status of a blind review reveals the
+	// candidate
+	changed := application
+	revealed := false
+	if statusChanged {
+		changed.Status = status
+		if services.RevealsIdentity(&job, application, status) {
+			now := time.Now().UTC()
+			updateFields["identityRevealedAt"] = now
+			updateFields["identityRevealedBy"] = adminObjectId
+			changed.IdentityRevealedAt = &now
+			revealed = true
+		}
+	}
+
+	// the update only applies to the version that was read, so a concurrent
+	// edit is refused instead of overwritten
+	versionFilter := services.AtVersion(services.NotDeleted(bson.M{"_id": objectID}), application.Version)
+	update := bson.M{"$set": updateFields, "$inc": bson.M{"version": 1}}
+	if !newFileID.IsZero() {
+		// the text and match of the old resume go, the new one is scored in
+		// the background
+		update["$unset"] = bson.M{"resumeText": "", "match": ""}
+	}
+	err = services.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		result, err := ah.Collection.UpdateOne(sc, versionFilter, update)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return services.ErrVersionConflict
+		}
+		if !oldFileID.IsZero() {
+			if err := services.QueueFileDeletion(sc, oldFileID); err != nil {
+				return err
+			}
+		}
+		if !newFileID.IsZero() {
+			if err := services.QueueResumeAnalysis(sc, objectID, newFileID); err != nil {
+				return err
+			}
+		}
+		if !statusChanged {
+			return nil
+		}
+		if err := services.NotifyStatusChange(sc, application, status); err != nil {
+			return err
+		}
+		if job.ID.IsZero() {
+			return nil
+		}
+		data := services.ApplicationWebhookData(&job, changed)
+		data["previousStatus"] = application.Status
+		return services.QueueWebhookEvent(sc, job.UserID, models.EventApplicationStatusChanged, data)
+	})
 	if err != nil {
+		if !newFileID.IsZero() {
+			services.DeleteFile(ctx, newFileID)
+		}
+		if errors.Is(err, services.ErrVersionConflict) {
+			ah.applicationVersionConflict(ctx, c, objectID)
+			return
+		}
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	current := applicationAuditFields(application)
+	updated := bson.M{}
+	for key, value := range updateFields {
+		if _, ok := current[key]; ok {
+			updated[key] = value
+		}
+	}
+	before, after := changedFields(current, updated)
+	action := models.AuditApplicationUpdate
+	if statusChanged {
+		action = models.AuditApplicationStatusChange
+	}
+	audit(ctx, c, action, models.AuditTargetApplication, application.ID, before, after)
+	if revealed {
+		audit(ctx, c, models.AuditApplicationReveal, models.AuditTargetApplication, application.ID, nil, bson.M{"trigger": "status", "status": status})
+	}
+
+	c.Header("ETag", versionETag(application.Version+1))
+	c.JSON(http.StatusOK, gin.H{"message": "application updated", "version": application.Version + 1})
+}
+
+// applicationVersionConflict answers an edit that lost the race against
+// another write with the application's current version, or 404 if it was
+// deleted meanwhile.
+func (ah *ApplicationHandler) applicationVersionConflict(ctx context.Context, c *gin.Context, applicationID primitive.ObjectID) {
+	var current models.Application
+	err := ah.Collection.FindOne(ctx, services.NotDeleted(bson.M{"_id": applicationID})).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		ah.errorHandler.HandleNotFound(c)
+		return
+	} else if err != nil {
 		ah.errorHandler.HandleInternalServerError(c)
 		return
 	}
+	respondPreconditionFailed(c, current.Version)
+}
 
-	c.JSON(http.StatusOK, gin.H{"message": "application updated"})
+// applicationAuditFields lists the fields of an application EditApplication
+// can change, keyed like its $set.
+func applicationAuditFields(application models.Application) bson.M {
+	return bson.M{
+		"name":               application.Name,
+		"status":             application.Status,
+		"email":              application.Email,
+		"company":            application.Company,
+		"jobName":            application.JobName,
+		"resume.filename":    application.Resume.Filename,
+		"resume.contentType": application.Resume.ContentType,
+		"resume.data":        string(application.Resume.Data),
+	}
 }
 
 func (ah *ApplicationHandler) DeleteApplication(c *gin.Context) {
@@ -430,13 +754,48 @@ func (ah *ApplicationHandler) DeleteApplication(c *gin.Context) {
 		return
 	}
 
+	role, _ := c.MustGet("role").(string)
+	userObjId, err := primitive.ObjectIDFromHex(c.GetString("id"))
+	if err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
 
-	filter := bson.M{"_id": objectId}
+	filter := services.NotDeleted(bson.M{"_id": objectId})
 
-	_, err = ah.Collection.DeleteOne(ctx, filter)
+	var application models.Application
+	err = ah.Collection.FindOne(ctx, filter).Decode(&application)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ah.errorHandler.HandleNotFound(c)
+			return
+		}
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	// Candidates can withdraw their own applications, recruiters can delete
+	// the applications to their jobs
+	allowed := role == "user" && application.UserID == userObjId
+	if role == "admin" {
+		count, err := db.DB.Collection("jobs").CountDocuments(ctx, bson.M{"_id": application.JobID, "userId": userObjId})
+		if err != nil {
+			ah.errorHandler.HandleInternalServerError(c)
+			return
+		}
+		allowed = count > 0
+	}
+	if !allowed {
+		ah.errorHandler.HandleUnauthorized(c)
+		return
+	}
 
+	// The application goes to the trash, its resume is deleted when the
+	// trash is purged
+	err = services.SoftDelete(ctx, ah.Collection, bson.M{"_id": objectId}, userObjId)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			ah.errorHandler.HandleNotFound(c)
@@ -445,6 +804,8 @@ func (ah *ApplicationHandler) DeleteApplication(c *gin.Context) {
 		ah.errorHandler.HandleInternalServerError(c)
 		return
 	}
+
+	audit(ctx, c, models.AuditApplicationDelete, models.AuditTargetApplication, objectId, applicationAuditFields(application), nil)
 	c.JSON(http.StatusOK, gin.H{"message": "application deleted"})
 }
 
@@ -476,7 +837,7 @@ func (ah *ApplicationHandler) SearchAdminApplications(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
 
-	filter := bson.M{"userId": objectId}
+	filter := services.NotDeleted(bson.M{"userId": objectId})
 	cursor, err := db.DB.Collection("jobs").Find(ctx, filter)
 	if err != nil {
 		ah.errorHandler.HandleInternalServerError(c)
@@ -498,18 +859,25 @@ func (ah *ApplicationHandler) SearchAdminApplications(c *gin.Context) {
 
 	// Extract job IDs
 	var jobIDs []primitive.ObjectID
-	for _, job := range jobs {
+	jobsByID := map[primitive.ObjectID]*models.Job{}
+	for i, job := range jobs {
 		jobIDs = append(jobIDs, job.ID)
+		jobsByID[job.ID] = &jobs[i]
 	}
 
-	var filters bson.M = bson.M{"jobId": bson.M{"$in": jobIDs}}
+	var filters bson.M = services.NotDeleted(bson.M{"jobId": bson.M{"$in": jobIDs}})
 
 	if email := c.Query("email"); email != "" {
 		filters["email"] = bson.M{"$regex": email, "$options": "i"}
 	}
 
 	var application []models.Application
-	cursor, err = ah.Collection.Find(ctx, filters)
+	opts, ok := ah.applicationListOptions(c, filters)
+	if !ok {
+		return
+	}
+
+	cursor, err = ah.Collection.Find(ctx, filters, opts)
 	if err != nil {
 		ah.errorHandler.HandleInternalServerError(c)
 		return
@@ -520,10 +888,20 @@ func (ah *ApplicationHandler) SearchAdminApplications(c *gin.Context) {
 		ah.errorHandler.HandleInternalServerError(c)
 		return
 	}
-	if len(application) == 0 {
-		application = []models.Application{}
+
+	// Mask candidates under blind review. An email search must not find
+	// them, or it could be used to tell who they are.
+	visible := []models.Application{}
+	for _, app := range application {
+		if services.IdentityHidden(jobsByID[app.JobID], app) {
+			if c.Query("email") != "" {
+				continue
+			}
+			app = services.MaskApplication(app)
+		}
+		visible = append(visible, app)
 	}
-	c.JSON(http.StatusOK, application)
+	c.JSON(http.StatusOK, visible)
 }
 
 func (ah *ApplicationHandler) AdminInformation(c *gin.Context) {
@@ -554,7 +932,7 @@ func (ah *ApplicationHandler) AdminInformation(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
 	defer cancel()
 
-	filter := bson.M{"userId": objectId}
+	filter := services.NotDeleted(bson.M{"userId": objectId})
 	cursor, err := db.DB.Collection("jobs").Find(ctx, filter)
 	if err != nil {
 		ah.errorHandler.HandleInternalServerError(c)
@@ -591,7 +969,7 @@ func (ah *ApplicationHandler) AdminInformation(c *gin.Context) {
 		activeJobs += 1
 	}
 
-	var filters bson.M = bson.M{"jobId": bson.M{"$in": jobIDs}}
+	var filters bson.M = services.NotDeleted(bson.M{"jobId": bson.M{"$in": jobIDs}})
 
 	var application []models.Application
 	cursor, err = ah.Collection.Find(ctx, filters)
@@ -609,7 +987,7 @@ func (ah *ApplicationHandler) AdminInformation(c *gin.Context) {
 	// Update totalApplications based on the length of the application slice
 	totalApplications = len(application)
 
-	var filtersStatus bson.M = bson.M{"status": "Pending", "jobId": bson.M{"$in": jobIDs}}
+	var filtersStatus bson.M = services.NotDeleted(bson.M{"status": "Pending", "jobId": bson.M{"$in": jobIDs}})
 
 	var applicationStatus []models.Application
 	cursor, err = ah.Collection.Find(ctx, filtersStatus)
@@ -636,3 +1014,65 @@ func (ah *ApplicationHandler) AdminInformation(c *gin.Context) {
 
 	c.JSON(http.StatusOK, res)
 }
+
+// GetAppliedJob returns the job posting exactly as it was when the candidate
+// applied, along with the current version of the posting.
+func (ah *ApplicationHandler) GetAppliedJob(c *gin.Context) {
+	userId, ok := c.MustGet("id").(string)
+	if !ok {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	userObjectId, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var application models.Application
+	err = ah.Collection.FindOne(ctx, services.NotDeleted(bson.M{"_id": objectId, "userId": userObjectId})).Decode(&application)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ah.errorHandler.HandleNotFound(c)
+			return
+		}
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	revision, err := services.GetJobRevision(ctx, application.JobID, application.JobVersion)
+	if err == services.ErrRevisionNotFound {
+		ah.errorHandler.HandleNotFound(c)
+		return
+	} else if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	var current models.Job
+	currentVersion := 0
+	err = db.DB.Collection("jobs").FindOne(ctx, bson.M{"_id": application.JobID}).Decode(&current)
+	if err == nil {
+		currentVersion = current.Version
+	} else if err != mongo.ErrNoDocuments {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobVersion":     revision.Version,
+		"currentVersion": currentVersion,
+		"job":            revision.Snapshot,
+		"appliedAt":      application.ID.Timestamp(),
+	})
+}
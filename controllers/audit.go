@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AuditHandler struct {
+	Collection   *mongo.Collection
+	errorHandler *handler.ErrorHandler
+}
+
+func NewAuditHandler(collection *mongo.Collection, errorHandler *handler.ErrorHandler) *AuditHandler {
+	return &AuditHandler{
+		Collection:   collection,
+		errorHandler: errorHandler,
+	}
+}
+
+// auditMeta describes the logged in user and the request for the audit log.
+func auditMeta(c *gin.Context) services.AuditMeta {
+	actorID, _ := primitive.ObjectIDFromHex(c.GetString("id"))
+	return services.AuditMeta{
+		Actor: models.AuditActor{
+			ID:    actorID,
+			Email: c.GetString("email"),
+			Role:  c.GetString("role"),
+		},
+		IP:        c.ClientIP(),
+		RequestID: c.GetString("requestId"),
+	}
+}
+
+// audit records a change that already happened. A failure is logged, not
+// reported, since the change itself went through.
+func audit(ctx context.Context, c *gin.Context, action, targetType string, targetID primitive.ObjectID, before, after interface{}) {
+	if err := services.RecordAudit(ctx, auditMeta(c), action, targetType, targetID, before, after); err != nil {
+		log.Println("error writing audit log", action, targetID.Hex(), err)
+	}
+}
+
+// GetAuditLog lists audit entries, newest first. It filters on actorId,
+// action, targetType, targetId and a from/to time range (RFC 3339), and
+// pages with page and limit. Recruiters only see the entries about their own
+// jobs, the applications to them and their own actions, see
+// services.AuditScope; operators see everything.
+func (ah *AuditHandler) GetAuditLog(c *gin.Context) {
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+	if role != "admin" && role != "operator" {
+		ah.errorHandler.HandleUnauthorized(c)
+		return
+	}
+
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive number"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
+
+	filter := bson.M{}
+	for param, field := range map[string]string{"actorId": "actor.id", "targetId": "targetId"} {
+		if value := c.Query(param); value != "" {
+			id, err := primitive.ObjectIDFromHex(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": param + " is not a valid id"})
+				return
+			}
+			filter[field] = id
+		}
+	}
+	if action := c.Query("action"); action != "" {
+		filter["action"] = action
+	}
+	if targetType := c.Query("targetType"); targetType != "" {
+		filter["targetType"] = targetType
+	}
+	createdAt := bson.M{}
+	for param, operator := range map[string]string{"from": "$gte", "to": "$lt"} {
+		if value := c.Query(param); value != "" {
+			at, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be an RFC 3339 time"})
+				return
+			}
+			createdAt[operator] = at
+		}
+	}
+	if len(createdAt) > 0 {
+		filter["createdAt"] = createdAt
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var adminID primitive.ObjectID
+	if role != "operator" {
+		var err error
+		adminID, err = primitive.ObjectIDFromHex(c.GetString("id"))
+		if err != nil {
+			ah.errorHandler.HandleBadRequest(c)
+			return
+		}
+		scope, err := services.AuditScope(ctx, adminID)
+		if err != nil {
+			ah.errorHandler.HandleInternalServerError(c)
+			return
+		}
+		filter = bson.M{"$and": bson.A{filter, scope}}
+	}
+
+	total, err := ah.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "seq", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := ah.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	var entries []models.AuditEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	// recruiters do not learn candidates under blind review from the log
+	hidden := map[primitive.ObjectID]models.Application{}
+	if role != "operator" {
+		ids := []primitive.ObjectID{}
+		for _, entry := range entries {
+			if entry.TargetType == models.AuditTargetApplication {
+				ids = append(ids, entry.TargetID)
+			}
+		}
+		if hidden, err = services.HiddenApplications(ctx, ids); err != nil {
+			ah.errorHandler.HandleInternalServerError(c)
+			return
+		}
+	}
+
+	items := make([]gin.H, 0, len(entries))
+	for _, entry := range entries {
+		before, after := services.AuditValueDoc(entry.Before), services.AuditValueDoc(entry.After)
+		if application, ok := hidden[entry.TargetID]; ok && entry.TargetType == models.AuditTargetApplication {
+			for _, doc := range []bson.M{before, after} {
+				delete(doc, "name")
+				delete(doc, "email")
+				delete(doc, "resume.filename")
+			}
+			if entry.Actor.ID == application.UserID {
+				entry.Actor = models.AuditActor{Role: entry.Actor.Role}
+				entry.IP = ""
+			}
+		}
+		// a recruiter sees who acted, not other people's addresses
+		if role != "operator" && entry.Actor.ID != adminID {
+			entry.Actor.Email = ""
+			entry.IP = ""
+		}
+		items = append(items, gin.H{
+			"_id":        entry.ID,
+			"seq":        entry.Seq,
+			"actor":      entry.Actor,
+			"action":     entry.Action,
+			"targetType": entry.TargetType,
+			"targetId":   entry.TargetID,
+			"before":     before,
+			"after":      after,
+			"ip":         entry.IP,
+			"requestId":  entry.RequestID,
+			"createdAt":  entry.CreatedAt,
+			"prevHash":   entry.PrevHash,
+			"hash":       entry.Hash,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": items, "page": page, "limit": limit, "total": total})
+}
+
+// VerifyAuditLog checks the hash chain of the whole audit log. It is for
+// operators only, since it reads every entry.
+func (ah *AuditHandler) VerifyAuditLog(c *gin.Context) {
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+	if role != "operator" {
+		ah.errorHandler.HandleUnauthorized(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	result, err := services.VerifyAuditChain(ctx)
+	if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// changedFields keeps the fields whose value differs between before and
+// after, so audit entries show what actually changed.
+func changedFields(before, after bson.M) (bson.M, bson.M) {
+	oldValues, newValues := bson.M{}, bson.M{}
+	for key, value := range after {
+		if !reflect.DeepEqual(before[key], value) {
+			oldValues[key] = before[key]
+			newValues[key] = value
+		}
+	}
+	return oldValues, newValues
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"github.com/weldonkipchirchir/job-listing-server/utils"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// SetBlindReview turns blind review of a job's applications on or off.
+// While it is on, recruiters see candidates masked until their application
+// reaches revealStatus or is revealed explicitly. Applications already at
+// revealStatus are revealed right away.
+func (jh *JobHandler) SetBlindReview(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job, userObjId, ok := jh.ownedJob(ctx, c)
+	if !ok {
+		return
+	}
+
+	if !ifMatch(c, job.Version) {
+		respondPreconditionFailed(c, job.Version)
+		return
+	}
+
+	var blindReview models.BlindReview
+	if err := c.ShouldBindJSON(&blindReview); err != nil {
+		jh.errorHandler.HandleBadRequest(c)
+		return
+	}
+	blindReview.RevealStatus = strings.TrimSpace(blindReview.RevealStatus)
+	if blindReview.RevealStatus != "" {
+		blindReview.RevealStatus = utils.CapitalizeFirstLetter(blindReview.RevealStatus)
+	}
+	if problems := services.ValidateBlindReview(&blindReview); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid blind review setting", "details": problems})
+		return
+	}
+
+	if err := services.RecordJobBaseline(ctx, job); err != nil {
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"blindReview": blindReview,
+			"updatedAt":   time.Now().UTC(),
+		},
+		"$inc": bson.M{"version": 1},
+	}
+	updatedJob, err := jh.updateJob(ctx, update, job, models.RevisionUpdated, userObjId, 0)
+	if err != nil {
+		jh.handleUpdateJobError(ctx, c, job.ID, err)
+		return
+	}
+
+	before, after := changedFields(jobContentFields(*job), jobContentFields(*updatedJob))
+	audit(ctx, c, models.AuditJobUpdate, models.AuditTargetJob, job.ID, before, after)
+
+	revealed, err := services.RevealAtStatus(ctx, updatedJob, userObjId)
+	if err != nil {
+		log.Println("error revealing applications at the reveal status", job.ID.Hex(), err)
+	}
+	for _, id := range revealed {
+		audit(ctx, c, models.AuditApplicationReveal, models.AuditTargetApplication, id, nil, bson.M{"trigger": "status", "status": updatedJob.BlindReview.RevealStatus})
+	}
+
+	c.Header("ETag", versionETag(updatedJob.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"blindReview": updatedJob.BlindReview,
+		"version":     updatedJob.Version,
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/weldonkipchirchir/job-listing-server/db"
 	"github.com/weldonkipchirchir/job-listing-server/handler"
 	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -59,7 +60,10 @@ func (bh *BookmarkHandler) CreateBookmark(c *gin.Context) {
 	defer cancel()
 
 	_, err = bh.Collection.InsertOne(ctx, bookmark)
-	if err != nil {
+	if mongo.IsDuplicateKeyError(err) {
+		bh.bookmarkConflict(ctx, c, bookmark)
+		return
+	} else if err != nil {
 		bh.errorHandler.HandleInternalServerError(c)
 		return
 	}
@@ -67,6 +71,17 @@ func (bh *BookmarkHandler) CreateBookmark(c *gin.Context) {
 	c.JSON(http.StatusCreated, bookmark)
 }
 
+// bookmarkConflict answers a duplicate bookmark with the existing one.
+func (bh *BookmarkHandler) bookmarkConflict(ctx context.Context, c *gin.Context, bookmark models.Bookmark) {
+	var existing models.Bookmark
+	err := bh.Collection.FindOne(ctx, bson.M{"userId": bookmark.UserID, "jobId": bookmark.JobID}).Decode(&existing)
+	if err != nil {
+		bh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": "Job already bookmarked", "bookmark": existing})
+}
+
 // GetBookMarks retrieves bookmarks for a user
 func (bh *BookmarkHandler) GetBookmarks(c *gin.Context) {
 	userId, ok := c.Get("id")
@@ -116,7 +131,7 @@ func (bh *BookmarkHandler) GetBookmarks(c *gin.Context) {
 	}
 
 	var jobs []models.Job
-	jobFilter := bson.M{"_id": bson.M{"$in": jobIDs}}
+	jobFilter := services.NotDeleted(bson.M{"_id": bson.M{"$in": jobIDs}})
 	jobCursor, err := db.DB.Collection("jobs").Find(ctx, jobFilter)
 	if err != nil {
 		bh.errorHandler.HandleInternalServerError(c)
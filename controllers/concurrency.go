@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// versionETag is the entity tag of a job or application at version.
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// ifMatch reports whether the If-Match header of a write allows it on a
+// document at version. Without the header every write is allowed. Weak
+// tags never match, as RFC 9110 requires for If-Match; a bare version
+// number is accepted for clients that do not quote it.
+func ifMatch(c *gin.Context, version int) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == versionETag(version) || candidate == strconv.Itoa(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// respondPreconditionFailed refuses a write based on an outdated version
+// and tells the client which version is current, so it can reload and
+// reapply its change.
+func respondPreconditionFailed(c *gin.Context, currentVersion int) {
+	c.Header("ETag", versionETag(currentVersion))
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error":          "It was changed by someone else since you loaded it",
+		"currentVersion": currentVersion,
+	})
+}
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestIfMatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", true},
+		{"*", true},
+		{`"3"`, true},
+		{"3", true},
+		{`"2", "3"`, true},
+		{`"2"`, false},
+		{`W/"3"`, false},
+		{"garbage", false},
+	}
+	for _, test := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPut, "/", nil)
+		if test.header != "" {
+			c.Request.Header.Set("If-Match", test.header)
+		}
+		if got := ifMatch(c, 3); got != test.want {
+			t.Errorf("ifMatch(%q) = %v, want %v", test.header, got, test.want)
+		}
+	}
+}
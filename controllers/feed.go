@@ -0,0 +1,156 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"html/template"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const feedLimit = 100
+
+type FeedHandler struct {
+	Collection   *mongo.Collection
+	errorHandler *handler.ErrorHandler
+}
+
+func NewFeedHandler(collection *mongo.Collection, errorHandler *handler.ErrorHandler) *FeedHandler {
+	return &FeedHandler{
+		Collection:   collection,
+		errorHandler: errorHandler,
+	}
+}
+
+// feedJobs loads the latest published jobs, optionally limited to a single
+// company taken from the :company path parameter or the company query.
+func (fh *FeedHandler) feedJobs(c *gin.Context) ([]models.Job, string, bool) {
+	filter := services.NotDeleted(bson.M{})
+	title := "Jobly jobs"
+
+	company := c.Param("company")
+	if company == "" {
+		company = c.Query("company")
+	}
+	if company != "" {
+		filter["company"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(company) + "$", Options: "i"}
+		title = "Jobly jobs at " + company
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(feedLimit)
+	cursor, err := fh.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		fh.errorHandler.HandleInternalServerError(c)
+		return nil, "", false
+	}
+	defer cursor.Close(ctx)
+
+	var jobs []models.Job
+	if err := cursor.All(ctx, &jobs); err != nil {
+		fh.errorHandler.HandleInternalServerError(c)
+		return nil, "", false
+	}
+
+	return jobs, title, true
+}
+
+func (fh *FeedHandler) renderXML(c *gin.Context, contentType string, feed interface{}) {
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		fh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Data(http.StatusOK, contentType, append([]byte(xml.Header), body...))
+}
+
+func feedSelfURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + c.Request.URL.RequestURI()
+}
+
+func (fh *FeedHandler) GetRSSFeed(c *gin.Context) {
+	jobs, title, ok := fh.feedJobs(c)
+	if !ok {
+		return
+	}
+	fh.renderXML(c, "application/rss+xml; charset=utf-8", services.BuildRSS(title, feedSelfURL(c), jobs))
+}
+
+func (fh *FeedHandler) GetAtomFeed(c *gin.Context) {
+	jobs, title, ok := fh.feedJobs(c)
+	if !ok {
+		return
+	}
+	fh.renderXML(c, "application/atom+xml; charset=utf-8", services.BuildAtom(title, feedSelfURL(c), jobs))
+}
+
+func (fh *FeedHandler) GetJobXMLFeed(c *gin.Context) {
+	jobs, _, ok := fh.feedJobs(c)
+	if !ok {
+		return
+	}
+	fh.renderXML(c, "application/xml; charset=utf-8", services.BuildJobFeed(jobs))
+}
+
+var jsonLDScript = template.Must(template.New("jsonld").Parse(`<script type="application/ld+json">{{.}}</script>`))
+
+// GetJobJSONLD returns the schema.org JobPosting for a job. With ?embed=true
+// it is wrapped in a <script> tag ready to drop into the job page.
+func (fh *FeedHandler) GetJobJSONLD(c *gin.Context) {
+	objectId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		fh.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var job models.Job
+	err = fh.Collection.FindOne(ctx, services.NotDeleted(bson.M{"_id": objectId})).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			fh.errorHandler.HandleNotFound(c)
+			return
+		}
+		fh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	posting := services.BuildJobPosting(job)
+
+	if c.Query("embed") == "true" {
+		body, err := json.Marshal(posting)
+		if err != nil {
+			fh.errorHandler.HandleInternalServerError(c)
+			return
+		}
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusOK)
+		if err := jsonLDScript.Execute(c.Writer, template.JS(body)); err != nil {
+			fh.errorHandler.HandleInternalServerError(c)
+		}
+		return
+	}
+
+	c.Header("Content-Type", "application/ld+json; charset=utf-8")
+	c.JSON(http.StatusOK, posting)
+}
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+)
+
+var startedAt = time.Now()
+
+// Healthz is the liveness probe. It answers as long as the process can
+// serve requests and checks nothing else.
+func Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "ok",
+		"uptime": time.Since(startedAt).Round(time.Second).String(),
+	})
+}
+
+// Readyz is the readiness probe. It answers 503 with the failing checks
+// when a dependency is down and from the start of a graceful shutdown.
+func Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	readiness := services.CheckReadiness(ctx)
+	status := http.StatusOK
+	if !readiness.Ready {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, readiness)
+}
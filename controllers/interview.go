@@ -0,0 +1,518 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type InterviewHandler struct {
+	Collection   *mongo.Collection
+	errorHandler *handler.ErrorHandler
+}
+
+func NewInterviewHandler(collection *mongo.Collection, errorHandler *handler.ErrorHandler) *InterviewHandler {
+	return &InterviewHandler{
+		Collection:   collection,
+		errorHandler: errorHandler,
+	}
+}
+
+// currentUser returns the role and id of the logged in user.
+func (ih *InterviewHandler) currentUser(c *gin.Context) (string, primitive.ObjectID, bool) {
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		ih.errorHandler.HandleBadRequest(c)
+		return "", primitive.NilObjectID, false
+	}
+
+	userId, ok := c.MustGet("id").(string)
+	if !ok {
+		ih.errorHandler.HandleBadRequest(c)
+		return "", primitive.NilObjectID, false
+	}
+
+	userObjId, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		ih.errorHandler.HandleBadRequest(c)
+		return "", primitive.NilObjectID, false
+	}
+	return role, userObjId, true
+}
+
+// participantInterview loads the interview in the :id path parameter for the
+// candidate, the organizer or one of the interviewers. It writes the error
+// response itself.
+func (ih *InterviewHandler) participantInterview(ctx context.Context, c *gin.Context) (*models.Interview, primitive.ObjectID, bool) {
+	_, userObjId, ok := ih.currentUser(c)
+	if !ok {
+		return nil, primitive.NilObjectID, false
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		ih.errorHandler.HandleBadRequest(c)
+		return nil, primitive.NilObjectID, false
+	}
+
+	var interview models.Interview
+	err = ih.Collection.FindOne(ctx, bson.M{"_id": objectId}).Decode(&interview)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ih.errorHandler.HandleNotFound(c)
+			return nil, primitive.NilObjectID, false
+		}
+		ih.errorHandler.HandleInternalServerError(c)
+		return nil, primitive.NilObjectID, false
+	}
+
+	if interview.CandidateID == userObjId || interview.OrganizerID == userObjId {
+		return &interview, userObjId, true
+	}
+	for _, interviewerID := range interview.InterviewerIDs {
+		if interviewerID == userObjId {
+			return &interview, userObjId, true
+		}
+	}
+
+	ih.errorHandler.HandleNotFound(c)
+	return nil, primitive.NilObjectID, false
+}
+
+// maskInterviews hides the candidate of interviews under blind review from
+// everyone but the candidate. It writes the error response itself.
+func (ih *InterviewHandler) maskInterviews(ctx context.Context, c *gin.Context, viewerID primitive.ObjectID, interviews ...*models.Interview) bool {
+	ids := []primitive.ObjectID{}
+	for _, interview := range interviews {
+		if interview.CandidateID != viewerID {
+			ids = append(ids, interview.ApplicationID)
+		}
+	}
+	hidden, err := services.HiddenApplications(ctx, ids)
+	if err != nil {
+		ih.errorHandler.HandleInternalServerError(c)
+		return false
+	}
+	for _, interview := range interviews {
+		if _, ok := hidden[interview.ApplicationID]; ok && interview.CandidateID != viewerID {
+			*interview = services.MaskInterview(*interview)
+		}
+	}
+	return true
+}
+
+// updateInterview applies update unless the interview changed since it was
+// loaded and queues the emails for event with it, returning the updated
+// interview. It writes the error response itself.
+func (ih *InterviewHandler) updateInterview(ctx context.Context, c *gin.Context, interview *models.Interview, update bson.M, event string, previous *models.InterviewSlot) (*models.Interview, bool) {
+	filter := bson.M{"_id": interview.ID, "sequence": interview.Sequence, "status": interview.Status}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated models.Interview
+	err := services.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		if err := ih.Collection.FindOneAndUpdate(sc, filter, update, opts).Decode(&updated); err != nil {
+			return err
+		}
+		return services.QueueInterviewMail(sc, updated.ID, event, previous)
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusConflict, gin.H{"error": "Interview was changed in the meantime, reload and try again"})
+			return nil, false
+		}
+		ih.errorHandler.HandleInternalServerError(c)
+		return nil, false
+	}
+	return &updated, true
+}
+
+// CreateInterview proposes interview slots for an application to the
+// candidate.
+func (ih *InterviewHandler) CreateInterview(c *gin.Context) {
+	role, userObjId, ok := ih.currentUser(c)
+	if !ok {
+		return
+	}
+	if role != "admin" {
+		ih.errorHandler.HandleUnauthorized(c)
+		return
+	}
+
+	var interview models.Interview
+	if err := c.ShouldBindJSON(&interview); err != nil || interview.ApplicationID.IsZero() {
+		ih.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	problems := services.ValidateInterviewDetails(&interview)
+	problems = append(problems, services.NormalizeInterviewSlots(interview.Slots, time.Now())...)
+	if len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interview", "details": problems})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var application models.Application
+	err := db.DB.Collection("applications").FindOne(ctx, services.NotDeleted(bson.M{"_id": interview.ApplicationID})).Decode(&application)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ih.errorHandler.HandleNotFound(c)
+			return
+		}
+		ih.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	var job models.Job
+	err = db.DB.Collection("jobs").FindOne(ctx, bson.M{"_id": application.JobID}).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ih.errorHandler.HandleNotFound(c)
+			return
+		}
+		ih.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	if job.UserID != userObjId {
+		ih.errorHandler.HandleUnauthorized(c)
+		return
+	}
+	if application.Status == models.StatusRejected {
+		c.JSON(http.StatusConflict, gin.H{"error": "Application was rejected"})
+		return
+	}
+
+	_, unknown, err := services.FindInterviewers(ctx, interview.InterviewerIDs)
+	if err != nil {
+		ih.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	if len(unknown) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Interviewers must be admin users", "details": unknown})
+		return
+	}
+
+	now := time.Now().UTC()
+	interview.ID = primitive.NewObjectID()
+	interview.JobID = job.ID
+	interview.JobName = job.JobName
+	interview.Company = job.Company
+	interview.CandidateID = application.UserID
+	interview.CandidateName = application.Name
+	interview.CandidateEmail = application.Email
+	interview.OrganizerID = userObjId
+	if interview.InterviewerIDs == nil {
+		interview.InterviewerIDs = []primitive.ObjectID{}
+	}
+	if interview.Title == "" {
+		interview.Title = "Interview: " + job.JobName
+	}
+	interview.SelectedSlot = nil
+	interview.Status = models.InterviewProposed
+	interview.CancelReason = ""
+	interview.Sequence = 0
+	interview.CreatedAt = now
+	interview.UpdatedAt = now
+
+	err = services.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		if _, err := ih.Collection.InsertOne(sc, interview); err != nil {
+			return err
+		}
+		return services.QueueInterviewMail(sc, interview.ID, services.InterviewEventProposed, nil)
+	})
+	if err != nil {
+		ih.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	if services.IdentityHidden(&job, application) {
+		interview = services.MaskInterview(interview)
+	}
+	c.JSON(http.StatusCreated, interview)
+}
+
+// GetInterviews lists the interviews of the candidate, or for admins the
+// interviews they organize or take part in.
+func (ih *InterviewHandler) GetInterviews(c *gin.Context) {
+	role, userObjId, ok := ih.currentUser(c)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{"candidateId": userObjId}
+	if role == "admin" {
+		filter = bson.M{"$or": []bson.M{{"organizerId": userObjId}, {"interviewerIds": userObjId}}}
+	}
+	if applicationId := c.Query("applicationId"); applicationId != "" {
+		objectId, err := primitive.ObjectIDFromHex(applicationId)
+		if err != nil {
+			ih.errorHandler.HandleBadRequest(c)
+			return
+		}
+		filter["applicationId"] = objectId
+	}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := ih.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		ih.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	interviews := []models.Interview{}
+	if err := cursor.All(ctx, &interviews); err != nil {
+		ih.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	viewed := make([]*models.Interview, len(interviews))
+	for i := range interviews {
+		viewed[i] = &interviews[i]
+	}
+	if !ih.maskInterviews(ctx, c, userObjId, viewed...) {
+		return
+	}
+
+	c.JSON(http.StatusOK, interviews)
+}
+
+func (ih *InterviewHandler) GetInterview(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	interview, userObjId, ok := ih.participantInterview(ctx, c)
+	if !ok {
+		return
+	}
+	if !ih.maskInterviews(ctx, c, userObjId, interview) {
+		return
+	}
+
+	c.JSON(http.StatusOK, interview)
+}
+
+// PickSlot books one of the proposed slots. Only the candidate can pick.
+func (ih *InterviewHandler) PickSlot(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	interview, userObjId, ok := ih.participantInterview(ctx, c)
+	if !ok {
+		return
+	}
+	if interview.CandidateID != userObjId {
+		ih.errorHandler.HandleUnauthorized(c)
+		return
+	}
+	if interview.Status != models.InterviewProposed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Interview is " + interview.Status})
+		return
+	}
+
+	var request struct {
+		SlotID primitive.ObjectID `json:"slotId"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		ih.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	var selected *models.InterviewSlot
+	for i := range interview.Slots {
+		if interview.Slots[i].ID == request.SlotID {
+			selected = &interview.Slots[i]
+		}
+	}
+	if selected == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slotId is not one of the proposed slots"})
+		return
+	}
+	if !selected.Start.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Slot is in the past"})
+		return
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"selectedSlot": selected,
+			"status":       models.InterviewScheduled,
+			"updatedAt":    time.Now().UTC(),
+		},
+		"$inc": bson.M{"sequence": 1},
+	}
+	updated, ok := ih.updateInterview(ctx, c, interview, update, services.InterviewEventScheduled, nil)
+	if !ok || !ih.maskInterviews(ctx, c, userObjId, updated) {
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// RescheduleInterview replaces the slots of an interview. A single slot is
+// booked right away, several slots are proposed to the candidate again.
+// Only the organizer can reschedule.
+func (ih *InterviewHandler) RescheduleInterview(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	interview, userObjId, ok := ih.participantInterview(ctx, c)
+	if !ok {
+		return
+	}
+	if interview.OrganizerID != userObjId {
+		ih.errorHandler.HandleUnauthorized(c)
+		return
+	}
+	if interview.Status == models.InterviewCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Interview is cancelled"})
+		return
+	}
+
+	var request struct {
+		Slots     []models.InterviewSlot `json:"slots"`
+		Timezone  *string                `json:"timezone"`
+		Location  *string                `json:"location"`
+		VideoLink *string                `json:"videoLink"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		ih.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	changed := *interview
+	if request.Timezone != nil {
+		changed.Timezone = *request.Timezone
+	}
+	if request.Location != nil {
+		changed.Location = *request.Location
+	}
+	if request.VideoLink != nil {
+		changed.VideoLink = *request.VideoLink
+	}
+	problems := services.ValidateInterviewDetails(&changed)
+	problems = append(problems, services.NormalizeInterviewSlots(request.Slots, time.Now())...)
+	if len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid interview", "details": problems})
+		return
+	}
+
+	set := bson.M{
+		"slots":     request.Slots,
+		"timezone":  changed.Timezone,
+		"location":  changed.Location,
+		"videoLink": changed.VideoLink,
+		"updatedAt": time.Now().UTC(),
+	}
+	update := bson.M{"$set": set, "$inc": bson.M{"sequence": 1}}
+
+	event := services.InterviewEventProposed
+	previous := interview.SelectedSlot
+	if len(request.Slots) == 1 {
+		set["selectedSlot"] = request.Slots[0]
+		set["status"] = models.InterviewScheduled
+		event = services.InterviewEventScheduled
+		if previous != nil {
+			event = services.InterviewEventRescheduled
+		}
+		// the updated invite replaces the previous time
+		previous = nil
+	} else {
+		set["status"] = models.InterviewProposed
+		update["$unset"] = bson.M{"selectedSlot": ""}
+	}
+
+	updated, ok := ih.updateInterview(ctx, c, interview, update, event, previous)
+	if !ok || !ih.maskInterviews(ctx, c, userObjId, updated) {
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// CancelInterview cancels an interview. The organizer and the candidate can
+// cancel.
+func (ih *InterviewHandler) CancelInterview(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	interview, userObjId, ok := ih.participantInterview(ctx, c)
+	if !ok {
+		return
+	}
+	if interview.OrganizerID != userObjId && interview.CandidateID != userObjId {
+		ih.errorHandler.HandleUnauthorized(c)
+		return
+	}
+	if interview.Status == models.InterviewCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Interview is already cancelled"})
+		return
+	}
+
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	// the reason is optional, an empty body is fine
+	_ = c.ShouldBindJSON(&request)
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":       models.InterviewCancelled,
+			"cancelReason": request.Reason,
+			"updatedAt":    time.Now().UTC(),
+		},
+		"$inc": bson.M{"sequence": 1},
+	}
+	updated, ok := ih.updateInterview(ctx, c, interview, update, services.InterviewEventCancelled, interview.SelectedSlot)
+	if !ok || !ih.maskInterviews(ctx, c, userObjId, updated) {
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// GetInterviewICS downloads the calendar entry of a booked interview.
+func (ih *InterviewHandler) GetInterviewICS(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	interview, userObjId, ok := ih.participantInterview(ctx, c)
+	if !ok || !ih.maskInterviews(ctx, c, userObjId, interview) {
+		return
+	}
+	if interview.SelectedSlot == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "No slot has been picked yet"})
+		return
+	}
+
+	interviewers, _, err := services.FindInterviewers(ctx, append([]primitive.ObjectID{interview.OrganizerID}, interview.InterviewerIDs...))
+	if err != nil {
+		ih.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	method := services.ICSMethodRequest
+	if interview.Status == models.InterviewCancelled {
+		method = services.ICSMethodCancel
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="interview.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8; method="+method, services.InterviewICS(*interview, method, *interview.SelectedSlot, interviewers))
+}
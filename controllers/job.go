@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/weldonkipchirchir/job-listing-server/handler"
 	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
 	"github.com/weldonkipchirchir/job-listing-server/utils"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -33,7 +35,7 @@ func (jh *JobHandler) GetAllJobs(c *gin.Context) {
 	defer cancel()
 
 	var jobs []models.Job
-	cursor, err := jh.Collection.Find(ctx, bson.M{})
+	cursor, err := jh.Collection.Find(ctx, services.NotDeleted(bson.M{}))
 	if err != nil {
 		jh.errorHandler.HandleInternalServerError(c)
 		return
@@ -48,12 +50,29 @@ func (jh *JobHandler) GetAllJobs(c *gin.Context) {
 }
 
 func (jh *JobHandler) CreateJob(c *gin.Context) {
-	var job models.Job
-	if err := c.ShouldBindJSON(&job); err != nil {
+	var request models.JobRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
 		jh.errorHandler.HandleBadRequest(c)
 		log.Println("error in bind", err)
 		return
 	}
+	job := models.Job{
+		JobName:               request.JobName,
+		Type:                  request.Type,
+		Location:              request.Location,
+		SalaryHigh:            request.SalaryHigh,
+		SalaryLow:             request.SalaryLow,
+		Company:               request.Company,
+		ImageLink:             request.ImageLink,
+		Sponsored:             request.Sponsored,
+		Currency:              request.Currency,
+		MandatoryRequirements: request.MandatoryRequirements,
+		OptionalRequirements:  request.OptionalRequirements,
+		JobDescription:        request.JobDescription,
+		Industry:              request.Industry,
+		ScreeningQuestions:    services.ScreeningQuestionsFromDetails(request.ScreeningQuestions),
+		KnockoutAction:        request.KnockoutAction,
+	}
 
 	currencyCapitalize := utils.UpperCaseString(string(job.Currency))
 
@@ -82,6 +101,11 @@ func (jh *JobHandler) CreateJob(c *gin.Context) {
 		return
 	}
 
+	if problems := services.ValidateJobScreening(&job); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid screening questions", "details": problems})
+		return
+	}
+
 	job.ID = primitive.NewObjectID()
 
 	ownerID, err := primitive.ObjectIDFromHex(UserID)
@@ -90,12 +114,38 @@ func (jh *JobHandler) CreateJob(c *gin.Context) {
 		return
 	}
 	job.UserID = ownerID
+	job.Version = 1
+	job.SalaryHighAmount = services.SalaryAmount(job.SalaryHigh)
+	job.SalaryLowAmount = services.SalaryAmount(job.SalaryLow)
+	job.UpdatedAt = time.Now().UTC()
+
+	revision, err := services.NewJobRevision(job, nil, models.RevisionCreated, ownerID)
+	if err != nil {
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
 
-	_, err = jh.Collection.InsertOne(context.TODO(), job)
+	// The job, its first revision and the follow-up work are written together
+	err = services.WithTransaction(context.TODO(), func(sc mongo.SessionContext) error {
+		if _, err := jh.Collection.InsertOne(sc, job); err != nil {
+			return err
+		}
+		if err := services.RecordJobRevisions(sc, revision); err != nil {
+			return err
+		}
+		if err := services.QueueWebhookEvent(sc, ownerID, models.EventJobCreated, services.JobWebhookData(job)); err != nil {
+			return err
+		}
+		return services.QueueJobAlerts(sc, job)
+	})
 	if err != nil {
 		jh.errorHandler.HandleInternalServerError(c) // Use ErrorHandler to handle internal server error
 		return
 	}
+
+	services.InvalidateJobCache(context.TODO(), job.ID)
+	audit(context.TODO(), c, models.AuditJobCreate, models.AuditTargetJob, job.ID, nil, jobContentFields(job))
+
 	c.JSON(201, job)
 }
 
@@ -135,7 +185,7 @@ func (jh *JobHandler) Updatejob(c *gin.Context) {
 	var existingJob models.Job
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	err = jh.Collection.FindOne(ctx, bson.M{"_id": objectId}).Decode(&existingJob)
+	err = jh.Collection.FindOne(ctx, services.NotDeleted(bson.M{"_id": objectId})).Decode(&existingJob)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			jh.errorHandler.HandleNotFound(c)
@@ -150,6 +200,12 @@ func (jh *JobHandler) Updatejob(c *gin.Context) {
 		return
 	}
 
+	// If-Match makes sure the edit is based on the version being replaced
+	if !ifMatch(c, existingJob.Version) {
+		respondPreconditionFailed(c, existingJob.Version)
+		return
+	}
+
 	var updateJob models.Job
 	if err := c.ShouldBindJSON(&updateJob); err != nil {
 		jh.errorHandler.HandleBadRequest(c)
@@ -168,9 +224,11 @@ func (jh *JobHandler) Updatejob(c *gin.Context) {
 	}
 	if updateJob.SalaryHigh != "" {
 		updateFields["salaryHigh"] = updateJob.SalaryHigh
+		updateFields["salaryHighAmount"] = services.SalaryAmount(updateJob.SalaryHigh)
 	}
 	if updateJob.SalaryLow != "" {
 		updateFields["salaryLow"] = updateJob.SalaryLow
+		updateFields["salaryLowAmount"] = services.SalaryAmount(updateJob.SalaryLow)
 	}
 	if updateJob.Company != "" {
 		updateFields["company"] = updateJob.Company
@@ -202,14 +260,25 @@ func (jh *JobHandler) Updatejob(c *gin.Context) {
 		return
 	}
 
-	update := bson.M{"$set": updateFields}
-	_, err = jh.Collection.UpdateOne(context.TODO(), bson.M{"_id": objectId}, update)
-	if err != nil {
+	if err := services.RecordJobBaseline(ctx, &existingJob); err != nil {
 		jh.errorHandler.HandleInternalServerError(c)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "job updated"})
+	updateFields["updatedAt"] = time.Now().UTC()
+	update := bson.M{"$set": updateFields, "$inc": bson.M{"version": 1}}
+
+	updatedJob, err := jh.updateJob(ctx, update, &existingJob, models.RevisionUpdated, userObjId, 0)
+	if err != nil {
+		jh.handleUpdateJobError(ctx, c, objectId, err)
+		return
+	}
+
+	before, after := changedFields(jobContentFields(existingJob), jobContentFields(*updatedJob))
+	audit(ctx, c, models.AuditJobUpdate, models.AuditTargetJob, objectId, before, after)
+
+	c.Header("ETag", versionETag(updatedJob.Version))
+	c.JSON(http.StatusOK, gin.H{"message": "job updated", "version": updatedJob.Version})
 }
 
 func (jh *JobHandler) DeleteJob(c *gin.Context) {
@@ -248,7 +317,7 @@ func (jh *JobHandler) DeleteJob(c *gin.Context) {
 	var existingJob models.Job
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	err = jh.Collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&existingJob)
+	err = jh.Collection.FindOne(ctx, services.NotDeleted(bson.M{"_id": objectID})).Decode(&existingJob)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			jh.errorHandler.HandleNotFound(c)
@@ -263,16 +332,45 @@ func (jh *JobHandler) DeleteJob(c *gin.Context) {
 		return
 	}
 
-	_, err = jh.Collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	// Jobs go to the trash rather than being removed so their applications
+	// keep pointing at them and the delete can be undone
+	var impact services.JobDeleteImpact
+	err = services.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		impact, err = services.SoftDeleteJob(sc, existingJob, userObjId)
+		return err
+	})
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if errors.Is(err, services.ErrJobDeleted) {
 			jh.errorHandler.HandleNotFound(c)
 			return
 		}
 		jh.errorHandler.HandleInternalServerError(c)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "job deleted"})
+
+	services.InvalidateJobCache(ctx, objectID)
+	audit(ctx, c, models.AuditJobDelete, models.AuditTargetJob, objectID, jobContentFields(existingJob), impact)
+
+	c.JSON(http.StatusOK, gin.H{"message": "job deleted", "impact": impact})
+}
+
+// PreviewDeleteJob reports what deleting a job would affect without
+// changing anything.
+func (jh *JobHandler) PreviewDeleteJob(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job, _, ok := jh.ownedJob(ctx, c)
+	if !ok {
+		return
+	}
+
+	impact, err := services.PreviewJobDelete(ctx, job.ID)
+	if err != nil {
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"job": job, "impact": impact})
 }
 
 func (jh *JobHandler) GetJobById(c *gin.Context) {
@@ -281,26 +379,36 @@ func (jh *JobHandler) GetJobById(c *gin.Context) {
 	objectId, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		jh.errorHandler.HandleBadRequest(c)
+		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	filter := bson.M{"_id": objectId}
+	serveCached(c, services.JobCacheKey(objectId), "private, no-cache", func(ctx context.Context) (interface{}, validators, bool) {
+		filter := services.NotDeleted(bson.M{"_id": objectId})
 
-	var job models.Job
+		var job models.Job
 
-	err = jh.Collection.FindOne(ctx, filter).Decode(&job)
+		err := jh.Collection.FindOne(ctx, filter).Decode(&job)
 
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			jh.errorHandler.HandleNotFound(c)
-			return
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				jh.errorHandler.HandleNotFound(c)
+				return nil, validators{}, false
+			}
+			jh.errorHandler.HandleInternalServerError(c)
+			return nil, validators{}, false
 		}
-		jh.errorHandler.HandleInternalServerError(c)
-		return
+		// the version tag is what If-Match on the job's writes expects
+		return job, validators{ETag: versionETag(job.Version), LastModified: jobLastModified(job)}, true
+	})
+}
+
+// jobLastModified is when a job last changed. Jobs written before updatedAt
+// was kept fall back to their creation time.
+func jobLastModified(job models.Job) time.Time {
+	if job.UpdatedAt.IsZero() {
+		return job.ID.Timestamp()
 	}
-	c.JSON(http.StatusOK, job)
+	return job.UpdatedAt
 }
 
 func (jh *JobHandler) GetAdminsJobs(c *gin.Context) {
@@ -328,7 +436,7 @@ func (jh *JobHandler) GetAdminsJobs(c *gin.Context) {
 		return
 	}
 
-	filter := bson.M{"userId": objectId}
+	filter := services.NotDeleted(bson.M{"userId": objectId})
 
 	var jobs []models.Job
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -348,23 +456,25 @@ func (jh *JobHandler) GetAdminsJobs(c *gin.Context) {
 }
 
 func (jh *JobHandler) GetSponsoredJobs(c *gin.Context) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	serveCached(c, services.SponsoredJobsCacheKey, "public, no-cache", func(ctx context.Context) (interface{}, validators, bool) {
+		var jobs []models.Job
+		filter := services.NotDeleted(bson.M{"sponsored": true})
+		cursor, err := jh.Collection.Find(ctx, filter)
+		if err != nil {
+			jh.errorHandler.HandleInternalServerError(c)
+			return nil, validators{}, false
+		}
+		defer cursor.Close(ctx)
 
-	var jobs []models.Job
-	filter := bson.M{"sponsored": true}
-	cursor, err := jh.Collection.Find(ctx, filter)
-	if err != nil {
-		jh.errorHandler.HandleInternalServerError(c)
-		return
-	}
-	defer cursor.Close(ctx)
+		if err := cursor.All(ctx, &jobs); err != nil {
+			jh.errorHandler.HandleInternalServerError(c)
+			return nil, validators{}, false
+		}
 
-	if err := cursor.All(ctx, &jobs); err != nil {
-		jh.errorHandler.HandleInternalServerError(c)
-		return
-	}
-	c.JSON(http.StatusOK, jobs)
+		// a job leaving the list leaves no trace in the times of the jobs
+		// still on it, so the list counts as modified when it was loaded
+		return jobs, validators{LastModified: time.Now()}, true
+	})
 }
 
 func (jh *JobHandler) SearchJobs(c *gin.Context) {
@@ -396,7 +506,7 @@ func (jh *JobHandler) SearchJobs(c *gin.Context) {
 	}
 
 	var jobs []models.Job
-	cursor, err := jh.Collection.Find(ctx, filters)
+	cursor, err := jh.Collection.Find(ctx, services.NotDeleted(filters))
 	if err != nil {
 		jh.errorHandler.HandleInternalServerError(c)
 		return
@@ -426,7 +536,7 @@ func (jh *JobHandler) SearchJobsAll(c *gin.Context) {
 	searchPattern := primitive.Regex{Pattern: searchTerm, Options: "i"}
 
 	// Create the filter with $or to check the search term in multiple fields
-	filter := bson.M{
+	filter := services.NotDeleted(bson.M{
 		"$or": []bson.M{
 			{"jobName": searchPattern},
 			{"type": searchPattern},
@@ -438,7 +548,7 @@ func (jh *JobHandler) SearchJobsAll(c *gin.Context) {
 			{"industry": searchPattern},
 			{"currency": searchPattern},
 		},
-	}
+	})
 
 	var jobs []models.Job
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -487,10 +597,10 @@ func (jh *JobHandler) GetAdminsLatestJobs(c *gin.Context) {
 		return
 	}
 
-	filter := bson.M{"userId": objectId}
+	filter := services.NotDeleted(bson.M{"userId": objectId})
 
 	// Sort by the insertion timestamp in descending order to get the latest jobs first
-	opts := options.Find().SetSort(bson.D{{"_id", -1}}).SetLimit(4)
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(4)
 
 	var jobs []models.Job
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -553,7 +663,7 @@ func (jh *JobHandler) SearchAdminJobs(c *gin.Context) {
 	searchPattern := primitive.Regex{Pattern: searchTerm, Options: "i"}
 
 	// Create the filter with $or to check the search term in multiple fields
-	filter := bson.M{"userId": objectId,
+	filter := services.NotDeleted(bson.M{"userId": objectId,
 		"$or": []bson.M{
 			{"jobName": searchPattern},
 			{"type": searchPattern},
@@ -565,7 +675,7 @@ func (jh *JobHandler) SearchAdminJobs(c *gin.Context) {
 			{"industry": searchPattern},
 			{"currency": searchPattern},
 		},
-	}
+	})
 
 	var jobs []models.Job
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxJobAlerts = 20
+
+type JobAlertHandler struct {
+	Collection   *mongo.Collection
+	errorHandler *handler.ErrorHandler
+}
+
+func NewJobAlertHandler(collection *mongo.Collection, errorHandler *handler.ErrorHandler) *JobAlertHandler {
+	return &JobAlertHandler{
+		Collection:   collection,
+		errorHandler: errorHandler,
+	}
+}
+
+// candidateID returns the id of the logged in candidate.
+func (ah *JobAlertHandler) candidateID(c *gin.Context) (primitive.ObjectID, bool) {
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		ah.errorHandler.HandleBadRequest(c)
+		return primitive.NilObjectID, false
+	}
+	if role != "user" {
+		ah.errorHandler.HandleUnauthorized(c)
+		return primitive.NilObjectID, false
+	}
+
+	userId, ok := c.MustGet("id").(string)
+	if !ok {
+		ah.errorHandler.HandleBadRequest(c)
+		return primitive.NilObjectID, false
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return primitive.NilObjectID, false
+	}
+	return objectId, true
+}
+
+func validateJobAlert(alert *models.JobAlert) []string {
+	var problems []string
+
+	alert.Name = strings.TrimSpace(alert.Name)
+	alert.Location = strings.TrimSpace(alert.Location)
+	alert.Type = strings.TrimSpace(alert.Type)
+	alert.Industry = strings.TrimSpace(alert.Industry)
+	alert.Company = strings.TrimSpace(alert.Company)
+
+	keywords := []string{}
+	for _, keyword := range alert.Keywords {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			keywords = append(keywords, keyword)
+		}
+	}
+	alert.Keywords = keywords
+
+	if alert.Name == "" {
+		problems = append(problems, "name is required")
+	}
+	if len(alert.Keywords) == 0 && alert.Location == "" && alert.Type == "" && alert.Industry == "" && alert.Company == "" {
+		problems = append(problems, "at least one criterion is required")
+	}
+	return problems
+}
+
+func (ah *JobAlertHandler) GetJobAlerts(c *gin.Context) {
+	userID, ok := ah.candidateID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := ah.Collection.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	alerts := []models.JobAlert{}
+	if err := cursor.All(ctx, &alerts); err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, alerts)
+}
+
+func (ah *JobAlertHandler) CreateJobAlert(c *gin.Context) {
+	userID, ok := ah.candidateID(c)
+	if !ok {
+		return
+	}
+
+	alert := models.JobAlert{Active: true}
+	if err := c.ShouldBindJSON(&alert); err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+	if problems := validateJobAlert(&alert); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job alert", "details": problems})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	count, err := ah.Collection.CountDocuments(ctx, bson.M{"userId": userID})
+	if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	if count >= maxJobAlerts {
+		c.JSON(http.StatusConflict, gin.H{"error": "Job alert limit reached"})
+		return
+	}
+
+	alert.ID = primitive.NewObjectID()
+	alert.UserID = userID
+	alert.LastMatchedAt = nil
+	alert.CreatedAt = time.Now().UTC()
+
+	_, err = ah.Collection.InsertOne(ctx, alert)
+	if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusCreated, alert)
+}
+
+func (ah *JobAlertHandler) UpdateJobAlert(c *gin.Context) {
+	userID, ok := ah.candidateID(c)
+	if !ok {
+		return
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	var alert models.JobAlert
+	if err := c.ShouldBindJSON(&alert); err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+	if problems := validateJobAlert(&alert); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job alert", "details": problems})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{
+		"name":     alert.Name,
+		"keywords": alert.Keywords,
+		"location": alert.Location,
+		"type":     alert.Type,
+		"industry": alert.Industry,
+		"company":  alert.Company,
+		"active":   alert.Active,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated models.JobAlert
+	err = ah.Collection.FindOneAndUpdate(ctx, bson.M{"_id": objectId, "userId": userID}, update, opts).Decode(&updated)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ah.errorHandler.HandleNotFound(c)
+			return
+		}
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (ah *JobAlertHandler) DeleteJobAlert(c *gin.Context) {
+	userID, ok := ah.candidateID(c)
+	if !ok {
+		return
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := ah.Collection.DeleteOne(ctx, bson.M{"_id": objectId, "userId": userID})
+	if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	if result.DeletedCount == 0 {
+		ah.errorHandler.HandleNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "job alert deleted"})
+}
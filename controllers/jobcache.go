@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+)
+
+// cachedResponse is a JSON response body as it is kept in the job cache.
+type cachedResponse struct {
+	Body         []byte    `json:"body"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// validators identify the state of a response for conditional requests.
+// Without an ETag, one is derived from the response body. A zero
+// LastModified is left out.
+type validators struct {
+	ETag         string
+	LastModified time.Time
+}
+
+// serveCached answers with the response cached under key, or with the value
+// load returns, caching it. Errors are written by load, which then returns
+// ok false. The response carries ETag and Last-Modified, and a matching
+// conditional request is answered with 304 Not Modified.
+func serveCached(c *gin.Context, key, cacheControl string, load func(ctx context.Context) (value interface{}, v validators, ok bool)) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	jobCache := services.JobCache()
+	var response cachedResponse
+	cached, hit := jobCache.Get(ctx, key)
+	if hit {
+		if err := json.Unmarshal(cached, &response); err != nil {
+			log.Println("error decoding cached response", key, err)
+			hit = false
+		}
+	}
+	if !hit {
+		value, v, ok := load(ctx)
+		if !ok {
+			return
+		}
+		body, err := json.Marshal(value)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		if v.ETag == "" {
+			sum := sha256.Sum256(body)
+			v.ETag = `"` + hex.EncodeToString(sum[:16]) + `"`
+		}
+		response = cachedResponse{
+			Body:         body,
+			ETag:         v.ETag,
+			LastModified: v.LastModified.UTC().Truncate(time.Second),
+		}
+		if encoded, err := json.Marshal(response); err == nil {
+			jobCache.Set(ctx, key, encoded, services.JobCacheTTL)
+		}
+	}
+
+	c.Header("ETag", response.ETag)
+	c.Header("Cache-Control", cacheControl)
+	if !response.LastModified.IsZero() {
+		c.Header("Last-Modified", response.LastModified.Format(http.TimeFormat))
+	}
+	if notModified(c.Request, response.ETag, response.LastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", response.Body)
+}
+
+// notModified evaluates If-None-Match and, only without it, If-Modified-Since
+// as RFC 9110 orders them.
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		return etagMatches(header, etag)
+	}
+	if header := r.Header.Get("If-Modified-Since"); header != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(header)
+		return err == nil && !lastModified.After(since)
+	}
+	return false
+}
+
+// etagMatches reports whether a comma separated If-None-Match header lists
+// etag. The comparison is weak, it ignores the W/ prefix.
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
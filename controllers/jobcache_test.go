@@ -0,0 +1,24 @@
+package controllers
+
+import "testing"
+
+func TestEtagMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		etag   string
+		want   bool
+	}{
+		{`"abc"`, `"abc"`, true},
+		{`W/"abc"`, `"abc"`, true},
+		{`"abc"`, `W/"abc"`, true},
+		{`"x", "abc"`, `"abc"`, true},
+		{"*", `"abc"`, true},
+		{`"abd"`, `"abc"`, false},
+		{"", `"abc"`, false},
+	}
+	for _, test := range tests {
+		if got := etagMatches(test.header, test.etag); got != test.want {
+			t.Errorf("etagMatches(%q, %q) = %v, want %v", test.header, test.etag, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,387 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	jobImportBatchSize = 100
+	jobImportMaxBytes  = 10 << 20
+)
+
+type jobImportRowReport struct {
+	Line        int      `json:"line"`
+	ExternalRef string   `json:"externalRef,omitempty"`
+	JobName     string   `json:"jobName,omitempty"`
+	Errors      []string `json:"errors"`
+}
+
+// jobImportRowResult is what became of a valid row: "inserted", "updated",
+// "unchanged", "conflict" or "notImported" when the import stopped first.
+type jobImportRowResult struct {
+	Line        int                `json:"line"`
+	ExternalRef string             `json:"externalRef"`
+	JobID       primitive.ObjectID `json:"jobId,omitempty"`
+	Status      string             `json:"status"`
+}
+
+type jobImportSummary struct {
+	DryRun    bool                 `json:"dryRun"`
+	Total     int                  `json:"total"`
+	Valid     int                  `json:"valid"`
+	Invalid   int                  `json:"invalid"`
+	Inserted  int64                `json:"inserted"`
+	Updated   int64                `json:"updated"`
+	Unchanged int64                `json:"unchanged"`
+	Errors    []jobImportRowReport `json:"errors"`
+	Rows      []jobImportRowResult `json:"rows"`
+	Error     string               `json:"error,omitempty"`
+}
+
+// ImportJobs accepts a CSV or JSON-lines upload of jobs in the "file" form
+// field. Every row is validated; with ?dryRun=true only the report is
+// returned. Valid rows are upserted in batches keyed on the owner and the
+// row's externalRef, so uploading the same file twice updates the jobs, and
+// every inserted or changed job gets a revision. Jobs in the trash are not
+// updated, and a job edited while the import runs is reported on its row.
+// The report lists what became of every valid row, also when a failing
+// batch stops the import after earlier batches were saved.
+func (jh *JobHandler) ImportJobs(c *gin.Context) {
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		jh.errorHandler.HandleBadRequest(c)
+		return
+	}
+	if role != "admin" {
+		jh.errorHandler.HandleUnauthorized(c)
+		return
+	}
+
+	userId, ok := c.MustGet("id").(string)
+	if !ok {
+		jh.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	ownerID, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		jh.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, jobImportMaxBytes)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An import file is required in the 'file' field"})
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	defer file.Close()
+
+	rows, err := services.ParseJobImport(format, file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	summary := jobImportSummary{
+		DryRun: c.Query("dryRun") == "true",
+		Total:  len(rows),
+		Errors: []jobImportRowReport{},
+		Rows:   []jobImportRowResult{},
+	}
+
+	var valid []services.JobImportRow
+	for _, row := range rows {
+		if len(row.Errors) > 0 {
+			summary.Errors = append(summary.Errors, jobImportRowReport{
+				Line:        row.Line,
+				ExternalRef: row.Job.ExternalRef,
+				JobName:     row.Job.JobName,
+				Errors:      row.Errors,
+			})
+			continue
+		}
+		valid = append(valid, row)
+	}
+	summary.Valid = len(valid)
+	summary.Invalid = len(summary.Errors)
+
+	if summary.DryRun || len(valid) == 0 {
+		c.JSON(http.StatusOK, summary)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// jobUpdate is an existing job a row changes
+	type jobUpdate struct {
+		row      services.JobImportRow
+		previous models.Job
+		updated  models.Job
+		fields   bson.M
+		revision models.JobRevision
+	}
+
+	// every batch is committed on its own, so when one fails the rows of
+	// the earlier batches are saved and the answer lists them
+	stopImport := func(from int) {
+		for _, row := range valid[from:] {
+			summary.Rows = append(summary.Rows, jobImportRowResult{Line: row.Line, ExternalRef: row.Job.ExternalRef, Status: "notImported"})
+		}
+		summary.Error = fmt.Sprintf("The import stopped at line %d, the rows before it were saved", valid[from].Line)
+		c.JSON(http.StatusInternalServerError, summary)
+	}
+
+	for start := 0; start < len(valid); start += jobImportBatchSize {
+		end := start + jobImportBatchSize
+		if end > len(valid) {
+			end = len(valid)
+		}
+		batch := valid[start:end]
+
+		var refs []string
+		for _, row := range batch {
+			refs = append(refs, row.Job.ExternalRef)
+		}
+
+		// trashed jobs are left alone, a row matching one creates a new job
+		cursor, err := jh.Collection.Find(ctx, services.NotDeleted(bson.M{"userId": ownerID, "externalRef": bson.M{"$in": refs}}))
+		if err != nil {
+			stopImport(start)
+			return
+		}
+		var existingJobs []models.Job
+		if err := cursor.All(ctx, &existingJobs); err != nil {
+			stopImport(start)
+			return
+		}
+		existing := map[string]models.Job{}
+		for _, job := range existingJobs {
+			existing[job.ExternalRef] = job
+		}
+
+		now := time.Now().UTC()
+		results := map[int]jobImportRowResult{}
+		var inserts []mongo.WriteModel
+		var inserted []models.Job
+		var insertRevisions []models.JobRevision
+		var updates []jobUpdate
+		for _, row := range batch {
+			job := row.Job
+			previous, found := existing[job.ExternalRef]
+			if !found {
+				job.ID = primitive.NewObjectID()
+				job.UserID = ownerID
+				job.Version = 1
+				job.UpdatedAt = now
+				if job.OptionalRequirements == nil {
+					job.OptionalRequirements = []string{}
+				}
+				inserts = append(inserts, mongo.NewInsertOneModel().SetDocument(job))
+				inserted = append(inserted, job)
+				results[row.Line] = jobImportRowResult{Line: row.Line, ExternalRef: job.ExternalRef, JobID: job.ID, Status: "inserted"}
+
+				revision, err := services.NewJobRevision(job, nil, models.RevisionImported, ownerID)
+				if err != nil {
+					stopImport(start)
+					return
+				}
+				insertRevisions = append(insertRevisions, revision)
+				continue
+			}
+
+			if err := services.RecordJobBaseline(ctx, &previous); err != nil {
+				stopImport(start)
+				return
+			}
+
+			updated := applyJobContent(previous, job)
+			changes, err := services.DiffJobs(previous, updated)
+			if err != nil {
+				stopImport(start)
+				return
+			}
+			if len(changes) == 0 {
+				results[row.Line] = jobImportRowResult{Line: row.Line, ExternalRef: job.ExternalRef, JobID: previous.ID, Status: "unchanged"}
+				continue
+			}
+
+			updated.Version = previous.Version + 1
+			updated.UpdatedAt = now
+			fields := jobContentFields(updated)
+			fields["updatedAt"] = now
+
+			revision, err := services.NewJobRevision(updated, &previous, models.RevisionImported, ownerID)
+			if err != nil {
+				stopImport(start)
+				return
+			}
+			updates = append(updates, jobUpdate{row: row, previous: previous, updated: updated, fields: fields, revision: revision})
+		}
+
+		var applied, conflicts []jobUpdate
+		if len(inserts) > 0 || len(updates) > 0 {
+			err = services.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+				applied, conflicts = nil, nil
+				if len(inserts) > 0 {
+					if _, err := jh.Collection.BulkWrite(sc, inserts); err != nil {
+						return err
+					}
+				}
+				// an update only applies to the version the row was compared
+				// with, a job edited or trashed since then is reported instead
+				for _, update := range updates {
+					result, err := jh.Collection.UpdateOne(sc,
+						services.NotDeleted(services.AtVersion(bson.M{"_id": update.previous.ID}, update.previous.Version)),
+						bson.M{"$set": update.fields, "$inc": bson.M{"version": 1}})
+					if err != nil {
+						return err
+					}
+					if result.MatchedCount == 0 {
+						conflicts = append(conflicts, update)
+						continue
+					}
+					applied = append(applied, update)
+				}
+
+				revisions := append([]models.JobRevision{}, insertRevisions...)
+				for _, update := range applied {
+					revisions = append(revisions, update.revision)
+				}
+				if err := services.RecordJobRevisions(sc, revisions...); err != nil {
+					return err
+				}
+				for _, job := range inserted {
+					if err := services.QueueWebhookEvent(sc, ownerID, models.EventJobCreated, services.JobWebhookData(job)); err != nil {
+						return err
+					}
+				}
+				for _, update := range applied {
+					if err := services.QueueWebhookEvent(sc, ownerID, models.EventJobUpdated, services.JobWebhookData(update.updated)); err != nil {
+						return err
+					}
+				}
+				if len(inserted) == 0 {
+					return nil
+				}
+				return services.QueueJobAlerts(sc, inserted...)
+			})
+			if err != nil {
+				stopImport(start)
+				return
+			}
+		}
+
+		for _, update := range applied {
+			results[update.row.Line] = jobImportRowResult{Line: update.row.Line, ExternalRef: update.row.Job.ExternalRef, JobID: update.updated.ID, Status: "updated"}
+			summary.Updated++
+		}
+		for _, update := range conflicts {
+			results[update.row.Line] = jobImportRowResult{Line: update.row.Line, ExternalRef: update.row.Job.ExternalRef, JobID: update.previous.ID, Status: "conflict"}
+			summary.Errors = append(summary.Errors, jobImportRowReport{
+				Line:        update.row.Line,
+				ExternalRef: update.row.Job.ExternalRef,
+				JobName:     update.row.Job.JobName,
+				Errors:      []string{"the job was changed or moved to the trash during the import, upload the row again"},
+			})
+		}
+
+		summary.Inserted += int64(len(inserted))
+		for _, row := range batch {
+			result := results[row.Line]
+			if result.Status == "unchanged" {
+				summary.Unchanged++
+			}
+			summary.Rows = append(summary.Rows, result)
+		}
+
+		changedIDs := make([]primitive.ObjectID, len(applied))
+		for i, update := range applied {
+			changedIDs[i] = update.updated.ID
+		}
+		services.InvalidateJobCache(ctx, changedIDs...)
+
+		for _, job := range inserted {
+			audit(ctx, c, models.AuditJobImport, models.AuditTargetJob, job.ID, nil, jobContentFields(job))
+		}
+		for _, update := range applied {
+			before, after := changedFields(jobContentFields(update.previous), jobContentFields(update.updated))
+			audit(ctx, c, models.AuditJobImport, models.AuditTargetJob, update.updated.ID, before, after)
+		}
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// applyJobContent returns job with the content fields of update copied over.
+func applyJobContent(job, update models.Job) models.Job {
+	job.JobName = update.JobName
+	job.Type = update.Type
+	job.Location = update.Location
+	job.SalaryHigh = update.SalaryHigh
+	job.SalaryLow = update.SalaryLow
+	job.Company = update.Company
+	job.ImageLink = update.ImageLink
+	job.Sponsored = update.Sponsored
+	job.Currency = update.Currency
+	job.MandatoryRequirements = update.MandatoryRequirements
+	job.OptionalRequirements = update.OptionalRequirements
+	if job.OptionalRequirements == nil {
+		job.OptionalRequirements = []string{}
+	}
+	job.JobDescription = update.JobDescription
+	job.Industry = update.Industry
+	return job
+}
+
+// jobContentFields lists the user editable fields of a job for a $set.
+func jobContentFields(job models.Job) bson.M {
+	optional := job.OptionalRequirements
+	if optional == nil {
+		optional = []string{}
+	}
+	return bson.M{
+		"jobName":               job.JobName,
+		"type":                  job.Type,
+		"location":              job.Location,
+		"salaryHigh":            job.SalaryHigh,
+		"salaryLow":             job.SalaryLow,
+		"salaryHighAmount":      services.SalaryAmount(job.SalaryHigh),
+		"salaryLowAmount":       services.SalaryAmount(job.SalaryLow),
+		"company":               job.Company,
+		"imageLink":             job.ImageLink,
+		"sponsored":             job.Sponsored,
+		"currency":              job.Currency,
+		"mandatoryRequirements": job.MandatoryRequirements,
+		"optionalRequirements":  optional,
+		"jobDescription":        job.JobDescription,
+		"industry":              job.Industry,
+		"screeningQuestions":    job.ScreeningQuestions,
+		"knockoutAction":        job.KnockoutAction,
+		"blindReview":           job.BlindReview,
+	}
+}
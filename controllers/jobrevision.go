@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ownedJob loads the job in the :id path parameter and checks that it belongs
+// to the admin making the request. It writes the error response itself.
+func (jh *JobHandler) ownedJob(ctx context.Context, c *gin.Context) (*models.Job, primitive.ObjectID, bool) {
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		jh.errorHandler.HandleBadRequest(c)
+		return nil, primitive.NilObjectID, false
+	}
+	if role != "admin" {
+		jh.errorHandler.HandleUnauthorized(c)
+		return nil, primitive.NilObjectID, false
+	}
+
+	userId, ok := c.MustGet("id").(string)
+	if !ok {
+		jh.errorHandler.HandleBadRequest(c)
+		return nil, primitive.NilObjectID, false
+	}
+
+	userObjId, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		jh.errorHandler.HandleBadRequest(c)
+		return nil, primitive.NilObjectID, false
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		jh.errorHandler.HandleBadRequest(c)
+		return nil, primitive.NilObjectID, false
+	}
+
+	var job models.Job
+	err = jh.Collection.FindOne(ctx, services.NotDeleted(bson.M{"_id": objectId})).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			jh.errorHandler.HandleNotFound(c)
+			return nil, primitive.NilObjectID, false
+		}
+		jh.errorHandler.HandleInternalServerError(c)
+		return nil, primitive.NilObjectID, false
+	}
+
+	if job.UserID != userObjId {
+		jh.errorHandler.HandleUnauthorized(c)
+		return nil, primitive.NilObjectID, false
+	}
+
+	return &job, userObjId, true
+}
+
+// updateJob applies update to the job previous was read from and, in the
+// same transaction, records the resulting revision and queues the
+// job.updated webhook. It returns the updated job, or
+// services.ErrVersionConflict when the job was changed or deleted since.
+func (jh *JobHandler) updateJob(ctx context.Context, update bson.M, previous *models.Job, action string, authorID primitive.ObjectID, restoredFrom int) (*models.Job, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	filter := services.AtVersion(services.NotDeleted(bson.M{"_id": previous.ID}), previous.Version)
+
+	var updatedJob models.Job
+	err := services.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		err := jh.Collection.FindOneAndUpdate(sc, filter, update, opts).Decode(&updatedJob)
+		if err == mongo.ErrNoDocuments {
+			return services.ErrVersionConflict
+		} else if err != nil {
+			return err
+		}
+		revision, err := services.NewJobRevision(updatedJob, previous, action, authorID)
+		if err != nil {
+			return err
+		}
+		revision.RestoredFrom = restoredFrom
+		if err := services.RecordJobRevisions(sc, revision); err != nil {
+			return err
+		}
+		return services.QueueWebhookEvent(sc, authorID, models.EventJobUpdated, services.JobWebhookData(updatedJob))
+	})
+	if err != nil {
+		return nil, err
+	}
+	services.InvalidateJobCache(ctx, updatedJob.ID)
+	return &updatedJob, nil
+}
+
+// handleUpdateJobError answers a failed updateJob. A version conflict is a
+// 412 with the job's current version, or a 404 if it was deleted.
+func (jh *JobHandler) handleUpdateJobError(ctx context.Context, c *gin.Context, jobID primitive.ObjectID, err error) {
+	if !errors.Is(err, services.ErrVersionConflict) {
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	var current models.Job
+	err = jh.Collection.FindOne(ctx, services.NotDeleted(bson.M{"_id": jobID})).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		jh.errorHandler.HandleNotFound(c)
+		return
+	} else if err != nil {
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	respondPreconditionFailed(c, current.Version)
+}
+
+func (jh *JobHandler) GetJobRevisions(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job, _, ok := jh.ownedJob(ctx, c)
+	if !ok {
+		return
+	}
+
+	revisions, err := services.GetJobRevisions(ctx, job.ID)
+	if err != nil {
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+func (jh *JobHandler) GetJobRevision(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job, _, ok := jh.ownedJob(ctx, c)
+	if !ok {
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		jh.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	revision, err := services.GetJobRevision(ctx, job.ID, version)
+	if err == services.ErrRevisionNotFound {
+		jh.errorHandler.HandleNotFound(c)
+		return
+	} else if err != nil {
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+// DiffJobRevisions compares the snapshots of two revisions given by the from
+// and to query parameters. to defaults to the latest revision.
+func (jh *JobHandler) DiffJobRevisions(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job, _, ok := jh.ownedJob(ctx, c)
+	if !ok {
+		return
+	}
+
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a revision number"})
+		return
+	}
+	to := job.Version
+	if toParam := c.Query("to"); toParam != "" {
+		to, err = strconv.Atoi(toParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a revision number"})
+			return
+		}
+	}
+
+	fromRevision, err := services.GetJobRevision(ctx, job.ID, from)
+	if err == services.ErrRevisionNotFound {
+		jh.errorHandler.HandleNotFound(c)
+		return
+	} else if err != nil {
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	toRevision, err := services.GetJobRevision(ctx, job.ID, to)
+	if err == services.ErrRevisionNotFound {
+		jh.errorHandler.HandleNotFound(c)
+		return
+	} else if err != nil {
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	changes, err := services.DiffJobs(fromRevision.Snapshot, toRevision.Snapshot)
+	if err != nil {
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobId":   job.ID,
+		"from":    from,
+		"to":      to,
+		"changes": changes,
+	})
+}
+
+// RestoreJobRevision copies the content of an older revision back onto the
+// job. The restore is itself recorded as a new revision.
+func (jh *JobHandler) RestoreJobRevision(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job, userObjId, ok := jh.ownedJob(ctx, c)
+	if !ok {
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		jh.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	revision, err := services.GetJobRevision(ctx, job.ID, version)
+	if err == services.ErrRevisionNotFound {
+		jh.errorHandler.HandleNotFound(c)
+		return
+	} else if err != nil {
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	if !ifMatch(c, job.Version) {
+		respondPreconditionFailed(c, job.Version)
+		return
+	}
+
+	if err := services.RecordJobBaseline(ctx, job); err != nil {
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	fields := jobContentFields(revision.Snapshot)
+	fields["updatedAt"] = time.Now().UTC()
+	update := bson.M{"$set": fields, "$inc": bson.M{"version": 1}}
+
+	restoredJob, err := jh.updateJob(ctx, update, job, models.RevisionRestored, userObjId, version)
+	if err != nil {
+		jh.handleUpdateJobError(ctx, c, job.ID, err)
+		return
+	}
+
+	before, after := changedFields(jobContentFields(*job), jobContentFields(*restoredJob))
+	after["restoredFrom"] = version
+	audit(ctx, c, models.AuditJobRevisionRestore, models.AuditTargetJob, job.ID, before, after)
+
+	c.Header("ETag", versionETag(restoredJob.Version))
+	c.JSON(http.StatusOK, restoredJob)
+}
@@ -0,0 +1,400 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxMessageLength      = 5000
+	maxMessageAttachments = 5
+	maxAttachmentSize     = 10 << 20
+)
+
+type MessageHandler struct {
+	Collection   *mongo.Collection
+	errorHandler *handler.ErrorHandler
+}
+
+func NewMessageHandler(collection *mongo.Collection, errorHandler *handler.ErrorHandler) *MessageHandler {
+	return &MessageHandler{
+		Collection:   collection,
+		errorHandler: errorHandler,
+	}
+}
+
+// messageUser returns the role and id of the logged in user.
+func (mh *MessageHandler) messageUser(c *gin.Context) (string, primitive.ObjectID, bool) {
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		mh.errorHandler.HandleBadRequest(c)
+		return "", primitive.NilObjectID, false
+	}
+
+	userId, ok := c.MustGet("id").(string)
+	if !ok {
+		mh.errorHandler.HandleBadRequest(c)
+		return "", primitive.NilObjectID, false
+	}
+
+	userObjId, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		mh.errorHandler.HandleBadRequest(c)
+		return "", primitive.NilObjectID, false
+	}
+	return role, userObjId, true
+}
+
+// threadApplication loads the application in the :id path parameter. A
+// candidate can only open the thread of their own application and a
+// recruiter only threads of applications to their jobs. It writes the error
+// response itself.
+func (mh *MessageHandler) threadApplication(ctx context.Context, c *gin.Context) (*models.Application, *models.Job, string, primitive.ObjectID, bool) {
+	role, userObjId, ok := mh.messageUser(c)
+	if !ok {
+		return nil, nil, "", primitive.NilObjectID, false
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		mh.errorHandler.HandleBadRequest(c)
+		return nil, nil, "", primitive.NilObjectID, false
+	}
+
+	var application models.Application
+	err = db.DB.Collection("applications").FindOne(ctx, services.NotDeleted(bson.M{"_id": objectId})).Decode(&application)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			mh.errorHandler.HandleNotFound(c)
+			return nil, nil, "", primitive.NilObjectID, false
+		}
+		mh.errorHandler.HandleInternalServerError(c)
+		return nil, nil, "", primitive.NilObjectID, false
+	}
+
+	var job models.Job
+	err = db.DB.Collection("jobs").FindOne(ctx, bson.M{"_id": application.JobID}).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			mh.errorHandler.HandleNotFound(c)
+			return nil, nil, "", primitive.NilObjectID, false
+		}
+		mh.errorHandler.HandleInternalServerError(c)
+		return nil, nil, "", primitive.NilObjectID, false
+	}
+
+	switch {
+	case role == "user" && application.UserID == userObjId:
+	case role == "admin" && job.UserID == userObjId:
+	default:
+		mh.errorHandler.HandleNotFound(c)
+		return nil, nil, "", primitive.NilObjectID, false
+	}
+
+	return &application, &job, role, userObjId, true
+}
+
+// GetThreads lists the conversations of the logged in user with the latest
+// message and the number of unread messages.
+func (mh *MessageHandler) GetThreads(c *gin.Context) {
+	role, userObjId, ok := mh.messageUser(c)
+	if !ok {
+		return
+	}
+
+	match := bson.M{"candidateId": userObjId}
+	if role == "admin" {
+		match = bson.M{"recruiterId": userObjId}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "createdAt", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$applicationId",
+			"lastMessage": bson.M{"$first": "$$ROOT"},
+			"total":       bson.M{"$sum": 1},
+			"unread": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$and": bson.A{
+					bson.M{"$ne": bson.A{"$senderId", userObjId}},
+					bson.M{"$not": bson.A{"$readAt"}},
+				}},
+				1,
+				0,
+			}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "lastMessage.createdAt", Value: -1}}}},
+	}
+
+	cursor, err := mh.Collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		mh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	threads := []models.MessageThread{}
+	if err := cursor.All(ctx, &threads); err != nil {
+		mh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	if role == "admin" {
+		ids := make([]primitive.ObjectID, 0, len(threads))
+		for _, thread := range threads {
+			ids = append(ids, thread.ApplicationID)
+		}
+		hidden, err := services.HiddenApplications(ctx, ids)
+		if err != nil {
+			mh.errorHandler.HandleInternalServerError(c)
+			return
+		}
+		for i, thread := range threads {
+			if application, ok := hidden[thread.ApplicationID]; ok {
+				threads[i].LastMessage = services.MaskMessage(application, thread.LastMessage)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, threads)
+}
+
+// GetMessages returns the conversation of an application, oldest first.
+func (mh *MessageHandler) GetMessages(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	application, job, role, _, ok := mh.threadApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}})
+	cursor, err := mh.Collection.Find(ctx, bson.M{"applicationId": application.ID}, opts)
+	if err != nil {
+		mh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	messages := []models.Message{}
+	if err := cursor.All(ctx, &messages); err != nil {
+		mh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	if role == "admin" && services.IdentityHidden(job, *application) {
+		for i, message := range messages {
+			messages[i] = services.MaskMessage(*application, message)
+		}
+	}
+
+	c.JSON(http.StatusOK, messages)
+}
+
+// SendMessage adds a message to the conversation of an application.
+// Attachments are sent base64 encoded like resumes and stored in GridFS.
+func (mh *MessageHandler) SendMessage(c *gin.Context) {
+	var request struct {
+		Body        string       `json:"body"`
+		Attachments []models.PDF `json:"attachments"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		mh.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	request.Body = strings.TrimSpace(request.Body)
+	var problems []string
+	if request.Body == "" && len(request.Attachments) == 0 {
+		problems = append(problems, "a message needs a body or attachments")
+	}
+	if len(request.Body) > maxMessageLength {
+		problems = append(problems, "body is longer than 5000 characters")
+	}
+	if len(request.Attachments) > maxMessageAttachments {
+		problems = append(problems, "at most 5 attachments can be sent")
+	}
+	for _, attachment := range request.Attachments {
+		if attachment.Filename == "" || len(attachment.Data) == 0 {
+			problems = append(problems, "attachments need a filename and data")
+		}
+		if len(attachment.Data) > maxAttachmentSize {
+			problems = append(problems, attachment.Filename+" is larger than 10MB")
+		}
+	}
+	if len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message", "details": problems})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	application, job, role, userObjId, ok := mh.threadApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	message := models.Message{
+		ID:            primitive.NewObjectID(),
+		ApplicationID: application.ID,
+		JobID:         job.ID,
+		JobName:       job.JobName,
+		CandidateID:   application.UserID,
+		RecruiterID:   job.UserID,
+		SenderID:      userObjId,
+		SenderRole:    role,
+		Body:          request.Body,
+		Attachments:   []models.MessageAttachment{},
+		SenderName:    c.GetString("name"),
+		CreatedAt:     time.Now().UTC(),
+	}
+
+	for _, attachment := range request.Attachments {
+		fileID, err := services.UploadFile(ctx, attachment.Filename, attachment.Data)
+		if err != nil {
+			mh.deleteAttachments(ctx, message.Attachments)
+			mh.errorHandler.HandleInternalServerError(c)
+			return
+		}
+		if err := services.GuardUpload(ctx, fileID); err != nil {
+			services.DeleteFile(ctx, fileID)
+			mh.deleteAttachments(ctx, message.Attachments)
+			mh.errorHandler.HandleInternalServerError(c)
+			return
+		}
+		contentType := attachment.ContentType
+		if contentType == "" {
+			contentType = http.DetectContentType(attachment.Data)
+		}
+		message.Attachments = append(message.Attachments, models.MessageAttachment{
+			FileID:      fileID,
+			Filename:    attachment.Filename,
+			ContentType: contentType,
+			Size:        len(attachment.Data),
+		})
+	}
+
+	err := services.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		if _, err := mh.Collection.InsertOne(sc, message); err != nil {
+			return err
+		}
+		// the recruiter is told about the candidate's message by alias
+		if services.IdentityHidden(job, *application) {
+			return services.NotifyNewMessage(sc, services.MaskMessage(*application, message))
+		}
+		return services.NotifyNewMessage(sc, message)
+	})
+	if err != nil {
+		mh.deleteAttachments(ctx, message.Attachments)
+		mh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusCreated, message)
+}
+
+func (mh *MessageHandler) deleteAttachments(ctx context.Context, attachments []models.MessageAttachment) {
+	for _, attachment := range attachments {
+		if err := services.DeleteFile(ctx, attachment.FileID); err != nil {
+			log.Println("error deleting message attachment", err)
+		}
+	}
+}
+
+// MarkMessagesRead records a read receipt on every message of the thread
+// that was sent by the other side and not read yet.
+func (mh *MessageHandler) MarkMessagesRead(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	application, _, _, userObjId, ok := mh.threadApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	filter := bson.M{
+		"applicationId": application.ID,
+		"senderId":      bson.M{"$ne": userObjId},
+		"readAt":        bson.M{"$exists": false},
+	}
+	result, err := mh.Collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"readAt": now}})
+	if err != nil {
+		mh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"read": result.ModifiedCount, "readAt": now})
+}
+
+// GetMessageAttachment downloads an attachment of a message in a thread the
+// user has access to.
+func (mh *MessageHandler) GetMessageAttachment(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	application, job, role, _, ok := mh.threadApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	messageId, err := primitive.ObjectIDFromHex(c.Param("messageId"))
+	if err != nil {
+		mh.errorHandler.HandleBadRequest(c)
+		return
+	}
+	fileId, err := primitive.ObjectIDFromHex(c.Param("fileId"))
+	if err != nil {
+		mh.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	var message models.Message
+	err = mh.Collection.FindOne(ctx, bson.M{"_id": messageId, "applicationId": application.ID}).Decode(&message)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			mh.errorHandler.HandleNotFound(c)
+			return
+		}
+		mh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	if role == "admin" && services.IdentityHidden(job, *application) {
+		message = services.MaskMessage(*application, message)
+	}
+
+	for _, attachment := range message.Attachments {
+		if attachment.FileID != fileId {
+			continue
+		}
+		data, err := services.DownloadFile(ctx, attachment.FileID)
+		if err != nil {
+			mh.errorHandler.HandleInternalServerError(c)
+			return
+		}
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+		c.Data(http.StatusOK, attachment.ContentType, data)
+		return
+	}
+
+	mh.errorHandler.HandleNotFound(c)
+}
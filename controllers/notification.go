@@ -0,0 +1,301 @@
+package controllers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const streamHeartbeat = 25 * time.Second
+
+type NotificationHandler struct {
+	Collection   *mongo.Collection
+	errorHandler *handler.ErrorHandler
+}
+
+func NewNotificationHandler(collection *mongo.Collection, errorHandler *handler.ErrorHandler) *NotificationHandler {
+	return &NotificationHandler{
+		Collection:   collection,
+		errorHandler: errorHandler,
+	}
+}
+
+func (nh *NotificationHandler) userID(c *gin.Context) (primitive.ObjectID, bool) {
+	userId, ok := c.MustGet("id").(string)
+	if !ok {
+		nh.errorHandler.HandleBadRequest(c)
+		return primitive.NilObjectID, false
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		nh.errorHandler.HandleBadRequest(c)
+		return primitive.NilObjectID, false
+	}
+	return objectId, true
+}
+
+// GetNotifications lists the notifications of the logged in user, newest
+// first. unread=true only returns unread ones.
+func (nh *NotificationHandler) GetNotifications(c *gin.Context) {
+	userID, ok := nh.userID(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
+
+	filter := bson.M{"userId": userID}
+	if c.Query("unread") == "true" {
+		filter["read"] = false
+	}
+	if before := c.Query("before"); before != "" {
+		beforeId, err := primitive.ObjectIDFromHex(before)
+		if err != nil {
+			nh.errorHandler.HandleBadRequest(c)
+			return
+		}
+		filter["_id"] = bson.M{"$lt": beforeId}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
+	cursor, err := nh.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		nh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	notifications := []models.Notification{}
+	if err := cursor.All(ctx, &notifications); err != nil {
+		nh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	unread, err := nh.Collection.CountDocuments(ctx, bson.M{"userId": userID, "read": false})
+	if err != nil {
+		nh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"notifications": notifications, "unread": unread})
+}
+
+func (nh *NotificationHandler) GetUnreadCount(c *gin.Context) {
+	userID, ok := nh.userID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	unread, err := nh.Collection.CountDocuments(ctx, bson.M{"userId": userID, "read": false})
+	if err != nil {
+		nh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"unread": unread})
+}
+
+// SetNotificationRead marks a notification read (POST) or unread again
+// (DELETE).
+func (nh *NotificationHandler) SetNotificationRead(c *gin.Context) {
+	userID, ok := nh.userID(c)
+	if !ok {
+		return
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		nh.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"read": true, "readAt": time.Now().UTC()}}
+	if c.Request.Method == http.MethodDelete {
+		update = bson.M{"$set": bson.M{"read": false}, "$unset": bson.M{"readAt": ""}}
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var notification models.Notification
+	err = nh.Collection.FindOneAndUpdate(ctx, bson.M{"_id": objectId, "userId": userID}, update, opts).Decode(&notification)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			nh.errorHandler.HandleNotFound(c)
+			return
+		}
+		nh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, notification)
+}
+
+func (nh *NotificationHandler) MarkAllRead(c *gin.Context) {
+	userID, ok := nh.userID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{"read": true, "readAt": time.Now().UTC()}}
+	result, err := nh.Collection.UpdateMany(ctx, bson.M{"userId": userID, "read": false}, update)
+	if err != nil {
+		nh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"read": result.ModifiedCount})
+}
+
+func (nh *NotificationHandler) DeleteNotification(c *gin.Context) {
+	userID, ok := nh.userID(c)
+	if !ok {
+		return
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		nh.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := nh.Collection.DeleteOne(ctx, bson.M{"_id": objectId, "userId": userID})
+	if err != nil {
+		nh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	if result.DeletedCount == 0 {
+		nh.errorHandler.HandleNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "notification deleted"})
+}
+
+// CreateStreamTicket issues the single use ticket a browser opens the
+// notification stream with, so the access token stays out of the URL.
+func (nh *NotificationHandler) CreateStreamTicket(c *gin.Context) {
+	userID, _ := c.MustGet("id").(string)
+	name, _ := c.MustGet("name").(string)
+	email, _ := c.MustGet("email").(string)
+	role, _ := c.MustGet("role").(string)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ticket, expiresAt, err := services.IssueStreamTicket(ctx, userID, name, email, role)
+	if err != nil {
+		nh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"ticket": ticket, "expiresAt": expiresAt})
+}
+
+// StreamNotifications pushes notifications to the client as Server-Sent
+// Events, named after the notification type with the notification id as
+// event id. A reconnecting client sending Last-Event-ID first receives the
+// notifications it missed. Tickets are single use, so a browser reconnects
+// with a new ticket and passes the last id it saw as lastEventId.
+func (nh *NotificationHandler) StreamNotifications(c *gin.Context) {
+	userID, ok := nh.userID(c)
+	if !ok {
+		return
+	}
+
+	// subscribe before replaying so nothing falls in between
+	events, unsubscribe := services.GetBroker().Subscribe(userID)
+	defer unsubscribe()
+
+	var missed []models.Notification
+	lastEventId := c.GetHeader("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = c.Query("lastEventId")
+	}
+	if lastEventId != "" {
+		if lastId, err := primitive.ObjectIDFromHex(lastEventId); err == nil {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+			opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(200)
+			cursor, err := nh.Collection.Find(ctx, bson.M{"userId": userID, "_id": bson.M{"$gt": lastId}}, opts)
+			if err == nil {
+				err = cursor.All(ctx, &missed)
+			}
+			cancel()
+			if err != nil {
+				nh.errorHandler.HandleInternalServerError(c)
+				return
+			}
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	sent := map[primitive.ObjectID]bool{}
+	send := func(notification models.Notification) {
+		if sent[notification.ID] {
+			return
+		}
+		sent[notification.ID] = true
+		c.Render(-1, sse.Event{Id: notification.ID.Hex(), Event: notification.Type, Data: notification})
+	}
+
+	c.Render(-1, sse.Event{Event: "ready", Data: gin.H{"userId": userID}})
+	for _, notification := range missed {
+		send(notification)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-services.StreamsClosed():
+			return false
+		case notification, ok := <-events:
+			if !ok {
+				return false
+			}
+			send(notification)
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type PrivacyHandler struct {
+	Collection   *mongo.Collection
+	errorHandler *handler.ErrorHandler
+}
+
+func NewPrivacyHandler(collection *mongo.Collection, errorHandler *handler.ErrorHandler) *PrivacyHandler {
+	return &PrivacyHandler{
+		Collection:   collection,
+		errorHandler: errorHandler,
+	}
+}
+
+// candidateID returns the id of the logged in candidate. Export and erasure
+// are only offered to candidates; recruiter accounts own jobs other people
+// depend on.
+func (ph *PrivacyHandler) candidateID(c *gin.Context) (primitive.ObjectID, bool) {
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		ph.errorHandler.HandleBadRequest(c)
+		return primitive.NilObjectID, false
+	}
+	if role != "user" {
+		ph.errorHandler.HandleUnauthorized(c)
+		return primitive.NilObjectID, false
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.GetString("id"))
+	if err != nil {
+		ph.errorHandler.HandleBadRequest(c)
+		return primitive.NilObjectID, false
+	}
+	return userID, true
+}
+
+// ExportData sends a ZIP archive of everything stored about the logged in
+// candidate, including the original resume and attachment files.
+func (ph *PrivacyHandler) ExportData(c *gin.Context) {
+	userID, ok := ph.candidateID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Minute)
+	defer cancel()
+
+	filename := "jobly-export-" + time.Now().UTC().Format("20060102") + ".zip"
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	// the archive is streamed, so a failure can only cut it short
+	if err := services.WriteDataExport(ctx, c.Writer, userID); err != nil {
+		log.Println("error writing data export", userID.Hex(), err)
+		c.Abort()
+	}
+}
+
+// RequestErasure schedules the erasure of the logged in candidate's data.
+// The password is asked again, and the request can be cancelled until it
+// runs.
+func (ph *PrivacyHandler) RequestErasure(c *gin.Context) {
+	userID, ok := ph.candidateID(c)
+	if !ok {
+		return
+	}
+
+	var erasureRequest struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&erasureRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password is required"})
+		return
+	}
+
+	if _, err := services.LoginUser(c.GetString("email"), erasureRequest.Password); err != nil {
+		if err == services.ErrUserNotFound || err == services.ErrInvalidCredentials || err == services.ErrAccountDeleted {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			return
+		}
+		ph.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	request, err := services.RequestErasure(ctx, userID)
+	if err == services.ErrErasurePending {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		ph.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	audit(ctx, c, models.AuditUserErasureRequest, models.AuditTargetUser, userID, nil, request)
+
+	c.JSON(http.StatusAccepted, request)
+}
+
+// GetErasureRequest shows the latest erasure request of the logged in
+// candidate.
+func (ph *PrivacyHandler) GetErasureRequest(c *gin.Context) {
+	userID, ok := ph.candidateID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	request, err := services.GetErasureRequest(ctx, userID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ph.errorHandler.HandleNotFound(c)
+			return
+		}
+		ph.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// CancelErasure cancels the pending erasure request of the logged in
+// candidate.
+func (ph *PrivacyHandler) CancelErasure(c *gin.Context) {
+	userID, ok := ph.candidateID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	request, err := services.CancelErasure(ctx, userID)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ph.errorHandler.HandleNotFound(c)
+			return
+		}
+		ph.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	audit(ctx, c, models.AuditUserErasureCancel, models.AuditTargetUser, userID, nil, nil)
+
+	c.JSON(http.StatusOK, request)
+}
@@ -0,0 +1,356 @@
+package controllers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ProfileHandler struct {
+	Collection   *mongo.Collection
+	errorHandler *handler.ErrorHandler
+}
+
+func NewProfileHandler(collection *mongo.Collection, errorHandler *handler.ErrorHandler) *ProfileHandler {
+	return &ProfileHandler{
+		Collection:   collection,
+		errorHandler: errorHandler,
+	}
+}
+
+// candidateID returns the id of the logged in candidate.
+func (ph *ProfileHandler) candidateID(c *gin.Context) (primitive.ObjectID, bool) {
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		ph.errorHandler.HandleBadRequest(c)
+		return primitive.NilObjectID, false
+	}
+	if role != "user" {
+		ph.errorHandler.HandleUnauthorized(c)
+		return primitive.NilObjectID, false
+	}
+
+	userId, ok := c.MustGet("id").(string)
+	if !ok {
+		ph.errorHandler.HandleBadRequest(c)
+		return primitive.NilObjectID, false
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		ph.errorHandler.HandleBadRequest(c)
+		return primitive.NilObjectID, false
+	}
+	return objectId, true
+}
+
+func validateProfile(profile *models.Profile) []string {
+	var problems []string
+
+	profile.Headline = strings.TrimSpace(profile.Headline)
+	if len(profile.Headline) < 3 {
+		problems = append(problems, "headline must be at least 3 characters")
+	}
+
+	var skills []string
+	seen := map[string]bool{}
+	for _, skill := range profile.Skills {
+		skill = strings.TrimSpace(skill)
+		if skill == "" || seen[strings.ToLower(skill)] {
+			continue
+		}
+		seen[strings.ToLower(skill)] = true
+		skills = append(skills, skill)
+	}
+	profile.Skills = skills
+
+	for i, work := range profile.WorkHistory {
+		if strings.TrimSpace(work.Title) == "" || strings.TrimSpace(work.Company) == "" {
+			problems = append(problems, "work history entries need a title and company")
+		}
+		if work.Current {
+			profile.WorkHistory[i].EndDate = ""
+		}
+	}
+	for _, education := range profile.Education {
+		if strings.TrimSpace(education.Institution) == "" {
+			problems = append(problems, "education entries need an institution")
+		}
+		if education.StartYear != 0 && education.EndYear != 0 && education.EndYear < education.StartYear {
+			problems = append(problems, "education end year is before start year")
+		}
+	}
+	for _, link := range profile.Links {
+		u, err := url.Parse(link.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, "links must be absolute http(s) urls")
+		}
+	}
+
+	if profile.Skills == nil {
+		profile.Skills = []string{}
+	}
+	if profile.WorkHistory == nil {
+		profile.WorkHistory = []models.WorkExperience{}
+	}
+	if profile.Education == nil {
+		profile.Education = []models.Education{}
+	}
+	if profile.Links == nil {
+		profile.Links = []models.ProfileLink{}
+	}
+
+	return problems
+}
+
+func (ph *ProfileHandler) GetProfile(c *gin.Context) {
+	userID, ok := ph.candidateID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var profile models.Profile
+	err := ph.Collection.FindOne(ctx, bson.M{"userId": userID}).Decode(&profile)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ph.errorHandler.HandleNotFound(c)
+			return
+		}
+		ph.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+func (ph *ProfileHandler) CreateProfile(c *gin.Context) {
+	userID, ok := ph.candidateID(c)
+	if !ok {
+		return
+	}
+
+	var profile models.Profile
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		ph.errorHandler.HandleBadRequest(c)
+		return
+	}
+	if problems := validateProfile(&profile); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid profile", "details": problems})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	count, err := ph.Collection.CountDocuments(ctx, bson.M{"userId": userID})
+	if err != nil {
+		ph.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	if count > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Profile already exists"})
+		return
+	}
+
+	now := time.Now().UTC()
+	profile.ID = primitive.NewObjectID()
+	profile.UserID = userID
+	profile.DefaultResume = nil
+	profile.CreatedAt = now
+	profile.UpdatedAt = now
+
+	_, err = ph.Collection.InsertOne(ctx, profile)
+	if err != nil {
+		ph.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusCreated, profile)
+}
+
+func (ph *ProfileHandler) UpdateProfile(c *gin.Context) {
+	userID, ok := ph.candidateID(c)
+	if !ok {
+		return
+	}
+
+	var profile models.Profile
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		ph.errorHandler.HandleBadRequest(c)
+		return
+	}
+	if problems := validateProfile(&profile); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid profile", "details": problems})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update := bson.M{"$set": bson.M{
+		"headline":    profile.Headline,
+		"summary":     profile.Summary,
+		"skills":      profile.Skills,
+		"workHistory": profile.WorkHistory,
+		"education":   profile.Education,
+		"links":       profile.Links,
+		"updatedAt":   time.Now().UTC(),
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated models.Profile
+	err := ph.Collection.FindOneAndUpdate(ctx, bson.M{"userId": userID}, update, opts).Decode(&updated)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ph.errorHandler.HandleNotFound(c)
+			return
+		}
+		ph.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+func (ph *ProfileHandler) DeleteProfile(c *gin.Context) {
+	userID, ok := ph.candidateID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var profile models.Profile
+	err := ph.Collection.FindOneAndDelete(ctx, bson.M{"userId": userID}).Decode(&profile)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ph.errorHandler.HandleNotFound(c)
+			return
+		}
+		ph.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	if profile.DefaultResume != nil {
+		if fileID, err := services.ResumeFileID(*profile.DefaultResume); err == nil {
+			if err := services.DeleteFile(ctx, fileID); err != nil {
+				log.Println("error deleting profile resume", err)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "profile deleted"})
+}
+
+// UploadDefaultResume stores the resume used for one click applications,
+// replacing the previous one.
+func (ph *ProfileHandler) UploadDefaultResume(c *gin.Context) {
+	userID, ok := ph.candidateID(c)
+	if !ok {
+		return
+	}
+
+	var resume models.PDF
+	if err := c.ShouldBindJSON(&resume); err != nil || resume.Filename == "" || len(resume.Data) == 0 {
+		ph.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var profile models.Profile
+	err := ph.Collection.FindOne(ctx, bson.M{"userId": userID}).Decode(&profile)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ph.errorHandler.HandleNotFound(c)
+			return
+		}
+		ph.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	stored, err := services.StoreResume(ctx, resume)
+	if err != nil {
+		ph.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	update := bson.M{"$set": bson.M{"defaultResume": stored, "updatedAt": time.Now().UTC()}}
+	_, err = ph.Collection.UpdateOne(ctx, bson.M{"_id": profile.ID}, update)
+	if err != nil {
+		if fileID, idErr := services.ResumeFileID(stored); idErr == nil {
+			services.DeleteFile(ctx, fileID)
+		}
+		ph.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	if profile.DefaultResume != nil {
+		if fileID, err := services.ResumeFileID(*profile.DefaultResume); err == nil {
+			if err := services.DeleteFile(ctx, fileID); err != nil {
+				log.Println("error deleting previous profile resume", err)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "resume uploaded", "filename": stored.Filename})
+}
+
+func (ph *ProfileHandler) GetDefaultResume(c *gin.Context) {
+	userID, ok := ph.candidateID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var profile models.Profile
+	err := ph.Collection.FindOne(ctx, bson.M{"userId": userID}).Decode(&profile)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ph.errorHandler.HandleNotFound(c)
+			return
+		}
+		ph.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	if profile.DefaultResume == nil {
+		ph.errorHandler.HandleNotFound(c)
+		return
+	}
+
+	fileID, err := services.ResumeFileID(*profile.DefaultResume)
+	if err != nil {
+		ph.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	data, err := services.DownloadFile(ctx, fileID)
+	if err != nil {
+		ph.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PDF{
+		Filename:    profile.DefaultResume.Filename,
+		ContentType: profile.DefaultResume.ContentType,
+		Data:        data,
+	})
+}
@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// recruiterApplication loads the application in the :id path parameter and
+// checks that it was made to a job owned by the admin making the request. It
+// writes the error response itself.
+func (ah *ApplicationHandler) recruiterApplication(ctx context.Context, c *gin.Context) (*models.Application, *models.Job, primitive.ObjectID, bool) {
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		ah.errorHandler.HandleBadRequest(c)
+		return nil, nil, primitive.NilObjectID, false
+	}
+	if role != "admin" {
+		ah.errorHandler.HandleUnauthorized(c)
+		return nil, nil, primitive.NilObjectID, false
+	}
+
+	userId, ok := c.MustGet("id").(string)
+	if !ok {
+		ah.errorHandler.HandleBadRequest(c)
+		return nil, nil, primitive.NilObjectID, false
+	}
+
+	userObjectId, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return nil, nil, primitive.NilObjectID, false
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return nil, nil, primitive.NilObjectID, false
+	}
+
+	var application models.Application
+	err = ah.Collection.FindOne(ctx, services.NotDeleted(bson.M{"_id": objectId})).Decode(&application)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ah.errorHandler.HandleNotFound(c)
+			return nil, nil, primitive.NilObjectID, false
+		}
+		ah.errorHandler.HandleInternalServerError(c)
+		return nil, nil, primitive.NilObjectID, false
+	}
+
+	var job models.Job
+	err = db.DB.Collection("jobs").FindOne(ctx, bson.M{"_id": application.JobID}).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ah.errorHandler.HandleNotFound(c)
+			return nil, nil, primitive.NilObjectID, false
+		}
+		ah.errorHandler.HandleInternalServerError(c)
+		return nil, nil, primitive.NilObjectID, false
+	}
+
+	if job.UserID != userObjectId {
+		ah.errorHandler.HandleUnauthorized(c)
+		return nil, nil, primitive.NilObjectID, false
+	}
+
+	return &application, &job, userObjectId, true
+}
+
+// applicationListOptions applies the query parameters shared by the admin
+// listings: minScore/maxScore filter on the resume match score and
+// minRating/maxRating on the average scorecard rating, tag (repeatable)
+// keeps applications carrying all given tags, and sortBy=score|rating
+// orders by either (order=asc for lowest first).
+func (ah *ApplicationHandler) applicationListOptions(c *gin.Context, filter bson.M) (*options.FindOptions, bool) {
+	opts := options.Find()
+
+	ranges := []struct {
+		min, max, field string
+	}{
+		{"minScore", "maxScore", "match.score"},
+		{"minRating", "maxRating", "averageRating"},
+	}
+	for _, r := range ranges {
+		bounds := bson.M{}
+		for param, operator := range map[string]string{r.min: "$gte", r.max: "$lte"} {
+			value := c.Query(param)
+			if value == "" {
+				continue
+			}
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": param + " must be a number"})
+				return nil, false
+			}
+			bounds[operator] = number
+		}
+		if len(bounds) > 0 {
+			filter[r.field] = bounds
+		}
+	}
+
+	if tags := c.QueryArray("tag"); len(tags) > 0 {
+		tags, problems := services.NormalizeTags(tags)
+		if len(problems) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tags", "details": problems})
+			return nil, false
+		}
+		if len(tags) > 0 {
+			filter["tags"] = bson.M{"$all": tags}
+		}
+	}
+
+	direction := -1
+	if c.Query("order") == "asc" {
+		direction = 1
+	}
+	switch c.Query("sortBy") {
+	case "":
+	case "score":
+		opts.SetSort(bson.D{{Key: "match.score", Value: direction}, {Key: "_id", Value: -1}})
+	case "rating":
+		opts.SetSort(bson.D{{Key: "averageRating", Value: direction}, {Key: "_id", Value: -1}})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sortBy must be score or rating"})
+		return nil, false
+	}
+
+	return opts, true
+}
+
+// AnalyzeApplicationResume re-runs text extraction and scoring, e.g. after
+// the job requirements changed or for applications made before scoring.
+func (ah *ApplicationHandler) AnalyzeApplicationResume(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	application, job, _, ok := ah.recruiterApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	resumeText, match, err := services.AnalyzeStoredResume(ctx, application.Resume, *job)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Could not read the resume: " + err.Error()})
+		return
+	}
+
+	update := bson.M{"$set": bson.M{"resumeText": resumeText, "match": match}}
+	_, err = ah.Collection.UpdateOne(ctx, bson.M{"_id": application.ID}, update)
+	if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, match)
+}
+
+// GetApplicationResumeText returns the extracted resume text and match.
+func (ah *ApplicationHandler) GetApplicationResumeText(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	application, job, _, ok := ah.recruiterApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	if services.IdentityHidden(job, *application) {
+		masked := services.MaskApplication(*application)
+		application = &masked
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"_id":   application.ID,
+		"text":  application.ResumeText,
+		"match": application.Match,
+		"blind": application.Blind,
+	})
+}
+
+// RevealApplicationIdentity lifts blind review for one application so its
+// recruiters see who the candidate is. Every reveal is audited.
+func (ah *ApplicationHandler) RevealApplicationIdentity(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	application, job, userObjectId, ok := ah.recruiterApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			ah.errorHandler.HandleBadRequest(c)
+			return
+		}
+	}
+
+	if job.BlindReview == nil || !job.BlindReview.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Blind review is not enabled for this job"})
+		return
+	}
+
+	if services.IdentityHidden(job, *application) {
+		now := time.Now().UTC()
+		update := bson.M{"$set": bson.M{"identityRevealedAt": now, "identityRevealedBy": userObjectId}}
+		result, err := ah.Collection.UpdateOne(ctx, bson.M{"_id": application.ID, "identityRevealedAt": bson.M{"$exists": false}}, update)
+		if err != nil {
+			ah.errorHandler.HandleInternalServerError(c)
+			return
+		}
+		if result.ModifiedCount > 0 {
+			application.IdentityRevealedAt = &now
+			application.IdentityRevealedBy = &userObjectId
+			audit(ctx, c, models.AuditApplicationReveal, models.AuditTargetApplication, application.ID, nil, bson.M{"trigger": "manual", "reason": request.Reason})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"_id":                application.ID,
+		"userId":             application.UserID,
+		"name":               application.Name,
+		"email":              application.Email,
+		"identityRevealedAt": application.IdentityRevealedAt,
+		"identityRevealedBy": application.IdentityRevealedBy,
+	})
+}
@@ -0,0 +1,321 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"github.com/weldonkipchirchir/job-listing-server/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RetentionHandler struct {
+	Collection   *mongo.Collection
+	errorHandler *handler.ErrorHandler
+}
+
+func NewRetentionHandler(collection *mongo.Collection, errorHandler *handler.ErrorHandler) *RetentionHandler {
+	return &RetentionHandler{
+		Collection:   collection,
+		errorHandler: errorHandler,
+	}
+}
+
+type retentionPolicyRequest struct {
+	Company   string `json:"company"`
+	Status    string `json:"status"`
+	AfterDays int    `json:"afterDays"`
+	Action    string `json:"action"`
+	Active    *bool  `json:"active"`
+}
+
+// adminID checks that the request comes from an admin and returns their
+// id. An admin's policies cover the jobs they post.
+func (rh *RetentionHandler) adminID(c *gin.Context) (primitive.ObjectID, bool) {
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		rh.errorHandler.HandleBadRequest(c)
+		return primitive.NilObjectID, false
+	}
+	if role != "admin" {
+		rh.errorHandler.HandleUnauthorized(c)
+		return primitive.NilObjectID, false
+	}
+
+	adminID, err := primitive.ObjectIDFromHex(c.GetString("id"))
+	if err != nil {
+		rh.errorHandler.HandleBadRequest(c)
+		return primitive.NilObjectID, false
+	}
+	return adminID, true
+}
+
+// ownedPolicy loads the policy in the :id path parameter if adminID owns
+// it. It writes the error response itself.
+func (rh *RetentionHandler) ownedPolicy(ctx context.Context, c *gin.Context, id string, adminID primitive.ObjectID) (*models.RetentionPolicy, bool) {
+	objectId, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		rh.errorHandler.HandleBadRequest(c)
+		return nil, false
+	}
+
+	var policy models.RetentionPolicy
+	err = rh.Collection.FindOne(ctx, bson.M{"_id": objectId, "createdBy": adminID}).Decode(&policy)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			rh.errorHandler.HandleNotFound(c)
+			return nil, false
+		}
+		rh.errorHandler.HandleInternalServerError(c)
+		return nil, false
+	}
+	return &policy, true
+}
+
+func (rh *RetentionHandler) GetRetentionPolicies(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	adminID, ok := rh.adminID(c)
+	if !ok {
+		return
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "company", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := rh.Collection.Find(ctx, bson.M{"createdBy": adminID}, opts)
+	if err != nil {
+		rh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	policies := []models.RetentionPolicy{}
+	if err := cursor.All(ctx, &policies); err != nil {
+		rh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, policies)
+}
+
+func (rh *RetentionHandler) CreateRetentionPolicy(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	adminID, ok := rh.adminID(c)
+	if !ok {
+		return
+	}
+
+	var request retentionPolicyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		rh.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	if request.Status != "" {
+		request.Status = utils.CapitalizeFirstLetter(request.Status)
+	}
+
+	now := time.Now().UTC()
+	policy := models.RetentionPolicy{
+		ID:        primitive.NewObjectID(),
+		Company:   request.Company,
+		Status:    request.Status,
+		AfterDays: request.AfterDays,
+		Action:    request.Action,
+		Active:    request.Active == nil || *request.Active,
+		CreatedBy: adminID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if problems := services.ValidateRetentionPolicy(policy); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid retention policy", "details": problems})
+		return
+	}
+
+	if _, err := rh.Collection.InsertOne(ctx, policy); err != nil {
+		rh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusCreated, policy)
+}
+
+func (rh *RetentionHandler) UpdateRetentionPolicy(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	adminID, ok := rh.adminID(c)
+	if !ok {
+		return
+	}
+	policy, ok := rh.ownedPolicy(ctx, c, c.Param("id"), adminID)
+	if !ok {
+		return
+	}
+
+	var request retentionPolicyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		rh.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	// an empty company applies the policy to all of the admin's jobs
+	policy.Company = request.Company
+	if request.AfterDays != 0 {
+		policy.AfterDays = request.AfterDays
+	}
+	if request.Action != "" {
+		policy.Action = request.Action
+	}
+	if request.Active != nil {
+		policy.Active = *request.Active
+	}
+	// an empty status applies the policy to every status
+	policy.Status = request.Status
+	if policy.Status != "" {
+		policy.Status = utils.CapitalizeFirstLetter(policy.Status)
+	}
+	policy.UpdatedAt = time.Now().UTC()
+
+	if problems := services.ValidateRetentionPolicy(*policy); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid retention policy", "details": problems})
+		return
+	}
+
+	update := bson.M{"$set": bson.M{
+		"company":   policy.Company,
+		"status":    policy.Status,
+		"afterDays": policy.AfterDays,
+		"action":    policy.Action,
+		"active":    policy.Active,
+		"updatedAt": policy.UpdatedAt,
+	}}
+	if _, err := rh.Collection.UpdateOne(ctx, bson.M{"_id": policy.ID}, update); err != nil {
+		rh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (rh *RetentionHandler) DeleteRetentionPolicy(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	adminID, ok := rh.adminID(c)
+	if !ok {
+		return
+	}
+	policy, ok := rh.ownedPolicy(ctx, c, c.Param("id"), adminID)
+	if !ok {
+		return
+	}
+
+	if _, err := rh.Collection.DeleteOne(ctx, bson.M{"_id": policy.ID}); err != nil {
+		rh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Retention policy deleted"})
+}
+
+// PreviewRetention is a dry run of the active policies of the admin, or of
+// the policy given by policyId whether active or not. It reports what the
+// next enforcement run would purge.
+func (rh *RetentionHandler) PreviewRetention(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	adminID, ok := rh.adminID(c)
+	if !ok {
+		return
+	}
+
+	if policyId := c.Query("policyId"); policyId != "" {
+		policy, ok := rh.ownedPolicy(ctx, c, policyId, adminID)
+		if !ok {
+			return
+		}
+		report, err := services.ApplyRetentionPolicy(ctx, *policy, true)
+		if err != nil {
+			rh.errorHandler.HandleInternalServerError(c)
+			return
+		}
+		c.JSON(http.StatusOK, []models.RetentionReport{report})
+		return
+	}
+
+	reports, err := services.RunRetention(ctx, adminID, true)
+	if err != nil {
+		rh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, reports)
+}
+
+// GetRetentionReports lists what enforcement runs purged for the admin's
+// policies, newest first. policyId narrows it to one policy.
+func (rh *RetentionHandler) GetRetentionReports(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	adminID, ok := rh.adminID(c)
+	if !ok {
+		return
+	}
+
+	page, err := strconv.ParseInt(c.DefaultQuery("page", "1"), 10, 64)
+	if err != nil || page < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive number"})
+		return
+	}
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
+
+	filter := bson.M{"ownerId": adminID}
+	if policyId := c.Query("policyId"); policyId != "" {
+		objectId, err := primitive.ObjectIDFromHex(policyId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "policyId is not a valid id"})
+			return
+		}
+		filter["policyId"] = objectId
+	}
+
+	reportsCollection := db.DB.Collection("retentionReports")
+	total, err := reportsCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		rh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "ranAt", Value: -1}}).
+		SetSkip((page - 1) * limit).
+		SetLimit(limit)
+	cursor, err := reportsCollection.Find(ctx, filter, opts)
+	if err != nil {
+		rh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	reports := []models.RetentionReport{}
+	if err := cursor.All(ctx, &reports); err != nil {
+		rh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reports": reports, "page": page, "limit": limit, "total": total})
+}
@@ -0,0 +1,406 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// recruiterName looks up the display name stored with notes and scorecards.
+func recruiterName(ctx context.Context, userID primitive.ObjectID) string {
+	var user models.User
+	err := db.DB.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user)
+	if err != nil {
+		return ""
+	}
+	return user.Name
+}
+
+func (ah *ApplicationHandler) GetNotes(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	application, _, _, ok := ah.recruiterApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	notes := application.Notes
+	if notes == nil {
+		notes = []models.RecruiterNote{}
+	}
+	c.JSON(http.StatusOK, notes)
+}
+
+func (ah *ApplicationHandler) CreateNote(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	application, _, userObjId, ok := ah.recruiterApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Body string `json:"body"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || strings.TrimSpace(request.Body) == "" {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	note := models.RecruiterNote{
+		ID:         primitive.NewObjectID(),
+		Body:       strings.TrimSpace(request.Body),
+		AuthorID:   userObjId,
+		AuthorName: recruiterName(ctx, userObjId),
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	_, err := ah.Collection.UpdateOne(ctx, bson.M{"_id": application.ID}, bson.M{"$push": bson.M{"notes": note}})
+	if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusCreated, note)
+}
+
+// UpdateNote edits a note. Only the author of a note can change it.
+func (ah *ApplicationHandler) UpdateNote(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	application, _, userObjId, ok := ah.recruiterApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	noteId, err := primitive.ObjectIDFromHex(c.Param("noteId"))
+	if err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	var request struct {
+		Body string `json:"body"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil || strings.TrimSpace(request.Body) == "" {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	filter := bson.M{
+		"_id":   application.ID,
+		"notes": bson.M{"$elemMatch": bson.M{"_id": noteId, "authorId": userObjId}},
+	}
+	update := bson.M{"$set": bson.M{
+		"notes.$.body":      strings.TrimSpace(request.Body),
+		"notes.$.updatedAt": time.Now().UTC(),
+	}}
+	result, err := ah.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	if result.MatchedCount == 0 {
+		ah.errorHandler.HandleNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "note updated"})
+}
+
+// DeleteNote removes a note. Only the author of a note can delete it.
+func (ah *ApplicationHandler) DeleteNote(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	application, _, userObjId, ok := ah.recruiterApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	noteId, err := primitive.ObjectIDFromHex(c.Param("noteId"))
+	if err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	filter := bson.M{
+		"_id":   application.ID,
+		"notes": bson.M{"$elemMatch": bson.M{"_id": noteId, "authorId": userObjId}},
+	}
+	update := bson.M{"$pull": bson.M{"notes": bson.M{"_id": noteId}}}
+	result, err := ah.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	if result.MatchedCount == 0 {
+		ah.errorHandler.HandleNotFound(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "note deleted"})
+}
+
+func (ah *ApplicationHandler) GetTags(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	application, _, _, ok := ah.recruiterApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	tags := application.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	c.JSON(http.StatusOK, tags)
+}
+
+// SetTags replaces the tags of an application (PUT) or adds to them (POST).
+func (ah *ApplicationHandler) SetTags(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	application, _, _, ok := ah.recruiterApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	var request struct {
+		Tags []string `json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+	tags, problems := services.NormalizeTags(request.Tags)
+	if len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tags", "details": problems})
+		return
+	}
+
+	update := bson.M{"$set": bson.M{"tags": tags}}
+	if c.Request.Method == http.MethodPost {
+		update = bson.M{"$addToSet": bson.M{"tags": bson.M{"$each": tags}}}
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated models.Application
+	err := ah.Collection.FindOneAndUpdate(ctx, bson.M{"_id": application.ID}, update, opts).Decode(&updated)
+	if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	if updated.Tags == nil {
+		updated.Tags = []string{}
+	}
+	c.JSON(http.StatusOK, updated.Tags)
+}
+
+func (ah *ApplicationHandler) DeleteTag(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	application, _, _, ok := ah.recruiterApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	tag := strings.ToLower(strings.TrimSpace(c.Param("tag")))
+	update := bson.M{"$pull": bson.M{"tags": tag}}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated models.Application
+	err := ah.Collection.FindOneAndUpdate(ctx, bson.M{"_id": application.ID}, update, opts).Decode(&updated)
+	if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	if updated.Tags == nil {
+		updated.Tags = []string{}
+	}
+	c.JSON(http.StatusOK, updated.Tags)
+}
+
+// GetScorecards returns the scorecards of an application together with the
+// criteria taken from the job's requirements.
+func (ah *ApplicationHandler) GetScorecards(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	application, job, _, ok := ah.recruiterApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	scorecards := application.Scorecards
+	if scorecards == nil {
+		scorecards = []models.Scorecard{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"criteria":      services.ScorecardCriteria(*job),
+		"scorecards":    scorecards,
+		"averageRating": application.AverageRating,
+	})
+}
+
+// CreateScorecard adds the scorecard of the requesting recruiter. Every
+// recruiter has at most one scorecard per application.
+func (ah *ApplicationHandler) CreateScorecard(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	application, job, userObjId, ok := ah.recruiterApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	var card models.Scorecard
+	if err := c.ShouldBindJSON(&card); err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+	if problems := services.NormalizeScorecard(&card, *job); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scorecard", "details": problems})
+		return
+	}
+
+	for _, existing := range application.Scorecards {
+		if existing.AuthorID == userObjId {
+			c.JSON(http.StatusConflict, gin.H{"error": "Scorecard already exists", "scorecard": existing})
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	card.ID = primitive.NewObjectID()
+	card.AuthorID = userObjId
+	card.AuthorName = recruiterName(ctx, userObjId)
+	card.CreatedAt = now
+	card.UpdatedAt = now
+
+	if err := ah.saveScorecards(ctx, application.ID, append(application.Scorecards, card)); err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusCreated, card)
+}
+
+// UpdateScorecard replaces the ratings of a scorecard. Only its author can
+// change it.
+func (ah *ApplicationHandler) UpdateScorecard(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	application, job, userObjId, ok := ah.recruiterApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	scorecardId, err := primitive.ObjectIDFromHex(c.Param("scorecardId"))
+	if err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	var card models.Scorecard
+	if err := c.ShouldBindJSON(&card); err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+	if problems := services.NormalizeScorecard(&card, *job); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scorecard", "details": problems})
+		return
+	}
+
+	scorecards := application.Scorecards
+	for i, existing := range scorecards {
+		if existing.ID != scorecardId || existing.AuthorID != userObjId {
+			continue
+		}
+		card.ID = existing.ID
+		card.AuthorID = existing.AuthorID
+		card.AuthorName = existing.AuthorName
+		card.CreatedAt = existing.CreatedAt
+		card.UpdatedAt = time.Now().UTC()
+		scorecards[i] = card
+
+		if err := ah.saveScorecards(ctx, application.ID, scorecards); err != nil {
+			ah.errorHandler.HandleInternalServerError(c)
+			return
+		}
+		c.JSON(http.StatusOK, card)
+		return
+	}
+
+	ah.errorHandler.HandleNotFound(c)
+}
+
+// DeleteScorecard removes a scorecard. Only its author can delete it.
+func (ah *ApplicationHandler) DeleteScorecard(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	application, _, userObjId, ok := ah.recruiterApplication(ctx, c)
+	if !ok {
+		return
+	}
+
+	scorecardId, err := primitive.ObjectIDFromHex(c.Param("scorecardId"))
+	if err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	scorecards := []models.Scorecard{}
+	found := false
+	for _, existing := range application.Scorecards {
+		if existing.ID == scorecardId && existing.AuthorID == userObjId {
+			found = true
+			continue
+		}
+		scorecards = append(scorecards, existing)
+	}
+	if !found {
+		ah.errorHandler.HandleNotFound(c)
+		return
+	}
+
+	if err := ah.saveScorecards(ctx, application.ID, scorecards); err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "scorecard deleted"})
+}
+
+// saveScorecards stores the scorecards of an application along with their
+// average, which SearchAdminApplications filters on. Unrated applications
+// have no averageRating.
+func (ah *ApplicationHandler) saveScorecards(ctx context.Context, applicationID primitive.ObjectID, scorecards []models.Scorecard) error {
+	update := bson.M{"$set": bson.M{"scorecards": scorecards, "averageRating": services.AverageRating(scorecards)}}
+	if len(scorecards) == 0 {
+		update = bson.M{"$set": bson.M{"scorecards": scorecards}, "$unset": bson.M{"averageRating": ""}}
+	}
+	_, err := ah.Collection.UpdateOne(ctx, bson.M{"_id": applicationID}, update)
+	return err
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// SetScreeningQuestions replaces the screening questions of a job. With
+// fromRequirements set, a yes/no knockout question is added for every
+// mandatory requirement that is not covered by a question yet.
+func (jh *JobHandler) SetScreeningQuestions(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job, userObjId, ok := jh.ownedJob(ctx, c)
+	if !ok {
+		return
+	}
+
+	if !ifMatch(c, job.Version) {
+		respondPreconditionFailed(c, job.Version)
+		return
+	}
+
+	var request struct {
+		Questions        []models.ScreeningQuestionDetail `json:"questions"`
+		KnockoutAction   string                           `json:"knockoutAction"`
+		FromRequirements bool                             `json:"fromRequirements"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		jh.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	knockoutAction, ok := services.NormalizeKnockoutAction(request.KnockoutAction)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "knockoutAction must be reject or flag"})
+		return
+	}
+
+	questions := services.ScreeningQuestionsFromDetails(request.Questions)
+	if request.FromRequirements {
+		questions = services.QuestionsFromRequirements(job.MandatoryRequirements, questions)
+	}
+	if problems := services.NormalizeScreeningQuestions(questions); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid screening questions", "details": problems})
+		return
+	}
+
+	if err := services.RecordJobBaseline(ctx, job); err != nil {
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"screeningQuestions": questions,
+			"knockoutAction":     knockoutAction,
+			"updatedAt":          time.Now().UTC(),
+		},
+		"$inc": bson.M{"version": 1},
+	}
+	updatedJob, err := jh.updateJob(ctx, update, job, models.RevisionUpdated, userObjId, 0)
+	if err != nil {
+		jh.handleUpdateJobError(ctx, c, job.ID, err)
+		return
+	}
+
+	before, after := changedFields(jobContentFields(*job), jobContentFields(*updatedJob))
+	audit(ctx, c, models.AuditJobUpdate, models.AuditTargetJob, job.ID, before, after)
+
+	c.Header("ETag", versionETag(updatedJob.Version))
+	c.JSON(http.StatusOK, gin.H{
+		"screeningQuestions": services.ScreeningQuestionDetails(updatedJob.ScreeningQuestions),
+		"knockoutAction":     updatedJob.KnockoutAction,
+		"version":            updatedJob.Version,
+	})
+}
+
+// GetScreeningQuestions lists the questions a candidate has to answer when
+// applying, without the knockout criteria. The owner of the job gets them
+// with the criteria.
+func (jh *JobHandler) GetScreeningQuestions(c *gin.Context) {
+	objectId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		jh.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var job models.Job
+	err = jh.Collection.FindOne(ctx, services.NotDeleted(bson.M{"_id": objectId})).Decode(&job)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			jh.errorHandler.HandleNotFound(c)
+			return
+		}
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	role, _ := c.MustGet("role").(string)
+	userId, _ := c.MustGet("id").(string)
+	if role == "admin" && job.UserID.Hex() == userId {
+		c.JSON(http.StatusOK, services.ScreeningQuestionDetails(job.ScreeningQuestions))
+		return
+	}
+
+	c.JSON(http.StatusOK, services.ScreeningQuestionViews(job.ScreeningQuestions))
+}
@@ -0,0 +1,214 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// trashExpiry is when a record deleted at deletedAt is purged for good.
+func trashExpiry(deletedAt *time.Time) time.Time {
+	if deletedAt == nil {
+		return time.Time{}
+	}
+	return deletedAt.Add(services.TrashRetention())
+}
+
+// GetDeletedJobs lists the jobs of the logged in admin that are in the
+// trash, most recently deleted first.
+func (jh *JobHandler) GetDeletedJobs(c *gin.Context) {
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		jh.errorHandler.HandleBadRequest(c)
+		return
+	}
+	if role != "admin" {
+		jh.errorHandler.HandleUnauthorized(c)
+		return
+	}
+
+	userObjId, err := primitive.ObjectIDFromHex(c.GetString("id"))
+	if err != nil {
+		jh.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "deletedAt", Value: -1}})
+	cursor, err := jh.Collection.Find(ctx, services.Deleted(bson.M{"userId": userObjId}), opts)
+	if err != nil {
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	var jobs []models.Job
+	if err := cursor.All(ctx, &jobs); err != nil {
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	trash := make([]gin.H, 0, len(jobs))
+	for _, job := range jobs {
+		trash = append(trash, gin.H{"job": job, "purgeAt": trashExpiry(job.DeletedAt)})
+	}
+	c.JSON(http.StatusOK, trash)
+}
+
+// RestoreJob takes a job of the logged in admin out of the trash.
+func (jh *JobHandler) RestoreJob(c *gin.Context) {
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		jh.errorHandler.HandleBadRequest(c)
+		return
+	}
+	if role != "admin" {
+		jh.errorHandler.HandleUnauthorized(c)
+		return
+	}
+
+	userObjId, err := primitive.ObjectIDFromHex(c.GetString("id"))
+	if err != nil {
+		jh.errorHandler.HandleBadRequest(c)
+		return
+	}
+	objectId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		jh.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	count, err := jh.Collection.CountDocuments(ctx, services.Deleted(bson.M{"_id": objectId, "userId": userObjId}))
+	if err != nil {
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	if count == 0 {
+		jh.errorHandler.HandleNotFound(c)
+		return
+	}
+
+	var job *models.Job
+	err = services.WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		job, err = services.RestoreJob(sc, objectId, userObjId)
+		return err
+	})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			jh.errorHandler.HandleNotFound(c)
+			return
+		}
+		jh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	services.InvalidateJobCache(ctx, objectId)
+	audit(ctx, c, models.AuditJobRestore, models.AuditTargetJob, objectId, nil, nil)
+
+	c.JSON(http.StatusOK, job)
+}
+
+// GetDeletedApplications lists the applications the logged in user deleted
+// that are still in the trash.
+func (ah *ApplicationHandler) GetDeletedApplications(c *gin.Context) {
+	userObjId, err := primitive.ObjectIDFromHex(c.GetString("id"))
+	if err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "deletedAt", Value: -1}}).
+		SetProjection(bson.M{"resume.data": 0})
+	cursor, err := ah.Collection.Find(ctx, services.Deleted(bson.M{"deletedBy": userObjId}), opts)
+	if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	var applications []models.Application
+	if err := cursor.All(ctx, &applications); err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	// a recruiter's trash names candidates under blind review by alias
+	ids := []primitive.ObjectID{}
+	for _, application := range applications {
+		if application.UserID != userObjId {
+			ids = append(ids, application.ID)
+		}
+	}
+	hidden, err := services.HiddenApplications(ctx, ids)
+	if err != nil {
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	trash := make([]gin.H, 0, len(applications))
+	for _, application := range applications {
+		if _, ok := hidden[application.ID]; ok {
+			application = services.MaskApplication(application)
+		}
+		trash = append(trash, gin.H{
+			"_id":       application.ID,
+			"jobId":     application.JobID,
+			"jobName":   application.JobName,
+			"company":   application.Company,
+			"name":      application.Name,
+			"status":    application.Status,
+			"deletedAt": application.DeletedAt,
+			"purgeAt":   trashExpiry(application.DeletedAt),
+		})
+	}
+	c.JSON(http.StatusOK, trash)
+}
+
+// RestoreApplication takes an application the logged in user deleted out of
+// the trash.
+func (ah *ApplicationHandler) RestoreApplication(c *gin.Context) {
+	userObjId, err := primitive.ObjectIDFromHex(c.GetString("id"))
+	if err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+	objectId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		ah.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err = services.Restore(ctx, ah.Collection, bson.M{"_id": objectId, "deletedBy": userObjId})
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			ah.errorHandler.HandleNotFound(c)
+			return
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "You applied to this job again since, delete that application first"})
+			return
+		}
+		ah.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	audit(ctx, c, models.AuditApplicationRestore, models.AuditTargetApplication, objectId, nil, nil)
+
+	c.JSON(http.StatusOK, gin.H{"message": "application restored"})
+}
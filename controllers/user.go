@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"log"
 	"net/http"
 	"time"
 
@@ -15,15 +16,22 @@ import (
 )
 
 func Register(c *gin.Context) {
-	var user models.User
-
-	user.ID = primitive.NewObjectID()
-
-	if err := c.BindJSON(&user); err != nil {
+	var request models.RegisterRequest
+	if err := c.BindJSON(&request); err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
+	user := models.User{
+		ID:       primitive.NewObjectID(),
+		Name:     request.Name,
+		Email:    request.Email,
+		Phone:    request.Phone,
+		Address:  request.Address,
+		Password: request.Password,
+		Role:     "user",
+	}
+
 	err := services.RegisterUser(&user)
 	if err == services.ErrEmailTaken {
 		c.JSON(http.StatusConflict, gin.H{"error": "Username already taken"})
@@ -57,6 +65,9 @@ func Login(c *gin.Context) {
 	if err == services.ErrUserNotFound || err == services.ErrInvalidCredentials {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
+	} else if err == services.ErrAccountDeleted {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is deleted, restore it to log in"})
+		return
 	} else if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
 		return
@@ -93,6 +104,72 @@ func Login(c *gin.Context) {
 	c.JSON(http.StatusOK, res)
 }
 
+// DeleteAccount moves the logged in user's account to the trash and logs
+// them out.
+func DeleteAccount(c *gin.Context) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bad request"})
+		return
+	}
+
+	err = services.DeleteUser(userID)
+	if err == services.ErrUserNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+	audit(c.Request.Context(), c, models.AuditUserDelete, models.AuditTargetUser, userID, nil, nil)
+
+	c.SetCookie("token", "", -1, "/", "", false, true)
+	c.SetCookie("refreshToken", "", -1, "/", "", false, true)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Account deleted",
+		"purgeAt": time.Now().Add(services.TrashRetention()),
+	})
+}
+
+// RestoreAccount brings back a deleted account that has not been purged
+// yet. The account's credentials are required.
+func RestoreAccount(c *gin.Context) {
+	var restoreRequest struct {
+		Email    string `json:"email" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.BindJSON(&restoreRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid json format"})
+		return
+	}
+
+	user, err := services.RestoreUser(restoreRequest.Email, restoreRequest.Password)
+	if err == services.ErrUserNotFound || err == services.ErrInvalidCredentials {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+		return
+	}
+
+	// nobody is logged in here, the restored account is the actor
+	meta := auditMeta(c)
+	meta.Actor = models.AuditActor{ID: user.ID, Email: user.Email, Role: user.Role}
+	if err := services.RecordAudit(c.Request.Context(), meta, models.AuditUserRestore, models.AuditTargetUser, user.ID, nil, nil); err != nil {
+		log.Println("error writing audit log", models.AuditUserRestore, user.ID.Hex(), err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Account restored",
+		"user": models.UserResponse{
+			ID:    user.ID,
+			Name:  user.Name,
+			Email: user.Email,
+			Role:  user.Role,
+		},
+	})
+}
+
 func Logout(c *gin.Context) {
 	c.SetCookie("token", "", -1, "/", "", false, true)
 	c.SetCookie("refreshToken", "", -1, "/", "", false, true)
@@ -156,9 +233,6 @@ func Settings(c *gin.Context) {
 	if user.Address != "" {
 		updateFields["address"] = user.Address
 	}
-	if user.Role != "" {
-		updateFields["role"] = user.Role
-	}
 
 	// Check if the password field is non-empty
 	if user.Password != "" {
@@ -181,6 +255,19 @@ func Settings(c *gin.Context) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	var current models.User
+	err = db.DB.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&current)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	// roles are only given with jobly user, never by the account itself
+	if user.Role != "" && user.Role != current.Role {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The role cannot be changed here"})
+		return
+	}
+
 	update := bson.M{"$set": updateFields}
 	_, err = db.DB.Collection("users").UpdateOne(ctx, bson.M{"_id": userID}, update)
 	if err != nil {
@@ -188,5 +275,19 @@ func Settings(c *gin.Context) {
 		return
 	}
 
+	before, after := changedFields(bson.M{
+		"name":    current.Name,
+		"email":   current.Email,
+		"phone":   current.Phone,
+		"address": current.Address,
+	}, updateFields)
+	// the audit log records that the password changed, never the hash
+	if _, ok := after["password"]; ok {
+		delete(before, "password")
+		delete(after, "password")
+		after["passwordChanged"] = true
+	}
+	audit(ctx, c, models.AuditUserUpdate, models.AuditTargetUser, userID, before, after)
+
 	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
 }
@@ -0,0 +1,444 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxWebhooks = 10
+
+type WebhookHandler struct {
+	Collection   *mongo.Collection
+	errorHandler *handler.ErrorHandler
+}
+
+func NewWebhookHandler(collection *mongo.Collection, errorHandler *handler.ErrorHandler) *WebhookHandler {
+	return &WebhookHandler{
+		Collection:   collection,
+		errorHandler: errorHandler,
+	}
+}
+
+func deliveries() *mongo.Collection {
+	return db.DB.Collection("webhookDeliveries")
+}
+
+// adminID returns the id of the logged in admin.
+func (wh *WebhookHandler) adminID(c *gin.Context) (primitive.ObjectID, bool) {
+	role, ok := c.MustGet("role").(string)
+	if !ok {
+		wh.errorHandler.HandleBadRequest(c)
+		return primitive.NilObjectID, false
+	}
+	if role != "admin" {
+		wh.errorHandler.HandleUnauthorized(c)
+		return primitive.NilObjectID, false
+	}
+
+	userId, ok := c.MustGet("id").(string)
+	if !ok {
+		wh.errorHandler.HandleBadRequest(c)
+		return primitive.NilObjectID, false
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		wh.errorHandler.HandleBadRequest(c)
+		return primitive.NilObjectID, false
+	}
+	return objectId, true
+}
+
+// ownedWebhook loads the webhook in the :id path parameter if it belongs to
+// the logged in admin. It writes the error response itself.
+func (wh *WebhookHandler) ownedWebhook(ctx context.Context, c *gin.Context) (*models.Webhook, bool) {
+	ownerID, ok := wh.adminID(c)
+	if !ok {
+		return nil, false
+	}
+
+	objectId, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		wh.errorHandler.HandleBadRequest(c)
+		return nil, false
+	}
+
+	var webhook models.Webhook
+	err = wh.Collection.FindOne(ctx, bson.M{"_id": objectId, "ownerId": ownerID}).Decode(&webhook)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			wh.errorHandler.HandleNotFound(c)
+			return nil, false
+		}
+		wh.errorHandler.HandleInternalServerError(c)
+		return nil, false
+	}
+	return &webhook, true
+}
+
+func validateWebhook(ctx context.Context, webhook *models.Webhook) []string {
+	var problems []string
+
+	webhook.URL = strings.TrimSpace(webhook.URL)
+	if err := services.ValidateWebhookURL(ctx, webhook.URL); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	events := []string{}
+	seen := map[string]bool{}
+	for _, event := range webhook.Events {
+		known := false
+		for _, e := range models.WebhookEvents {
+			known = known || e == event
+		}
+		if !known {
+			problems = append(problems, "unknown event "+strconv.Quote(event))
+			continue
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	if len(webhook.Events) == 0 {
+		problems = append(problems, "at least one event is required")
+	}
+	webhook.Events = events
+	webhook.Description = strings.TrimSpace(webhook.Description)
+
+	return problems
+}
+
+func (wh *WebhookHandler) GetWebhooks(c *gin.Context) {
+	ownerID, ok := wh.adminID(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := wh.Collection.Find(ctx, bson.M{"ownerId": ownerID})
+	if err != nil {
+		wh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	webhooks := []models.Webhook{}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		wh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": webhooks, "events": models.WebhookEvents})
+}
+
+// CreateWebhook registers an endpoint. The response is the only time the
+// signing secret is shown.
+func (wh *WebhookHandler) CreateWebhook(c *gin.Context) {
+	ownerID, ok := wh.adminID(c)
+	if !ok {
+		return
+	}
+
+	webhook := models.Webhook{Active: true}
+	if err := c.ShouldBindJSON(&webhook); err != nil {
+		wh.errorHandler.HandleBadRequest(c)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if problems := validateWebhook(ctx, &webhook); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook", "details": problems})
+		return
+	}
+
+	count, err := wh.Collection.CountDocuments(ctx, bson.M{"ownerId": ownerID})
+	if err != nil {
+		wh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	if count >= maxWebhooks {
+		c.JSON(http.StatusConflict, gin.H{"error": "Webhook limit reached"})
+		return
+	}
+
+	secret, err := services.GenerateWebhookSecret()
+	if err != nil {
+		wh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	now := time.Now().UTC()
+	webhook.ID = primitive.NewObjectID()
+	webhook.OwnerID = ownerID
+	webhook.Secret = secret
+	webhook.CreatedAt = now
+	webhook.UpdatedAt = now
+
+	_, err = wh.Collection.InsertOne(ctx, webhook)
+	if err != nil {
+		wh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+func (wh *WebhookHandler) GetWebhook(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	webhook, ok := wh.ownedWebhook(ctx, c)
+	if !ok {
+		return
+	}
+
+	webhook.Secret = ""
+	c.JSON(http.StatusOK, webhook)
+}
+
+func (wh *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	webhook, ok := wh.ownedWebhook(ctx, c)
+	if !ok {
+		return
+	}
+
+	var update models.Webhook
+	if err := c.ShouldBindJSON(&update); err != nil {
+		wh.errorHandler.HandleBadRequest(c)
+		return
+	}
+	if problems := validateWebhook(ctx, &update); len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook", "details": problems})
+		return
+	}
+
+	set := bson.M{
+		"url":         update.URL,
+		"events":      update.Events,
+		"description": update.Description,
+		"active":      update.Active,
+		"updatedAt":   time.Now().UTC(),
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var updated models.Webhook
+	err := wh.Collection.FindOneAndUpdate(ctx, bson.M{"_id": webhook.ID}, bson.M{"$set": set}, opts).Decode(&updated)
+	if err != nil {
+		wh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	updated.Secret = ""
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteWebhook removes an endpoint and its pending deliveries. The
+// delivery log is kept.
+func (wh *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	webhook, ok := wh.ownedWebhook(ctx, c)
+	if !ok {
+		return
+	}
+
+	if _, err := wh.Collection.DeleteOne(ctx, bson.M{"_id": webhook.ID}); err != nil {
+		wh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	_, err := deliveries().DeleteMany(ctx, bson.M{"webhookId": webhook.ID, "status": models.DeliveryPending})
+	if err != nil {
+		wh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "webhook deleted"})
+}
+
+func (wh *WebhookHandler) RotateWebhookSecret(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	webhook, ok := wh.ownedWebhook(ctx, c)
+	if !ok {
+		return
+	}
+
+	secret, err := services.GenerateWebhookSecret()
+	if err != nil {
+		wh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	update := bson.M{"$set": bson.M{"secret": secret, "updatedAt": time.Now().UTC()}}
+	if _, err := wh.Collection.UpdateOne(ctx, bson.M{"_id": webhook.ID}, update); err != nil {
+		wh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"secret": secret})
+}
+
+// PingWebhook queues a webhook.ping delivery to check the endpoint.
+func (wh *WebhookHandler) PingWebhook(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	webhook, ok := wh.ownedWebhook(ctx, c)
+	if !ok {
+		return
+	}
+
+	envelope := services.WebhookEnvelope{
+		ID:        primitive.NewObjectID(),
+		Type:      models.EventWebhookPing,
+		CreatedAt: time.Now().UTC(),
+		Data:      gin.H{"webhookId": webhook.ID},
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		wh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	delivery := services.NewWebhookDelivery(*webhook, envelope.ID, envelope.Type, string(payload))
+	if _, err := deliveries().InsertOne(ctx, delivery); err != nil {
+		wh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// GetWebhookDeliveries lists the delivery log of a webhook, newest first,
+// optionally filtered by status.
+func (wh *WebhookHandler) GetWebhookDeliveries(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	webhook, ok := wh.ownedWebhook(ctx, c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	if err != nil || limit < 1 || limit > 200 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 200"})
+		return
+	}
+
+	filter := bson.M{"webhookId": webhook.ID}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	if event := c.Query("event"); event != "" {
+		filter["event"] = event
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
+	cursor, err := deliveries().Find(ctx, filter, opts)
+	if err != nil {
+		wh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	list := []models.WebhookDelivery{}
+	if err := cursor.All(ctx, &list); err != nil {
+		wh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+func (wh *WebhookHandler) webhookDelivery(ctx context.Context, c *gin.Context, webhook *models.Webhook) (*models.WebhookDelivery, bool) {
+	deliveryId, err := primitive.ObjectIDFromHex(c.Param("deliveryId"))
+	if err != nil {
+		wh.errorHandler.HandleBadRequest(c)
+		return nil, false
+	}
+
+	var delivery models.WebhookDelivery
+	err = deliveries().FindOne(ctx, bson.M{"_id": deliveryId, "webhookId": webhook.ID}).Decode(&delivery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			wh.errorHandler.HandleNotFound(c)
+			return nil, false
+		}
+		wh.errorHandler.HandleInternalServerError(c)
+		return nil, false
+	}
+	return &delivery, true
+}
+
+func (wh *WebhookHandler) GetWebhookDelivery(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	webhook, ok := wh.ownedWebhook(ctx, c)
+	if !ok {
+		return
+	}
+	delivery, ok := wh.webhookDelivery(ctx, c, webhook)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// RedeliverWebhook queues the payload of a past delivery again as a new
+// delivery with the same event id, so receivers can deduplicate.
+func (wh *WebhookHandler) RedeliverWebhook(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	webhook, ok := wh.ownedWebhook(ctx, c)
+	if !ok {
+		return
+	}
+	original, ok := wh.webhookDelivery(ctx, c, webhook)
+	if !ok {
+		return
+	}
+	if original.Status == models.DeliveryPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Delivery is still pending"})
+		return
+	}
+
+	delivery := services.NewWebhookDelivery(*webhook, original.EventID, original.Event, original.Payload)
+	delivery.RedeliveryOf = &original.ID
+	if _, err := deliveries().InsertOne(ctx, delivery); err != nil {
+		wh.errorHandler.HandleInternalServerError(c)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
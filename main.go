@@ -14,6 +14,7 @@ import (
 	"github.com/weldonkipchirchir/job-listing-server/db"
 	"github.com/weldonkipchirchir/job-listing-server/middleware"
 	"github.com/weldonkipchirchir/job-listing-server/routes"
+	"github.com/weldonkipchirchir/job-listing-server/services"
 )
 
 func main() {
@@ -24,6 +25,16 @@ func main() {
 		log.Fatalf("Error connecting to the database: %v", err)
 	}
 
+	indexCtx, cancelIndexes := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := services.EnsureIndexes(indexCtx); err != nil {
+		log.Println("Error ensuring indexes", err)
+	}
+	cancelIndexes()
+
+	services.SetMailer(services.MailerFromEnv())
+
+	router.Use(middleware.RequestID())
+
 	limiter := middleware.NewRateLimiter(10, 20)
 	router.Use(limiter.Middleware())
 
@@ -31,8 +42,8 @@ func main() {
 		cors.Config{
 			AllowOrigins:     []string{"http://localhost:5173"},
 			AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"},
-			AllowHeaders:     []string{"Origin", "Authorization", "Content-Type"},
-			ExposeHeaders:    []string{"Content-Length"},
+			AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "If-Match", "If-None-Match", "If-Modified-Since", middleware.RequestIDHeader},
+			ExposeHeaders:    []string{"Content-Length", "ETag", "Last-Modified", middleware.RequestIDHeader},
 			AllowCredentials: true,
 			MaxAge:           12 * time.Hour,
 		}))
@@ -43,17 +54,46 @@ func main() {
 		})
 	})
 
+	routes.HealthRoutes(router)
 	routes.SetUpUsers(router)
 	routes.JobRoutes(router)
 	routes.ApplicationRoutes(router)
 	routes.SearchLog(router)
 	routes.BookmarksRoutes(router)
+	routes.FeedRoutes(router)
+	routes.ProfileRoutes(router)
+	routes.InterviewRoutes(router)
+	routes.MessageRoutes(router)
+	routes.NotificationRoutes(router)
+	routes.WebhookRoutes(router)
+	routes.AuditRoutes(router)
+	routes.PrivacyRoutes(router)
+	routes.RetentionRoutes(router)
+
+	taskQueue := services.NewTaskQueue(4)
+	taskQueue.Start()
+
+	webhookDispatcher := services.NewWebhookDispatcher()
+	webhookDispatcher.Start()
+
+	fileCollector := services.NewFileCollector()
+	fileCollector.Start()
+
+	trashPurger := services.NewTrashPurger()
+	trashPurger.Start()
+
+	retentionEnforcer := services.NewRetentionEnforcer()
+	retentionEnforcer.Start()
+
+	services.WatchWorkers(taskQueue, webhookDispatcher, fileCollector, trashPurger, retentionEnforcer)
 
 	//create server
 	serv := &http.Server{
 		Addr:    ":8000",
 		Handler: router,
 	}
+	// open notification streams would hold the shutdown until it times out
+	serv.RegisterOnShutdown(services.CloseStreams)
 
 	//start the server
 	go func() {
@@ -69,11 +109,41 @@ func main() {
 	<-quit
 
 	log.Println("Server shutting down")
+
+	// report not ready and keep serving until load balancers stop routing
+	// new requests here
+	services.BeginShutdown()
+	time.Sleep(services.ReadinessDrainDelay())
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// still drain the workers and disconnect if requests outlived the timeout
 	if err := serv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown", err)
+		log.Println("Server did not shut down in time", err)
+	}
+
+	// let running tasks finish, anything left is picked up after the restart
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), 25*time.Second)
+	defer cancelDrain()
+	if err := taskQueue.Drain(drainCtx); err != nil {
+		log.Println("Task queue did not drain in time", err)
+	}
+
+	if err := webhookDispatcher.Stop(drainCtx); err != nil {
+		log.Println("Webhook dispatcher did not stop in time", err)
+	}
+
+	if err := fileCollector.Stop(drainCtx); err != nil {
+		log.Println("GridFS garbage collector did not stop in time", err)
+	}
+
+	if err := trashPurger.Stop(drainCtx); err != nil {
+		log.Println("Trash purge did not stop in time", err)
+	}
+
+	if err := retentionEnforcer.Stop(drainCtx); err != nil {
+		log.Println("Retention enforcement did not stop in time", err)
 	}
 
 	db.DbDisconnect()
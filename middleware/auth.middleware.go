@@ -1,11 +1,13 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/weldonkipchirchir/job-listing-server/auth"
+	"github.com/weldonkipchirchir/job-listing-server/services"
 )
 
 // func Authentication() gin.HandlerFunc {
@@ -87,6 +89,22 @@ func Authentication() gin.HandlerFunc {
 			return
 		}
 
+		// Tokens outlive the account they were issued to, a deleted
+		// account is refused here rather than only at login
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+		active, err := services.AccountActive(ctx, claims.Id)
+		cancel()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			c.Abort()
+			return
+		}
+		if !active {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is deleted"})
+			c.Abort()
+			return
+		}
+
 		// If access token is expired, attempt to refresh it
 		if claims.ExpiresAt < time.Now().Unix() {
 			refreshToken := c.GetHeader("RefreshToken")
@@ -122,3 +140,36 @@ func Authentication() gin.HandlerFunc {
 		}
 	}
 }
+
+// StreamAuthentication authenticates the notification stream with the
+// single use ticket in the ticket query parameter, see services.StreamTicket.
+// Clients that can send the Authorization header go through Authentication.
+func StreamAuthentication() gin.HandlerFunc {
+	authenticate := Authentication()
+	return func(c *gin.Context) {
+		ticket := c.Query("ticket")
+		if ticket == "" {
+			authenticate(c)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Second)
+		redeemed, err := services.RedeemStreamTicket(ctx, ticket)
+		cancel()
+		if err != nil {
+			status := http.StatusInternalServerError
+			if err == services.ErrInvalidStreamTicket {
+				status = http.StatusUnauthorized
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+
+		c.Set("id", redeemed.UserID)
+		c.Set("role", redeemed.Role)
+		c.Set("email", redeemed.Email)
+		c.Set("name", redeemed.Name)
+		c.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID tags every request with an id, stored as "requestId" in the
+// context and echoed in the X-Request-ID header. A well formed id sent by
+// the client is kept so requests can be traced across services.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			buf := make([]byte, 16)
+			rand.Read(buf)
+			requestID = hex.EncodeToString(buf)
+		}
+		c.Set("requestId", requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"context"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Resume file ids are kept hex encoded in PDF.Data. Most writes stored them
+// as binary, resume replacements as strings. This stores them all as binary.
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "resume file ids as binary",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			return convertResumeIDs(ctx, db, bsontype.String, func(value bson.RawValue) interface{} {
+				return []byte(value.StringValue())
+			})
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			return convertResumeIDs(ctx, db, bsontype.Binary, func(value bson.RawValue) interface{} {
+				_, data := value.Binary()
+				return string(data)
+			})
+		},
+	})
+}
+
+var resumeFields = []struct {
+	collection string
+	field      string
+}{
+	{"applications", "resume.data"},
+	{"profiles", "defaultResume.data"},
+}
+
+func convertResumeIDs(ctx context.Context, db *mongo.Database, from bsontype.Type, convert func(bson.RawValue) interface{}) error {
+	for _, resume := range resumeFields {
+		collection := db.Collection(resume.collection)
+		cursor, err := collection.Find(ctx, bson.M{resume.field: bson.M{"$type": int(from)}})
+		if err != nil {
+			return err
+		}
+		for cursor.Next(ctx) {
+			id, ok := cursor.Current.Lookup("_id").ObjectIDOK()
+			if !ok {
+				continue
+			}
+			value, err := cursor.Current.LookupErr(strings.Split(resume.field, ".")...)
+			if err != nil {
+				cursor.Close(ctx)
+				return err
+			}
+			_, err = collection.UpdateOne(ctx,
+				bson.M{"_id": id},
+				bson.M{"$set": bson.M{resume.field: convert(value)}})
+			if err != nil {
+				cursor.Close(ctx)
+				return err
+			}
+		}
+		err = cursor.Err()
+		cursor.Close(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
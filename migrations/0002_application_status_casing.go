@@ -0,0 +1,45 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/weldonkipchirchir/job-listing-server/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Application statuses are capitalized when written, but older documents
+// were stored as sent, e.g. "pending" next to "Pending". The original
+// casing is not kept, so this cannot be rolled back.
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "capitalize application statuses",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			applications := db.Collection("applications")
+			for _, field := range []string{"status", "statusBeforeClose"} {
+				values, err := applications.Distinct(ctx, field, bson.M{})
+				if err != nil {
+					return err
+				}
+				for _, value := range values {
+					status, ok := value.(string)
+					if !ok || status == "" {
+						continue
+					}
+					capitalized := utils.CapitalizeFirstLetter(status)
+					if capitalized == status {
+						continue
+					}
+					_, err := applications.UpdateMany(ctx,
+						bson.M{field: status},
+						bson.M{"$set": bson.M{field: capitalized}})
+					if err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	})
+}
@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Salaries are free text. This stores them parsed into numbers next to the
+// text, as new and updated jobs get them.
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "numeric salary amounts",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			jobs := db.Collection("jobs")
+			opts := options.Find().SetProjection(bson.M{"salaryHigh": 1, "salaryLow": 1})
+			cursor, err := jobs.Find(ctx, bson.M{}, opts)
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+
+			for cursor.Next(ctx) {
+				var job models.Job
+				if err := cursor.Decode(&job); err != nil {
+					return err
+				}
+				set, unset := bson.M{}, bson.M{}
+				for field, amount := range map[string]*float64{
+					"salaryHighAmount": services.SalaryAmount(job.SalaryHigh),
+					"salaryLowAmount":  services.SalaryAmount(job.SalaryLow),
+				} {
+					if amount != nil {
+						set[field] = *amount
+					} else {
+						unset[field] = ""
+					}
+				}
+				update := bson.M{}
+				if len(set) > 0 {
+					update["$set"] = set
+				}
+				if len(unset) > 0 {
+					update["$unset"] = unset
+				}
+				if _, err := jobs.UpdateOne(ctx, bson.M{"_id": job.ID}, update); err != nil {
+					return err
+				}
+			}
+			return cursor.Err()
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection("jobs").UpdateMany(ctx, bson.M{},
+				bson.M{"$unset": bson.M{"salaryHighAmount": "", "salaryLowAmount": ""}})
+			return err
+		},
+	})
+}
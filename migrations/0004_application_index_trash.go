@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// One application per candidate and job was enforced over trashed
+// applications too, so a candidate whose application a recruiter deleted
+// could not apply again until the trash was purged. The unique index now
+// includes deletedAt, which only live applications share.
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "application unique index ignores the trash",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			indexes := db.Collection("applications").Indexes()
+			_, err := indexes.CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "jobId", Value: 1}, {Key: "deletedAt", Value: 1}},
+				Options: options.Index().SetName("userId_jobId_deletedAt_unique").SetUnique(true),
+			})
+			if err != nil {
+				return err
+			}
+			return dropIndex(ctx, indexes, "userId_jobId_unique")
+		},
+		// rolling back fails while a candidate has a live and a trashed
+		// application to the same job
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			indexes := db.Collection("applications").Indexes()
+			_, err := indexes.CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "userId", Value: 1}, {Key: "jobId", Value: 1}},
+				Options: options.Index().SetName("userId_jobId_unique").SetUnique(true),
+			})
+			if err != nil {
+				return err
+			}
+			return dropIndex(ctx, indexes, "userId_jobId_deletedAt_unique")
+		},
+	})
+}
@@ -0,0 +1,95 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/services"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The audit hash covered the personal values of an entry directly, so they
+// could not be erased without breaking the chain. This checks the chain as
+// it was hashed before and seals it again over digests of those values.
+// Run it with the servers stopped, an entry written meanwhile would chain
+// onto a hash that is replaced.
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "audit hash covers digests of personal values",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			auditLog := db.Collection("auditLog")
+			opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}})
+			cursor, err := auditLog.Find(ctx, bson.M{}, opts)
+			if err != nil {
+				return err
+			}
+			defer cursor.Close(ctx)
+
+			var oldPrev, newPrev string
+			resumed := false
+			for cursor.Next(ctx) {
+				var entry models.AuditEntry
+				if err := cursor.Decode(&entry); err != nil {
+					return err
+				}
+				// sealed by an earlier, interrupted run, whose old hash
+				// the next entry points to is gone
+				if entry.ActorDigest != "" {
+					if entry.PrevHash != newPrev || entry.Hash != services.AuditHash(entry) {
+						return fmt.Errorf("audit entry %d does not verify, not resealing", entry.Seq)
+					}
+					newPrev, resumed = entry.Hash, true
+					continue
+				}
+				if (!resumed && entry.PrevHash != oldPrev) || entry.Hash != legacyAuditHash(entry) {
+					return fmt.Errorf("audit entry %d does not verify, not resealing", entry.Seq)
+				}
+				oldPrev, resumed = entry.Hash, false
+
+				entry.PrevHash = newPrev
+				services.SealAuditEntry(&entry)
+				_, err := auditLog.UpdateOne(ctx, bson.M{"_id": entry.ID}, bson.M{"$set": bson.M{
+					"beforeDigest": entry.BeforeDigest,
+					"afterDigest":  entry.AfterDigest,
+					"actorDigest":  entry.ActorDigest,
+					"prevHash":     entry.PrevHash,
+					"hash":         entry.Hash,
+				}})
+				if err != nil {
+					return err
+				}
+				newPrev = entry.Hash
+			}
+			return cursor.Err()
+		},
+	})
+}
+
+// legacyAuditHash is the audit hash before the values were digested.
+func legacyAuditHash(entry models.AuditEntry) string {
+	fields := []string{
+		entry.PrevHash,
+		strconv.FormatInt(entry.Seq, 10),
+		strconv.FormatInt(entry.CreatedAt.UnixMilli(), 10),
+		entry.Actor.ID.Hex(),
+		entry.Actor.Email,
+		entry.Actor.Role,
+		entry.Action,
+		entry.TargetType,
+		entry.TargetID.Hex(),
+		hex.EncodeToString(entry.Before),
+		hex.EncodeToString(entry.After),
+		entry.IP,
+		entry.RequestID,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\n")))
+	return hex.EncodeToString(sum[:])
+}
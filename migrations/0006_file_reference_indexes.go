@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// fileReferenceIndexes are the fields that reference GridFS files. Without
+// an index the garbage collector scanned three collections for every file.
+var fileReferenceIndexes = []struct {
+	collection string
+	field      string
+}{
+	{"applications", "resume.data"},
+	{"profiles", "defaultResume.data"},
+	{"messages", "attachments.fileId"},
+}
+
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "index gridfs file references",
+		Up: func(ctx context.Context, db *mongo.Database) error {
+			for _, index := range fileReferenceIndexes {
+				_, err := db.Collection(index.collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: index.field, Value: 1}},
+					Options: options.Index().SetName(index.field),
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(ctx context.Context, db *mongo.Database) error {
+			for _, index := range fileReferenceIndexes {
+				if err := dropIndex(ctx, db.Collection(index.collection).Indexes(), index.field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
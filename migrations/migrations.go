@@ -0,0 +1,236 @@
+// Package migrations evolves the documents stored in the database. Each
+// migration has a version, applied in ascending order and recorded in the
+// migrations collection, and can be rolled back with its down function.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	lockID = "migrations"
+	// lockLease is how long the lock is held without being renewed, so a
+	// crashed run does not block migrations forever
+	lockLease = 10 * time.Minute
+)
+
+var (
+	ErrLocked       = errors.New("another instance is running migrations")
+	ErrIrreversible = errors.New("migration cannot be rolled back")
+)
+
+// Migration changes stored documents from one schema version to the next.
+// Down undoes Up; it is nil for migrations that cannot be undone.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	Down    func(ctx context.Context, db *mongo.Database) error
+}
+
+// Applied records a migration in the migrations collection.
+type Applied struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"appliedAt"`
+}
+
+// Status describes a known migration and whether it is applied.
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+var registry = map[int]Migration{}
+
+func register(migration Migration) {
+	if _, ok := registry[migration.Version]; ok {
+		panic(fmt.Sprintf("migration %d registered twice", migration.Version))
+	}
+	registry[migration.Version] = migration
+}
+
+// All returns the known migrations in the order they apply.
+func All() []Migration {
+	migrations := make([]Migration, 0, len(registry))
+	for _, migration := range registry {
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations
+}
+
+func applied(ctx context.Context, db *mongo.Database) (map[int]Applied, error) {
+	cursor, err := db.Collection("migrations").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var records []Applied
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	byVersion := map[int]Applied{}
+	for _, record := range records {
+		byVersion[record.Version] = record
+	}
+	return byVersion, nil
+}
+
+// GetStatus lists the known migrations and whether they are applied.
+func GetStatus(ctx context.Context, db *mongo.Database) ([]Status, error) {
+	done, err := applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	statuses := []Status{}
+	for _, migration := range All() {
+		record, ok := done[migration.Version]
+		statuses = append(statuses, Status{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: record.AppliedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// Up applies the pending migrations up to and including target, or all of
+// them when target is 0. It returns the migrations it applied.
+func Up(ctx context.Context, db *mongo.Database, target int) ([]Migration, error) {
+	unlock, err := lock(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	done, err := applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var ran []Migration
+	for _, migration := range All() {
+		if target > 0 && migration.Version > target {
+			break
+		}
+		if _, ok := done[migration.Version]; ok {
+			continue
+		}
+		if err := migration.Up(ctx, db); err != nil {
+			return ran, fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+		record := Applied{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}
+		if _, err := db.Collection("migrations").InsertOne(ctx, record); err != nil {
+			return ran, err
+		}
+		ran = append(ran, migration)
+		if err := renew(ctx, db); err != nil {
+			return ran, err
+		}
+	}
+	return ran, nil
+}
+
+// Down rolls back the last steps applied migrations, newest first. It
+// returns the migrations it rolled back.
+func Down(ctx context.Context, db *mongo.Database, steps int) ([]Migration, error) {
+	unlock, err := lock(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	done, err := applied(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	all := All()
+	var ran []Migration
+	for i := len(all) - 1; i >= 0 && len(ran) < steps; i-- {
+		migration := all[i]
+		if _, ok := done[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == nil {
+			return ran, fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, ErrIrreversible)
+		}
+		if err := migration.Down(ctx, db); err != nil {
+			return ran, fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+		if _, err := db.Collection("migrations").DeleteOne(ctx, bson.M{"_id": migration.Version}); err != nil {
+			return ran, err
+		}
+		ran = append(ran, migration)
+		if err := renew(ctx, db); err != nil {
+			return ran, err
+		}
+	}
+	return ran, nil
+}
+
+func lockOwner() string {
+	host, _ := os.Hostname()
+	return host + ":" + strconv.Itoa(os.Getpid())
+}
+
+// lock takes the migration lock in the locks collection. An expired lock
+// is taken over; a held one fails with ErrLocked.
+func lock(ctx context.Context, db *mongo.Database) (func(), error) {
+	owner := lockOwner()
+	now := time.Now().UTC()
+	_, err := db.Collection("locks").UpdateOne(ctx,
+		bson.M{"_id": lockID, "expiresAt": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{"owner": owner, "lockedAt": now, "expiresAt": now.Add(lockLease)}},
+		options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		// released with a fresh context so a cancelled run still unlocks
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		db.Collection("locks").DeleteOne(ctx, bson.M{"_id": lockID, "owner": owner})
+	}, nil
+}
+
+// renew extends the lease of the lock held by this process.
+func renew(ctx context.Context, db *mongo.Database) error {
+	result, err := db.Collection("locks").UpdateOne(ctx,
+		bson.M{"_id": lockID, "owner": lockOwner()},
+		bson.M{"$set": bson.M{"expiresAt": time.Now().UTC().Add(lockLease)}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrLocked
+	}
+	return nil
+}
+
+// dropIndex drops an index, which is fine to be missing.
+func dropIndex(ctx context.Context, indexes mongo.IndexView, name string) error {
+	_, err := indexes.DropOne(ctx, name)
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && (commandErr.Code == 26 || commandErr.Code == 27) {
+		return nil
+	}
+	return err
+}
@@ -1,19 +1,60 @@
 package models
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	StatusPending  = "Pending"
+	StatusRejected = "Rejected"
+	StatusFlagged  = "Flagged"
+	// StatusClosed is set on open applications when their job is deleted
+	StatusClosed = "Closed"
+)
+
 type Application struct {
-	ID      primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
-	JobID   primitive.ObjectID `json:"jobId" bson:"jobId" validate:"required"`
-	JobName string             `json:"jobName" bson:"jobName" validate:"required"`
-	UserID  primitive.ObjectID `json:"userId" bson:"userId" validate:"required"`
-	Name    string             `json:"name" bson:"name" validate:"required"`
-	Status  string             `json:"status" bson:"status" validate:"required,min=3"`
-	Resume  PDF                `json:"resume" bson:"resume" validate:"required"`
-	Email   string             `json:"email" bson:"email" validate:"email"`
-	Company string             `json:"company" bson:"company" validate:"company"`
+	ID                 primitive.ObjectID  `json:"_id,omitempty" bson:"_id,omitempty"`
+	JobID              primitive.ObjectID  `json:"jobId" bson:"jobId" validate:"required"`
+	JobName            string              `json:"jobName" bson:"jobName" validate:"required"`
+	UserID             primitive.ObjectID  `json:"userId" bson:"userId" validate:"required"`
+	Name               string              `json:"name" bson:"name" validate:"required"`
+	Status             string              `json:"status" bson:"status" validate:"required,min=3"`
+	Resume             PDF                 `json:"resume" bson:"resume" validate:"required"`
+	Email              string              `json:"email" bson:"email" validate:"email"`
+	Company            string              `json:"company" bson:"company" validate:"company"`
+	JobVersion         int                 `json:"jobVersion" bson:"jobVersion"`
+	Version            int                 `json:"version" bson:"version"`
+	Answers            []ScreeningAnswer   `json:"answers,omitempty" bson:"answers,omitempty"`
+	KnockoutReasons    []string            `json:"knockoutReasons,omitempty" bson:"knockoutReasons,omitempty"`
+	Flagged            bool                `json:"flagged,omitempty" bson:"flagged,omitempty"`
+	ProfileSnapshot    *ProfileSnapshot    `json:"profileSnapshot,omitempty" bson:"profileSnapshot,omitempty"`
+	ResumeText         string              `json:"-" bson:"resumeText,omitempty"`
+	Match              *ResumeMatch        `json:"match,omitempty" bson:"match,omitempty"`
+	Notes              []RecruiterNote     `json:"notes,omitempty" bson:"notes,omitempty"`
+	Tags               []string            `json:"tags,omitempty" bson:"tags,omitempty"`
+	Scorecards         []Scorecard         `json:"scorecards,omitempty" bson:"scorecards,omitempty"`
+	AverageRating      float64             `json:"averageRating,omitempty" bson:"averageRating,omitempty"`
+	StatusBeforeClose  string              `json:"-" bson:"statusBeforeClose,omitempty"`
+	StatusChangedAt    *time.Time          `json:"statusChangedAt,omitempty" bson:"statusChangedAt,omitempty"`
+	ResumePurgedAt     *time.Time          `json:"resumePurgedAt,omitempty" bson:"resumePurgedAt,omitempty"`
+	DeletedAt          *time.Time          `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+	DeletedBy          *primitive.ObjectID `json:"deletedBy,omitempty" bson:"deletedBy,omitempty"`
+	ErasedAt           *time.Time          `json:"erasedAt,omitempty" bson:"erasedAt,omitempty"`
+	IdentityRevealedAt *time.Time          `json:"identityRevealedAt,omitempty" bson:"identityRevealedAt,omitempty"`
+	IdentityRevealedBy *primitive.ObjectID `json:"identityRevealedBy,omitempty" bson:"identityRevealedBy,omitempty"`
+	// Blind is set on responses with the candidate's identity masked
+	Blind bool `json:"blind,omitempty" bson:"-"`
+}
+
+// ApplicationRequest is what a candidate sends to apply; everything else on
+// an application is set by the server, the name and email from the account.
+type ApplicationRequest struct {
+	JobID      primitive.ObjectID `json:"jobId" validate:"required"`
+	Resume     PDF                `json:"resume"`
+	Answers    []ScreeningAnswer  `json:"answers"`
+	UseProfile bool               `json:"useProfile"`
 }
 
 type PDF struct {
@@ -23,18 +64,30 @@ type PDF struct {
 }
 
 type ApplicationUserResponse struct {
-	ID      primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
-	JobID   primitive.ObjectID `json:"jobId" bson:"jobId" validate:"required"`
-	Status  string             `json:"status" bson:"status" validate:"required,min=3"`
-	JobName string             `json:"jobName" bson:"jobName" validate:"required"`
-	Company string             `json:"company" bson:"company" validate:"company"`
+	ID         primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	JobID      primitive.ObjectID `json:"jobId" bson:"jobId" validate:"required"`
+	Status     string             `json:"status" bson:"status" validate:"required,min=3"`
+	JobName    string             `json:"jobName" bson:"jobName" validate:"required"`
+	Company    string             `json:"company" bson:"company" validate:"company"`
+	JobVersion int                `json:"jobVersion" bson:"jobVersion"`
 }
 type ApplicationAdminResponse struct {
-	ID      primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
-	JobID   primitive.ObjectID `json:"jobId" bson:"jobId" validate:"required"`
-	Status  string             `json:"status" bson:"status" validate:"required,min=3"`
-	JobName string             `json:"jobName" bson:"jobName" validate:"required"`
-	Name    string             `json:"name" bson:"name" validate:"required"`
-	Email   string             `json:"email" bson:"email" validate:"email"`
-	Resume  PDF                `json:"resume" bson:"resume" validate:"required"`
+	ID              primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	JobID           primitive.ObjectID `json:"jobId" bson:"jobId" validate:"required"`
+	Status          string             `json:"status" bson:"status" validate:"required,min=3"`
+	JobName         string             `json:"jobName" bson:"jobName" validate:"required"`
+	Name            string             `json:"name" bson:"name" validate:"required"`
+	Email           string             `json:"email" bson:"email" validate:"email"`
+	Resume          PDF                `json:"resume" bson:"resume" validate:"required"`
+	Answers         []ScreeningAnswer  `json:"answers,omitempty" bson:"answers,omitempty"`
+	KnockoutReasons []string           `json:"knockoutReasons,omitempty" bson:"knockoutReasons,omitempty"`
+	Flagged         bool               `json:"flagged,omitempty" bson:"flagged,omitempty"`
+	ProfileSnapshot *ProfileSnapshot   `json:"profileSnapshot,omitempty" bson:"profileSnapshot,omitempty"`
+	Match           *ResumeMatch       `json:"match,omitempty" bson:"match,omitempty"`
+	Notes           []RecruiterNote    `json:"notes,omitempty" bson:"notes,omitempty"`
+	Tags            []string           `json:"tags,omitempty" bson:"tags,omitempty"`
+	Scorecards      []Scorecard        `json:"scorecards,omitempty" bson:"scorecards,omitempty"`
+	AverageRating   float64            `json:"averageRating,omitempty" bson:"averageRating,omitempty"`
+	Version         int                `json:"version" bson:"version"`
+	Blind           bool               `json:"blind,omitempty" bson:"-"`
 }
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	AuditTargetJob         = "job"
+	AuditTargetApplication = "application"
+	AuditTargetUser        = "user"
+)
+
+const (
+	AuditJobCreate               = "job.create"
+	AuditJobUpdate               = "job.update"
+	AuditJobImport               = "job.import"
+	AuditJobRevisionRestore      = "job.revision_restore"
+	AuditJobDelete               = "job.delete"
+	AuditJobRestore              = "job.restore"
+	AuditApplicationUpdate       = "application.update"
+	AuditApplicationStatusChange = "application.status_change"
+	AuditApplicationDelete       = "application.delete"
+	AuditApplicationRestore      = "application.restore"
+	AuditApplicationReveal       = "application.reveal_identity"
+	AuditUserUpdate              = "user.update"
+	AuditUserRoleChange          = "user.role_change"
+	AuditUserDelete              = "user.delete"
+	AuditUserRestore             = "user.restore"
+	AuditUserErasureRequest      = "user.erasure_request"
+	AuditUserErasureCancel       = "user.erasure_cancel"
+	AuditUserErase               = "user.erase"
+)
+
+type AuditActor struct {
+	ID    primitive.ObjectID `json:"id,omitempty" bson:"id,omitempty"`
+	Email string             `json:"email,omitempty" bson:"email,omitempty"`
+	Role  string             `json:"role,omitempty" bson:"role,omitempty"`
+}
+
+// AuditEntry is one link of the append-only audit log. Hash covers the
+// entry and the hash of the previous entry, so editing or removing an
+// entry breaks the chain from there on. The personal values, before, after
+// and the actor's email and ip, are only covered through their digests, so
+// an erasure can remove them and the chain still verifies.
+type AuditEntry struct {
+	ID           primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Seq          int64              `json:"seq" bson:"seq"`
+	Actor        AuditActor         `json:"actor" bson:"actor"`
+	Action       string             `json:"action" bson:"action"`
+	TargetType   string             `json:"targetType" bson:"targetType"`
+	TargetID     primitive.ObjectID `json:"targetId" bson:"targetId"`
+	Before       bson.Raw           `json:"-" bson:"before,omitempty"`
+	After        bson.Raw           `json:"-" bson:"after,omitempty"`
+	IP           string             `json:"ip,omitempty" bson:"ip,omitempty"`
+	RequestID    string             `json:"requestId,omitempty" bson:"requestId,omitempty"`
+	CreatedAt    time.Time          `json:"createdAt" bson:"createdAt"`
+	BeforeDigest string             `json:"-" bson:"beforeDigest,omitempty"`
+	AfterDigest  string             `json:"-" bson:"afterDigest,omitempty"`
+	ActorDigest  string             `json:"-" bson:"actorDigest,omitempty"`
+	PrevHash     string             `json:"prevHash" bson:"prevHash"`
+	Hash         string             `json:"hash" bson:"hash"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	InterviewProposed  = "proposed"
+	InterviewScheduled = "scheduled"
+	InterviewCancelled = "cancelled"
+)
+
+type InterviewSlot struct {
+	ID    primitive.ObjectID `json:"_id" bson:"_id"`
+	Start time.Time          `json:"start" bson:"start"`
+	End   time.Time          `json:"end" bson:"end"`
+}
+
+type Interview struct {
+	ID             primitive.ObjectID   `json:"_id,omitempty" bson:"_id,omitempty"`
+	ApplicationID  primitive.ObjectID   `json:"applicationId" bson:"applicationId" validate:"required"`
+	JobID          primitive.ObjectID   `json:"jobId" bson:"jobId"`
+	JobName        string               `json:"jobName" bson:"jobName"`
+	Company        string               `json:"company" bson:"company"`
+	CandidateID    primitive.ObjectID   `json:"candidateId" bson:"candidateId"`
+	CandidateName  string               `json:"candidateName" bson:"candidateName"`
+	CandidateEmail string               `json:"candidateEmail" bson:"candidateEmail"`
+	OrganizerID    primitive.ObjectID   `json:"organizerId" bson:"organizerId"`
+	InterviewerIDs []primitive.ObjectID `json:"interviewerIds" bson:"interviewerIds"`
+	Title          string               `json:"title" bson:"title"`
+	Notes          string               `json:"notes,omitempty" bson:"notes,omitempty"`
+	Location       string               `json:"location,omitempty" bson:"location,omitempty"`
+	VideoLink      string               `json:"videoLink,omitempty" bson:"videoLink,omitempty"`
+	Timezone       string               `json:"timezone" bson:"timezone"`
+	Slots          []InterviewSlot      `json:"slots" bson:"slots"`
+	SelectedSlot   *InterviewSlot       `json:"selectedSlot,omitempty" bson:"selectedSlot,omitempty"`
+	Status         string               `json:"status" bson:"status"`
+	CancelReason   string               `json:"cancelReason,omitempty" bson:"cancelReason,omitempty"`
+	Sequence       int                  `json:"sequence" bson:"sequence"`
+	CreatedAt      time.Time            `json:"createdAt" bson:"createdAt"`
+	UpdatedAt      time.Time            `json:"updatedAt" bson:"updatedAt"`
+}
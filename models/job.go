@@ -1,25 +1,67 @@
 package models
 
 import (
+	"time"
+
 	"github.com/weldonkipchirchir/job-listing-server/utils"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Job struct {
-	ID                    primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
-	JobName               string             `json:"jobName" bson:"jobName" validate:"required,min=3"`
-	Type                  string             `json:"type" bson:"type" validate:"required,min=3"`
-	Location              string             `json:"location" bson:"location" validate:"required,min=3"`
-	SalaryHigh            string             `json:"salaryHigh" bson:"salaryHigh" validate:"required"`
-	SalaryLow             string             `json:"salaryLow" bson:"salaryLow" validate:"required"`
-	Company               string             `json:"company" bson:"company" validate:"required,min=3"`
-	ImageLink             string             `json:"imageLink" bson:"imageLink" validate:"required"`
-	Sponsored             bool               `json:"sponsored" bson:"sponsored" validate:"required"`
-	UserID                primitive.ObjectID `json:"userId" bson:"userId" validate:"required"`
-	Currency              utils.Currency     `json:"currency" bson:"currency" validate:"required,min3"`
-	MandatoryRequirements []string           `json:"mandatoryRequirements" bson:"mandatoryRequirements" validate:"required"`
-	OptionalRequirements  []string           `json:"optionalRequirements" bson:"optionalRequirements"`
-	JobDescription        string             `json:"jobDescription" bson:"jobDescription" validate:"required"`
-	Industry              string             `json:"industry" bson:"industry" validate:"required"`
-	DaysAgo               int                `json:"daysAgo" bson:"-"`
+	ID         primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	JobName    string             `json:"jobName" bson:"jobName" validate:"required,min=3"`
+	Type       string             `json:"type" bson:"type" validate:"required,min=3"`
+	Location   string             `json:"location" bson:"location" validate:"required,min=3"`
+	SalaryHigh string             `json:"salaryHigh" bson:"salaryHigh" validate:"required"`
+	SalaryLow  string             `json:"salaryLow" bson:"salaryLow" validate:"required"`
+	// SalaryHighAmount and SalaryLowAmount are the salaries parsed into
+	// numbers, unset when the text is not a number
+	SalaryHighAmount      *float64            `json:"salaryHighAmount,omitempty" bson:"salaryHighAmount,omitempty"`
+	SalaryLowAmount       *float64            `json:"salaryLowAmount,omitempty" bson:"salaryLowAmount,omitempty"`
+	Company               string              `json:"company" bson:"company" validate:"required,min=3"`
+	ImageLink             string              `json:"imageLink" bson:"imageLink" validate:"required"`
+	Sponsored             bool                `json:"sponsored" bson:"sponsored" validate:"required"`
+	UserID                primitive.ObjectID  `json:"userId" bson:"userId" validate:"required"`
+	Currency              utils.Currency      `json:"currency" bson:"currency" validate:"required,min3"`
+	MandatoryRequirements []string            `json:"mandatoryRequirements" bson:"mandatoryRequirements" validate:"required"`
+	OptionalRequirements  []string            `json:"optionalRequirements" bson:"optionalRequirements"`
+	JobDescription        string              `json:"jobDescription" bson:"jobDescription" validate:"required"`
+	Industry              string              `json:"industry" bson:"industry" validate:"required"`
+	ScreeningQuestions    []ScreeningQuestion `json:"screeningQuestions,omitempty" bson:"screeningQuestions,omitempty"`
+	KnockoutAction        string              `json:"knockoutAction,omitempty" bson:"knockoutAction,omitempty"`
+	BlindReview           *BlindReview        `json:"blindReview,omitempty" bson:"blindReview,omitempty"`
+	ExternalRef           string              `json:"externalRef,omitempty" bson:"externalRef,omitempty"`
+	Version               int                 `json:"version" bson:"version"`
+	UpdatedAt             time.Time           `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+	DeletedAt             *time.Time          `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+	DeletedBy             *primitive.ObjectID `json:"deletedBy,omitempty" bson:"deletedBy,omitempty"`
+	DaysAgo               int                 `json:"daysAgo" bson:"-"`
+}
+
+// JobRequest is what a recruiter sends to post a job; ids, versions and
+// deletion are set by the server, blind review through its own endpoint.
+type JobRequest struct {
+	JobName               string                    `json:"jobName" validate:"required,min=3"`
+	Type                  string                    `json:"type" validate:"required,min=3"`
+	Location              string                    `json:"location" validate:"required,min=3"`
+	SalaryHigh            string                    `json:"salaryHigh" validate:"required"`
+	SalaryLow             string                    `json:"salaryLow" validate:"required"`
+	Company               string                    `json:"company" validate:"required,min=3"`
+	ImageLink             string                    `json:"imageLink" validate:"required"`
+	Sponsored             bool                      `json:"sponsored"`
+	Currency              utils.Currency            `json:"currency" validate:"required,min3"`
+	MandatoryRequirements []string                  `json:"mandatoryRequirements" validate:"required"`
+	OptionalRequirements  []string                  `json:"optionalRequirements"`
+	JobDescription        string                    `json:"jobDescription" validate:"required"`
+	Industry              string                    `json:"industry" validate:"required"`
+	ScreeningQuestions    []ScreeningQuestionDetail `json:"screeningQuestions"`
+	KnockoutAction        string                    `json:"knockoutAction"`
+}
+
+// BlindReview hides who the candidates of a job are from its recruiters
+// until an application reaches RevealStatus or its identity is revealed
+// explicitly.
+type BlindReview struct {
+	Enabled      bool   `json:"enabled" bson:"enabled"`
+	RevealStatus string `json:"revealStatus,omitempty" bson:"revealStatus,omitempty"`
 }
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	RevisionCreated  = "created"
+	RevisionUpdated  = "updated"
+	RevisionImported = "imported"
+	RevisionRestored = "restored"
+	RevisionBaseline = "baseline"
+)
+
+type JobRevision struct {
+	ID           primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	JobID        primitive.ObjectID `json:"jobId" bson:"jobId"`
+	Version      int                `json:"version" bson:"version"`
+	Action       string             `json:"action" bson:"action"`
+	Snapshot     Job                `json:"snapshot" bson:"snapshot"`
+	Changes      []FieldChange      `json:"changes" bson:"changes"`
+	AuthorID     primitive.ObjectID `json:"authorId" bson:"authorId"`
+	RestoredFrom int                `json:"restoredFrom,omitempty" bson:"restoredFrom,omitempty"`
+	CreatedAt    time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+type FieldChange struct {
+	Field string      `json:"field" bson:"field"`
+	From  interface{} `json:"from" bson:"from"`
+	To    interface{} `json:"to" bson:"to"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MessageAttachment struct {
+	FileID      primitive.ObjectID `json:"fileId" bson:"fileId"`
+	Filename    string             `json:"filename" bson:"filename"`
+	ContentType string             `json:"contentType" bson:"contentType"`
+	Size        int                `json:"size" bson:"size"`
+}
+
+// Message is one entry of the conversation about an application. ReadAt is
+// set once the other side has read it.
+type Message struct {
+	ID            primitive.ObjectID  `json:"_id,omitempty" bson:"_id,omitempty"`
+	ApplicationID primitive.ObjectID  `json:"applicationId" bson:"applicationId"`
+	JobID         primitive.ObjectID  `json:"jobId" bson:"jobId"`
+	JobName       string              `json:"jobName" bson:"jobName"`
+	CandidateID   primitive.ObjectID  `json:"candidateId" bson:"candidateId"`
+	RecruiterID   primitive.ObjectID  `json:"recruiterId" bson:"recruiterId"`
+	SenderID      primitive.ObjectID  `json:"senderId" bson:"senderId"`
+	SenderRole    string              `json:"senderRole" bson:"senderRole"`
+	SenderName    string              `json:"senderName" bson:"senderName"`
+	Body          string              `json:"body" bson:"body"`
+	Attachments   []MessageAttachment `json:"attachments" bson:"attachments"`
+	ReadAt        *time.Time          `json:"readAt,omitempty" bson:"readAt,omitempty"`
+	CreatedAt     time.Time           `json:"createdAt" bson:"createdAt"`
+}
+
+// MessageThread summarizes the conversation of one application.
+type MessageThread struct {
+	ApplicationID primitive.ObjectID `json:"applicationId" bson:"_id"`
+	LastMessage   Message            `json:"lastMessage" bson:"lastMessage"`
+	Unread        int                `json:"unread" bson:"unread"`
+	Total         int                `json:"total" bson:"total"`
+}
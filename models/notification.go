@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	NotificationNewApplication = "application.new"
+	NotificationStatusChanged  = "application.status_changed"
+	NotificationNewMessage     = "message.new"
+	NotificationJobAlert       = "job.alert"
+)
+
+type Notification struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"userId" bson:"userId"`
+	Type      string             `json:"type" bson:"type"`
+	Title     string             `json:"title" bson:"title"`
+	Body      string             `json:"body,omitempty" bson:"body,omitempty"`
+	Data      map[string]string  `json:"data,omitempty" bson:"data,omitempty"`
+	Read      bool               `json:"read" bson:"read"`
+	ReadAt    *time.Time         `json:"readAt,omitempty" bson:"readAt,omitempty"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// JobAlert is a saved search of a candidate. New jobs matching all of its
+// non-empty criteria raise a job alert notification.
+type JobAlert struct {
+	ID            primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID        primitive.ObjectID `json:"userId" bson:"userId"`
+	Name          string             `json:"name" bson:"name"`
+	Keywords      []string           `json:"keywords" bson:"keywords"`
+	Location      string             `json:"location,omitempty" bson:"location,omitempty"`
+	Type          string             `json:"type,omitempty" bson:"type,omitempty"`
+	Industry      string             `json:"industry,omitempty" bson:"industry,omitempty"`
+	Company       string             `json:"company,omitempty" bson:"company,omitempty"`
+	Active        bool               `json:"active" bson:"active"`
+	LastMatchedAt *time.Time         `json:"lastMatchedAt,omitempty" bson:"lastMatchedAt,omitempty"`
+	CreatedAt     time.Time          `json:"createdAt" bson:"createdAt"`
+}
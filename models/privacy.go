@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ErasurePending   = "pending"
+	ErasureCancelled = "cancelled"
+	ErasureCompleted = "completed"
+)
+
+// ErasedName replaces the name of a candidate whose data was erased.
+const ErasedName = "Erased candidate"
+
+// ErasureReport counts what an erasure changed.
+type ErasureReport struct {
+	ApplicationsAnonymized  int64 `json:"applicationsAnonymized" bson:"applicationsAnonymized"`
+	MessagesRedacted        int64 `json:"messagesRedacted" bson:"messagesRedacted"`
+	InterviewsAnonymized    int64 `json:"interviewsAnonymized" bson:"interviewsAnonymized"`
+	RecordsDeleted          int64 `json:"recordsDeleted" bson:"recordsDeleted"`
+	FilesDeleted            int64 `json:"filesDeleted" bson:"filesDeleted"`
+	AuditEntriesRedacted    int64 `json:"auditEntriesRedacted" bson:"auditEntriesRedacted"`
+	NotificationsRedacted   int64 `json:"notificationsRedacted" bson:"notificationsRedacted"`
+	WebhookPayloadsRedacted int64 `json:"webhookPayloadsRedacted" bson:"webhookPayloadsRedacted"`
+	TasksRedacted           int64 `json:"tasksRedacted" bson:"tasksRedacted"`
+}
+
+// ErasureRequest tracks a candidate's request to have their data erased.
+// It is carried out at ScheduledFor unless cancelled before.
+type ErasureRequest struct {
+	ID           primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID       primitive.ObjectID `json:"userId" bson:"userId"`
+	Status       string             `json:"status" bson:"status"`
+	RequestedAt  time.Time          `json:"requestedAt" bson:"requestedAt"`
+	ScheduledFor time.Time          `json:"scheduledFor" bson:"scheduledFor"`
+	CancelledAt  *time.Time         `json:"cancelledAt,omitempty" bson:"cancelledAt,omitempty"`
+	CompletedAt  *time.Time         `json:"completedAt,omitempty" bson:"completedAt,omitempty"`
+	Report       *ErasureReport     `json:"report,omitempty" bson:"report,omitempty"`
+}
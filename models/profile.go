@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Profile struct {
+	ID            primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID        primitive.ObjectID `json:"userId" bson:"userId" validate:"required"`
+	Headline      string             `json:"headline" bson:"headline" validate:"required,min=3"`
+	Summary       string             `json:"summary" bson:"summary"`
+	Skills        []string           `json:"skills" bson:"skills"`
+	WorkHistory   []WorkExperience   `json:"workHistory" bson:"workHistory"`
+	Education     []Education        `json:"education" bson:"education"`
+	Links         []ProfileLink      `json:"links" bson:"links"`
+	DefaultResume *PDF               `json:"defaultResume,omitempty" bson:"defaultResume,omitempty"`
+	CreatedAt     time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt     time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+type WorkExperience struct {
+	Title       string `json:"title" bson:"title" validate:"required"`
+	Company     string `json:"company" bson:"company" validate:"required"`
+	Location    string `json:"location,omitempty" bson:"location,omitempty"`
+	StartDate   string `json:"startDate" bson:"startDate"`
+	EndDate     string `json:"endDate,omitempty" bson:"endDate,omitempty"`
+	Current     bool   `json:"current" bson:"current"`
+	Description string `json:"description,omitempty" bson:"description,omitempty"`
+}
+
+type Education struct {
+	Institution  string `json:"institution" bson:"institution" validate:"required"`
+	Degree       string `json:"degree,omitempty" bson:"degree,omitempty"`
+	FieldOfStudy string `json:"fieldOfStudy,omitempty" bson:"fieldOfStudy,omitempty"`
+	StartYear    int    `json:"startYear,omitempty" bson:"startYear,omitempty"`
+	EndYear      int    `json:"endYear,omitempty" bson:"endYear,omitempty"`
+}
+
+type ProfileLink struct {
+	Label string `json:"label" bson:"label"`
+	URL   string `json:"url" bson:"url" validate:"required,url"`
+}
+
+// ProfileSnapshot is the copy of a candidate profile kept on an application,
+// so later profile edits do not change what the recruiter received.
+type ProfileSnapshot struct {
+	ProfileID   primitive.ObjectID `json:"profileId" bson:"profileId"`
+	Headline    string             `json:"headline" bson:"headline"`
+	Summary     string             `json:"summary" bson:"summary"`
+	Skills      []string           `json:"skills" bson:"skills"`
+	WorkHistory []WorkExperience   `json:"workHistory" bson:"workHistory"`
+	Education   []Education        `json:"education" bson:"education"`
+	Links       []ProfileLink      `json:"links" bson:"links"`
+	TakenAt     time.Time          `json:"takenAt" bson:"takenAt"`
+}
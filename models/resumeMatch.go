@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+type ResumeMatch struct {
+	Score            float64   `json:"score" bson:"score"`
+	Matched          []string  `json:"matched" bson:"matched"`
+	Missing          []string  `json:"missing" bson:"missing"`
+	MandatoryMatched int       `json:"mandatoryMatched" bson:"mandatoryMatched"`
+	MandatoryTotal   int       `json:"mandatoryTotal" bson:"mandatoryTotal"`
+	OptionalMatched  int       `json:"optionalMatched" bson:"optionalMatched"`
+	OptionalTotal    int       `json:"optionalTotal" bson:"optionalTotal"`
+	AnalyzedAt       time.Time `json:"analyzedAt" bson:"analyzedAt"`
+}
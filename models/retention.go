@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// RetentionDeleteResume deletes the resume file and its extracted text
+	RetentionDeleteResume = "delete_resume"
+	// RetentionAnonymize strips the candidate's identity from the application
+	RetentionAnonymize = "anonymize"
+	// RetentionDeleteApplication deletes the application with its messages
+	RetentionDeleteApplication = "delete_application"
+)
+
+// RetentionPolicy limits how long an organization keeps application data.
+// It belongs to the recruiter who created it and applies to applications
+// for their jobs, only those posted for Company if set, that have been in
+// Status (any status if empty) for more than AfterDays.
+type RetentionPolicy struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Company   string             `json:"company,omitempty" bson:"company,omitempty"`
+	Status    string             `json:"status,omitempty" bson:"status,omitempty"`
+	AfterDays int                `json:"afterDays" bson:"afterDays"`
+	Action    string             `json:"action" bson:"action"`
+	Active    bool               `json:"active" bson:"active"`
+	CreatedBy primitive.ObjectID `json:"createdBy" bson:"createdBy"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+// RetentionReport records what one policy purged in one enforcement run, or
+// would purge in a dry run.
+type RetentionReport struct {
+	ID             primitive.ObjectID   `json:"_id,omitempty" bson:"_id,omitempty"`
+	RunID          primitive.ObjectID   `json:"runId" bson:"runId"`
+	PolicyID       primitive.ObjectID   `json:"policyId" bson:"policyId"`
+	OwnerID        primitive.ObjectID   `json:"ownerId" bson:"ownerId"`
+	Company        string               `json:"company,omitempty" bson:"company,omitempty"`
+	Status         string               `json:"status,omitempty" bson:"status,omitempty"`
+	Action         string               `json:"action" bson:"action"`
+	AfterDays      int                  `json:"afterDays" bson:"afterDays"`
+	Cutoff         time.Time            `json:"cutoff" bson:"cutoff"`
+	DryRun         bool                 `json:"dryRun" bson:"dryRun"`
+	Applications   int64                `json:"applications" bson:"applications"`
+	Files          int64                `json:"files" bson:"files"`
+	ApplicationIDs []primitive.ObjectID `json:"applicationIds,omitempty" bson:"applicationIds,omitempty"`
+	Error          string               `json:"error,omitempty" bson:"error,omitempty"`
+	RanAt          time.Time            `json:"ranAt" bson:"ranAt"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MinRating = 1
+	MaxRating = 5
+)
+
+const (
+	RecommendStrongYes = "strong_yes"
+	RecommendYes       = "yes"
+	RecommendNo        = "no"
+	RecommendStrongNo  = "strong_no"
+)
+
+// RecruiterNote is a private note on an application, only visible to the
+// recruiters of the job.
+type RecruiterNote struct {
+	ID         primitive.ObjectID `json:"_id" bson:"_id"`
+	Body       string             `json:"body" bson:"body" validate:"required"`
+	AuthorID   primitive.ObjectID `json:"authorId" bson:"authorId"`
+	AuthorName string             `json:"authorName" bson:"authorName"`
+	CreatedAt  time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt  time.Time          `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+}
+
+// CriterionRating rates the candidate against one requirement of the job.
+type CriterionRating struct {
+	Criterion string `json:"criterion" bson:"criterion"`
+	Mandatory bool   `json:"mandatory" bson:"mandatory"`
+	Rating    int    `json:"rating" bson:"rating"`
+	Comment   string `json:"comment,omitempty" bson:"comment,omitempty"`
+}
+
+// Scorecard is one recruiter's structured assessment of an application.
+type Scorecard struct {
+	ID             primitive.ObjectID `json:"_id" bson:"_id"`
+	AuthorID       primitive.ObjectID `json:"authorId" bson:"authorId"`
+	AuthorName     string             `json:"authorName" bson:"authorName"`
+	Ratings        []CriterionRating  `json:"ratings" bson:"ratings"`
+	Recommendation string             `json:"recommendation,omitempty" bson:"recommendation,omitempty"`
+	Summary        string             `json:"summary,omitempty" bson:"summary,omitempty"`
+	Average        float64            `json:"average" bson:"average"`
+	CreatedAt      time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt      time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	QuestionYesNo          = "yes_no"
+	QuestionMultipleChoice = "multiple_choice"
+	QuestionNumeric        = "numeric"
+	QuestionFreeText       = "free_text"
+
+	KnockoutReject = "reject"
+	KnockoutFlag   = "flag"
+)
+
+// ScreeningQuestion is a question of a job. The knockout criteria are never
+// part of its JSON, jobs are public; the owner of the job reads and writes
+// them through ScreeningQuestionDetail.
+type ScreeningQuestion struct {
+	ID              primitive.ObjectID `json:"_id" bson:"_id"`
+	Prompt          string             `json:"prompt" bson:"prompt" validate:"required"`
+	Type            string             `json:"type" bson:"type" validate:"required"`
+	Options         []string           `json:"options,omitempty" bson:"options,omitempty"`
+	Required        bool               `json:"required" bson:"required"`
+	Knockout        bool               `json:"-" bson:"knockout"`
+	AcceptedAnswers []string           `json:"-" bson:"acceptedAnswers,omitempty"`
+	Min             *float64           `json:"-" bson:"min,omitempty"`
+	Max             *float64           `json:"-" bson:"max,omitempty"`
+	Requirement     string             `json:"requirement,omitempty" bson:"requirement,omitempty"`
+}
+
+// ScreeningQuestionDetail is a question together with its knockout
+// criteria, as the owner of the job sees it.
+type ScreeningQuestionDetail struct {
+	ScreeningQuestion
+	Knockout        bool     `json:"knockout"`
+	AcceptedAnswers []string `json:"acceptedAnswers,omitempty"`
+	Min             *float64 `json:"min,omitempty"`
+	Max             *float64 `json:"max,omitempty"`
+}
+
+// ScreeningQuestionView is what candidates see; it leaves out the knockout
+// criteria.
+type ScreeningQuestionView struct {
+	ID       primitive.ObjectID `json:"_id"`
+	Prompt   string             `json:"prompt"`
+	Type     string             `json:"type"`
+	Options  []string           `json:"options,omitempty"`
+	Required bool               `json:"required"`
+}
+
+type ScreeningAnswer struct {
+	QuestionID primitive.ObjectID `json:"questionId" bson:"questionId" validate:"required"`
+	Value      string             `json:"value" bson:"value"`
+}
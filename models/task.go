@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	TaskPending = "pending"
+	TaskRunning = "running"
+	TaskDone    = "done"
+	TaskDead    = "dead"
+)
+
+type TaskError struct {
+	At      time.Time `json:"at" bson:"at"`
+	Attempt int       `json:"attempt" bson:"attempt"`
+	Error   string    `json:"error" bson:"error"`
+}
+
+// Task is a unit of background work in the durable task queue. Tasks that
+// keep failing end up dead-lettered with status dead.
+type Task struct {
+	ID          primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	Type        string             `json:"type" bson:"type"`
+	Payload     bson.Raw           `json:"payload" bson:"payload"`
+	Status      string             `json:"status" bson:"status"`
+	Attempts    int                `json:"attempts" bson:"attempts"`
+	MaxAttempts int                `json:"maxAttempts" bson:"maxAttempts"`
+	RunAt       time.Time          `json:"runAt" bson:"runAt"`
+	LockedUntil time.Time          `json:"lockedUntil" bson:"lockedUntil"`
+	LockedBy    string             `json:"lockedBy,omitempty" bson:"lockedBy,omitempty"`
+	Errors      []TaskError        `json:"errors,omitempty" bson:"errors,omitempty"`
+	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
+	CompletedAt *time.Time         `json:"completedAt,omitempty" bson:"completedAt,omitempty"`
+}
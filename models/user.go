@@ -1,17 +1,31 @@
 package models
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type User struct {
-	ID       primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
-	Name     string             `json:"name" bson:"name" validate:"required,min=3"`
-	Email    string             `json:"email" bson:"email" validate:"email"`
-	Phone    string             `json:"phone" bson:"phone" validate:"phone"`
-	Address  string             `json:"address" bson:"address" validate:"address"`
-	Password string             `json:"password" bson:"password" validate:"required"`
-	Role     string             `json:"role" bson:"role" validate:"role"`
+	ID        primitive.ObjectID  `json:"_id,omitempty" bson:"_id,omitempty"`
+	Name      string              `json:"name" bson:"name" validate:"required,min=3"`
+	Email     string              `json:"email" bson:"email" validate:"email"`
+	Phone     string              `json:"phone" bson:"phone" validate:"phone"`
+	Address   string              `json:"address" bson:"address" validate:"address"`
+	Password  string              `json:"password" bson:"password" validate:"required"`
+	Role      string              `json:"role" bson:"role" validate:"role"`
+	DeletedAt *time.Time          `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+	DeletedBy *primitive.ObjectID `json:"deletedBy,omitempty" bson:"deletedBy,omitempty"`
+}
+
+// RegisterRequest is what a sign-up may set. It has no role, every account
+// registers as a candidate and roles are only given with jobly user.
+type RegisterRequest struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+	Address  string `json:"address"`
+	Password string `json:"password"`
 }
 
 type UserResponse struct {
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	EventJobCreated               = "job.created"
+	EventJobUpdated               = "job.updated"
+	EventJobDeleted               = "job.deleted"
+	EventJobRestored              = "job.restored"
+	EventApplicationSubmitted     = "application.submitted"
+	EventApplicationStatusChanged = "application.status_changed"
+	EventWebhookPing              = "webhook.ping"
+)
+
+// WebhookEvents are the event types endpoints can subscribe to.
+var WebhookEvents = []string{
+	EventJobCreated,
+	EventJobUpdated,
+	EventJobDeleted,
+	EventJobRestored,
+	EventApplicationSubmitted,
+	EventApplicationStatusChanged,
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is an endpoint of an organization that receives signed event
+// deliveries. The secret is only returned when it is created or rotated.
+type Webhook struct {
+	ID          primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	OwnerID     primitive.ObjectID `json:"ownerId" bson:"ownerId"`
+	URL         string             `json:"url" bson:"url" validate:"required"`
+	Events      []string           `json:"events" bson:"events" validate:"required"`
+	Description string             `json:"description,omitempty" bson:"description,omitempty"`
+	Secret      string             `json:"secret,omitempty" bson:"secret"`
+	Active      bool               `json:"active" bson:"active"`
+	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+type DeliveryAttempt struct {
+	At         time.Time `json:"at" bson:"at"`
+	StatusCode int       `json:"statusCode,omitempty" bson:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+	Response   string    `json:"response,omitempty" bson:"response,omitempty"`
+	DurationMs int64     `json:"durationMs" bson:"durationMs"`
+}
+
+// WebhookDelivery is one event sent to one webhook. Pending deliveries form
+// the retry queue worked off by the webhook dispatcher.
+type WebhookDelivery struct {
+	ID            primitive.ObjectID  `json:"_id,omitempty" bson:"_id,omitempty"`
+	WebhookID     primitive.ObjectID  `json:"webhookId" bson:"webhookId"`
+	OwnerID       primitive.ObjectID  `json:"ownerId" bson:"ownerId"`
+	EventID       primitive.ObjectID  `json:"eventId" bson:"eventId"`
+	Event         string              `json:"event" bson:"event"`
+	Payload       string              `json:"payload" bson:"payload"`
+	Status        string              `json:"status" bson:"status"`
+	AttemptCount  int                 `json:"attemptCount" bson:"attemptCount"`
+	Attempts      []DeliveryAttempt   `json:"attempts" bson:"attempts"`
+	NextAttemptAt time.Time           `json:"nextAttemptAt,omitempty" bson:"nextAttemptAt"`
+	LockedUntil   time.Time           `json:"-" bson:"lockedUntil"`
+	RedeliveryOf  *primitive.ObjectID `json:"redeliveryOf,omitempty" bson:"redeliveryOf,omitempty"`
+	CreatedAt     time.Time           `json:"createdAt" bson:"createdAt"`
+	CompletedAt   *time.Time          `json:"completedAt,omitempty" bson:"completedAt,omitempty"`
+}
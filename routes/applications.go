@@ -21,5 +21,23 @@ func ApplicationRoutes(router *gin.Engine) {
 		applicationGroup.POST("/", applicationHandler.CreateApplications)
 		applicationGroup.PUT("/admin/:id", applicationHandler.EditApplication)
 		applicationGroup.DELETE("/:id", applicationHandler.DeleteApplication)
+		applicationGroup.GET("/trash", applicationHandler.GetDeletedApplications)
+		applicationGroup.POST("/:id/restore", applicationHandler.RestoreApplication)
+		applicationGroup.GET("/:id/job", applicationHandler.GetAppliedJob)
+		applicationGroup.GET("/admin/:id/resume-text", applicationHandler.GetApplicationResumeText)
+		applicationGroup.POST("/admin/:id/analyze", applicationHandler.AnalyzeApplicationResume)
+		applicationGroup.POST("/admin/:id/reveal", applicationHandler.RevealApplicationIdentity)
+		applicationGroup.GET("/:id/notes", applicationHandler.GetNotes)
+		applicationGroup.POST("/:id/notes", applicationHandler.CreateNote)
+		applicationGroup.PUT("/:id/notes/:noteId", applicationHandler.UpdateNote)
+		applicationGroup.DELETE("/:id/notes/:noteId", applicationHandler.DeleteNote)
+		applicationGroup.GET("/:id/tags", applicationHandler.GetTags)
+		applicationGroup.PUT("/:id/tags", applicationHandler.SetTags)
+		applicationGroup.POST("/:id/tags", applicationHandler.SetTags)
+		applicationGroup.DELETE("/:id/tags/:tag", applicationHandler.DeleteTag)
+		applicationGroup.GET("/:id/scorecards", applicationHandler.GetScorecards)
+		applicationGroup.POST("/:id/scorecards", applicationHandler.CreateScorecard)
+		applicationGroup.PUT("/:id/scorecards/:scorecardId", applicationHandler.UpdateScorecard)
+		applicationGroup.DELETE("/:id/scorecards/:scorecardId", applicationHandler.DeleteScorecard)
 	}
 }
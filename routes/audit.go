@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/controllers"
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/middleware"
+)
+
+func AuditRoutes(router *gin.Engine) {
+	errorHandler := handler.NewErrorHandler()
+	auditHandler := controllers.NewAuditHandler(db.GetCollection("auditLog"), errorHandler)
+	auditGroup := router.Group("/api/v1/audit")
+	auditGroup.Use(middleware.Authentication())
+	{
+		auditGroup.GET("/", auditHandler.GetAuditLog)
+		auditGroup.GET("/verify", auditHandler.VerifyAuditLog)
+	}
+}
@@ -0,0 +1,23 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/controllers"
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+)
+
+func FeedRoutes(router *gin.Engine) {
+	errorHandler := handler.NewErrorHandler()
+	feedHandler := controllers.NewFeedHandler(db.GetCollection("jobs"), errorHandler)
+	feedGroup := router.Group("/api/v1/feeds")
+	{
+		feedGroup.GET("/rss", feedHandler.GetRSSFeed)
+		feedGroup.GET("/atom", feedHandler.GetAtomFeed)
+		feedGroup.GET("/jobs.xml", feedHandler.GetJobXMLFeed)
+		feedGroup.GET("/jobs/:id/jsonld", feedHandler.GetJobJSONLD)
+		feedGroup.GET("/companies/:company/rss", feedHandler.GetRSSFeed)
+		feedGroup.GET("/companies/:company/atom", feedHandler.GetAtomFeed)
+		feedGroup.GET("/companies/:company/jobs.xml", feedHandler.GetJobXMLFeed)
+	}
+}
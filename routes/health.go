@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/controllers"
+)
+
+func HealthRoutes(router *gin.Engine) {
+	router.GET("/healthz", controllers.Healthz)
+	router.GET("/readyz", controllers.Readyz)
+}
@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/controllers"
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/middleware"
+)
+
+func InterviewRoutes(router *gin.Engine) {
+	errorHandler := handler.NewErrorHandler()
+	interviewHandler := controllers.NewInterviewHandler(db.GetCollection("interviews"), errorHandler)
+	interviewGroup := router.Group("/api/v1/interviews")
+	interviewGroup.Use(middleware.Authentication())
+	{
+		interviewGroup.GET("/", interviewHandler.GetInterviews)
+		interviewGroup.POST("/", interviewHandler.CreateInterview)
+		interviewGroup.GET("/:id", interviewHandler.GetInterview)
+		interviewGroup.GET("/:id/ics", interviewHandler.GetInterviewICS)
+		interviewGroup.POST("/:id/pick", interviewHandler.PickSlot)
+		interviewGroup.POST("/:id/reschedule", interviewHandler.RescheduleInterview)
+		interviewGroup.POST("/:id/cancel", interviewHandler.CancelInterview)
+	}
+}
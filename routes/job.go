@@ -19,8 +19,19 @@ func JobRoutes(router *gin.Engine) {
 		jobGroup.GET("/:id", jobHandler.GetJobById)
 		jobGroup.GET("/admin", jobHandler.GetAdminsJobs)
 		jobGroup.POST("/create", jobHandler.CreateJob)
+		jobGroup.POST("/admin/import", jobHandler.ImportJobs)
 		jobGroup.PUT("/admin/:id", jobHandler.Updatejob)
 		jobGroup.DELETE("/admin/:id", jobHandler.DeleteJob)
+		jobGroup.GET("/admin/:id/delete-preview", jobHandler.PreviewDeleteJob)
+		jobGroup.GET("/admin/trash", jobHandler.GetDeletedJobs)
+		jobGroup.POST("/admin/:id/restore", jobHandler.RestoreJob)
+		jobGroup.GET("/:id/questions", jobHandler.GetScreeningQuestions)
+		jobGroup.PUT("/admin/:id/questions", jobHandler.SetScreeningQuestions)
+		jobGroup.PUT("/admin/:id/blind-review", jobHandler.SetBlindReview)
+		jobGroup.GET("/admin/:id/revisions", jobHandler.GetJobRevisions)
+		jobGroup.GET("/admin/:id/revisions/diff", jobHandler.DiffJobRevisions)
+		jobGroup.GET("/admin/:id/revisions/:version", jobHandler.GetJobRevision)
+		jobGroup.POST("/admin/:id/revisions/:version/restore", jobHandler.RestoreJobRevision)
 		jobGroup.GET("/jobs/search", jobHandler.SearchJobs)
 		jobGroup.GET("/search", jobHandler.SearchJobsAll)
 		jobGroup.GET("/admin/latest-jobs", jobHandler.GetAdminsLatestJobs)
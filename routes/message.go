@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/controllers"
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/middleware"
+)
+
+func MessageRoutes(router *gin.Engine) {
+	errorHandler := handler.NewErrorHandler()
+	messageHandler := controllers.NewMessageHandler(db.GetCollection("messages"), errorHandler)
+
+	messageGroup := router.Group("/api/v1/messages")
+	messageGroup.Use(middleware.Authentication())
+	{
+		messageGroup.GET("/threads", messageHandler.GetThreads)
+	}
+
+	threadGroup := router.Group("/api/v1/applications/:id/messages")
+	threadGroup.Use(middleware.Authentication())
+	{
+		threadGroup.GET("", messageHandler.GetMessages)
+		threadGroup.POST("", messageHandler.SendMessage)
+		threadGroup.POST("/read", messageHandler.MarkMessagesRead)
+		threadGroup.GET("/:messageId/attachments/:fileId", messageHandler.GetMessageAttachment)
+	}
+}
@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/controllers"
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/middleware"
+)
+
+func NotificationRoutes(router *gin.Engine) {
+	errorHandler := handler.NewErrorHandler()
+	notificationHandler := controllers.NewNotificationHandler(db.GetCollection("notifications"), errorHandler)
+	notificationGroup := router.Group("/api/v1/notifications")
+	notificationGroup.Use(middleware.Authentication())
+	{
+		notificationGroup.GET("/", notificationHandler.GetNotifications)
+		notificationGroup.GET("/unread-count", notificationHandler.GetUnreadCount)
+		notificationGroup.POST("/stream-ticket", notificationHandler.CreateStreamTicket)
+		notificationGroup.POST("/read", notificationHandler.MarkAllRead)
+		notificationGroup.POST("/:id/read", notificationHandler.SetNotificationRead)
+		notificationGroup.DELETE("/:id/read", notificationHandler.SetNotificationRead)
+		notificationGroup.DELETE("/:id", notificationHandler.DeleteNotification)
+	}
+	// EventSource cannot send the Authorization header, the stream takes a
+	// ticket from /stream-ticket instead
+	router.GET("/api/v1/notifications/stream", middleware.StreamAuthentication(), notificationHandler.StreamNotifications)
+
+	jobAlertHandler := controllers.NewJobAlertHandler(db.GetCollection("jobAlerts"), errorHandler)
+	jobAlertGroup := router.Group("/api/v1/alerts")
+	jobAlertGroup.Use(middleware.Authentication())
+	{
+		jobAlertGroup.GET("/", jobAlertHandler.GetJobAlerts)
+		jobAlertGroup.POST("/", jobAlertHandler.CreateJobAlert)
+		jobAlertGroup.PUT("/:id", jobAlertHandler.UpdateJobAlert)
+		jobAlertGroup.DELETE("/:id", jobAlertHandler.DeleteJobAlert)
+	}
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/controllers"
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/middleware"
+)
+
+func PrivacyRoutes(router *gin.Engine) {
+	errorHandler := handler.NewErrorHandler()
+	privacyHandler := controllers.NewPrivacyHandler(db.GetCollection("erasureRequests"), errorHandler)
+	privacyGroup := router.Group("/api/v1/privacy")
+	privacyGroup.Use(middleware.Authentication())
+	{
+		privacyGroup.GET("/export", privacyHandler.ExportData)
+		privacyGroup.GET("/erasure", privacyHandler.GetErasureRequest)
+		privacyGroup.POST("/erasure", privacyHandler.RequestErasure)
+		privacyGroup.DELETE("/erasure", privacyHandler.CancelErasure)
+	}
+}
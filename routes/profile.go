@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/controllers"
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/middleware"
+)
+
+func ProfileRoutes(router *gin.Engine) {
+	errorHandler := handler.NewErrorHandler()
+	profileHandler := controllers.NewProfileHandler(db.GetCollection("profiles"), errorHandler)
+	profileGroup := router.Group("/api/v1/profile")
+	profileGroup.Use(middleware.Authentication())
+	{
+		profileGroup.GET("/", profileHandler.GetProfile)
+		profileGroup.POST("/", profileHandler.CreateProfile)
+		profileGroup.PUT("/", profileHandler.UpdateProfile)
+		profileGroup.DELETE("/", profileHandler.DeleteProfile)
+		profileGroup.GET("/resume", profileHandler.GetDefaultResume)
+		profileGroup.PUT("/resume", profileHandler.UploadDefaultResume)
+	}
+}
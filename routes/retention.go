@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/controllers"
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/middleware"
+)
+
+func RetentionRoutes(router *gin.Engine) {
+	errorHandler := handler.NewErrorHandler()
+	retentionHandler := controllers.NewRetentionHandler(db.GetCollection("retentionPolicies"), errorHandler)
+	retentionGroup := router.Group("/api/v1/retention")
+	retentionGroup.Use(middleware.Authentication())
+	{
+		retentionGroup.GET("/policies", retentionHandler.GetRetentionPolicies)
+		retentionGroup.POST("/policies", retentionHandler.CreateRetentionPolicy)
+		retentionGroup.PUT("/policies/:id", retentionHandler.UpdateRetentionPolicy)
+		retentionGroup.DELETE("/policies/:id", retentionHandler.DeleteRetentionPolicy)
+		retentionGroup.GET("/preview", retentionHandler.PreviewRetention)
+		retentionGroup.GET("/reports", retentionHandler.GetRetentionReports)
+	}
+}
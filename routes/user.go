@@ -15,12 +15,16 @@ func SetUpUsers(router *gin.Engine) {
 		users.POST("/register", controllers.Register)
 		// logout users
 		users.POST("/logout", controllers.Logout)
+		// restore a deleted account
+		users.POST("/restore", controllers.RestoreAccount)
 
 		// Apply middleware to all subsequent routes within the users group
 		users.Use(middleware.Authentication())
 		{
 			// update user settings
 			users.PUT("/settings", controllers.Settings)
+			// delete the account
+			users.DELETE("/account", controllers.DeleteAccount)
 		}
 	}
 }
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/weldonkipchirchir/job-listing-server/controllers"
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/handler"
+	"github.com/weldonkipchirchir/job-listing-server/middleware"
+)
+
+func WebhookRoutes(router *gin.Engine) {
+	errorHandler := handler.NewErrorHandler()
+	webhookHandler := controllers.NewWebhookHandler(db.GetCollection("webhooks"), errorHandler)
+	webhookGroup := router.Group("/api/v1/webhooks")
+	webhookGroup.Use(middleware.Authentication())
+	{
+		webhookGroup.GET("/", webhookHandler.GetWebhooks)
+		webhookGroup.POST("/", webhookHandler.CreateWebhook)
+		webhookGroup.GET("/:id", webhookHandler.GetWebhook)
+		webhookGroup.PUT("/:id", webhookHandler.UpdateWebhook)
+		webhookGroup.DELETE("/:id", webhookHandler.DeleteWebhook)
+		webhookGroup.POST("/:id/rotate-secret", webhookHandler.RotateWebhookSecret)
+		webhookGroup.POST("/:id/ping", webhookHandler.PingWebhook)
+		webhookGroup.GET("/:id/deliveries", webhookHandler.GetWebhookDeliveries)
+		webhookGroup.GET("/:id/deliveries/:deliveryId", webhookHandler.GetWebhookDelivery)
+		webhookGroup.POST("/:id/deliveries/:deliveryId/redeliver", webhookHandler.RedeliverWebhook)
+	}
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const auditInsertAttempts = 5
+
+// auditRedacted lists fields never written to the audit log.
+var auditRedacted = []string{"password", "resumeText"}
+
+// AuditMeta describes who made a change and from where.
+type AuditMeta struct {
+	Actor     models.AuditActor
+	IP        string
+	RequestID string
+}
+
+// AuditVerification is the result of checking the audit hash chain.
+type AuditVerification struct {
+	Valid   bool   `json:"valid"`
+	Checked int64  `json:"checked"`
+	BadSeq  int64  `json:"badSeq,omitempty"`
+	Problem string `json:"problem,omitempty"`
+}
+
+var (
+	auditIndexMu sync.Mutex
+	auditIndexed bool
+)
+
+func auditLog() *mongo.Collection {
+	return db.DB.Collection("auditLog")
+}
+
+// ensureAuditIndex makes seq unique, which serializes concurrent writers
+// onto a single chain.
+func ensureAuditIndex(ctx context.Context) error {
+	auditIndexMu.Lock()
+	defer auditIndexMu.Unlock()
+	if auditIndexed {
+		return nil
+	}
+	_, err := auditLog().Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "seq", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	auditIndexed = err == nil
+	return err
+}
+
+// auditValue stores a before or after value as a document with the
+// redacted fields removed. The raw bytes are what gets hashed, so the
+// value is only ever marshalled once.
+func auditValue(v interface{}) (bson.Raw, error) {
+	if v == nil {
+		return nil, nil
+	}
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	kept := doc[:0]
+	for _, element := range doc {
+		redacted := false
+		for _, key := range auditRedacted {
+			if element.Key == key {
+				redacted = true
+			}
+		}
+		if !redacted {
+			kept = append(kept, element)
+		}
+	}
+	return bson.Marshal(kept)
+}
+
+// AuditHash computes the chained hash of an entry. It covers the digests
+// of the personal values, see SealAuditEntry.
+func AuditHash(entry models.AuditEntry) string {
+	fields := []string{
+		entry.PrevHash,
+		strconv.FormatInt(entry.Seq, 10),
+		strconv.FormatInt(entry.CreatedAt.UnixMilli(), 10),
+		entry.Actor.ID.Hex(),
+		entry.Actor.Role,
+		entry.Action,
+		entry.TargetType,
+		entry.TargetID.Hex(),
+		entry.BeforeDigest,
+		entry.AfterDigest,
+		entry.ActorDigest,
+		entry.RequestID,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func auditDigest(value []byte) string {
+	if len(value) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])
+}
+
+func auditActorDigest(entry models.AuditEntry) string {
+	return auditDigest([]byte(entry.Actor.Email + "\n" + entry.IP))
+}
+
+// SealAuditEntry sets the digests of the personal values of an entry and
+// then its hash. PrevHash and Seq must be set already.
+func SealAuditEntry(entry *models.AuditEntry) {
+	entry.BeforeDigest = auditDigest(entry.Before)
+	entry.AfterDigest = auditDigest(entry.After)
+	entry.ActorDigest = auditActorDigest(*entry)
+	entry.Hash = AuditHash(*entry)
+}
+
+// auditValuesIntact reports whether the personal values still stored in an
+// entry match their digests. Erased values are missing and not checked.
+func auditValuesIntact(entry models.AuditEntry) bool {
+	if len(entry.Before) > 0 && auditDigest(entry.Before) != entry.BeforeDigest {
+		return false
+	}
+	if len(entry.After) > 0 && auditDigest(entry.After) != entry.AfterDigest {
+		return false
+	}
+	if entry.Actor.Email != "" || entry.IP != "" {
+		return auditActorDigest(entry) == entry.ActorDigest
+	}
+	return true
+}
+
+// RecordAudit appends an entry to the audit log. before and after hold the
+// changed values, either may be nil.
+func RecordAudit(ctx context.Context, meta AuditMeta, action, targetType string, targetID primitive.ObjectID, before, after interface{}) error {
+	if err := ensureAuditIndex(ctx); err != nil {
+		return err
+	}
+
+	entry := models.AuditEntry{
+		Actor:      meta.Actor,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IP:         meta.IP,
+		RequestID:  meta.RequestID,
+	}
+	var err error
+	if entry.Before, err = auditValue(before); err != nil {
+		return err
+	}
+	if entry.After, err = auditValue(after); err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < auditInsertAttempts; attempt++ {
+		var last models.AuditEntry
+		opts := options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}})
+		err := auditLog().FindOne(ctx, bson.M{}, opts).Decode(&last)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		entry.ID = primitive.NewObjectID()
+		entry.Seq = last.Seq + 1
+		entry.PrevHash = last.Hash
+		// Mongo keeps milliseconds, the hash must survive the round trip
+		entry.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+		SealAuditEntry(&entry)
+
+		_, err = auditLog().InsertOne(ctx, entry)
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+		// another writer took this seq, chain onto its entry instead
+	}
+	return errors.New("audit log is busy, entry not written")
+}
+
+// VerifyAuditChain walks the audit log in order and checks that every
+// entry follows on from the previous one and still matches its hash.
+func VerifyAuditChain(ctx context.Context) (AuditVerification, error) {
+	result := AuditVerification{Valid: true}
+
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}})
+	cursor, err := auditLog().Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return result, err
+	}
+	defer cursor.Close(ctx)
+
+	previous := models.AuditEntry{}
+	for cursor.Next(ctx) {
+		var entry models.AuditEntry
+		if err := cursor.Decode(&entry); err != nil {
+			return result, err
+		}
+		switch {
+		case entry.Seq != previous.Seq+1:
+			result.Problem = "entry missing before this one"
+		case entry.PrevHash != previous.Hash:
+			result.Problem = "previous hash does not match"
+		case entry.Hash != AuditHash(entry) || !auditValuesIntact(entry):
+			result.Problem = "entry was modified"
+		}
+		if result.Problem != "" {
+			result.Valid = false
+			result.BadSeq = entry.Seq
+			return result, nil
+		}
+		result.Checked++
+		previous = entry
+	}
+	return result, cursor.Err()
+}
+
+// AuditScope restricts the audit log to what a recruiter may see: their own
+// actions and account, their jobs and the applications to them. Operators
+// see the whole log.
+func AuditScope(ctx context.Context, adminID primitive.ObjectID) (bson.M, error) {
+	// trashed jobs and applications keep their history
+	jobIDs, err := db.DB.Collection("jobs").Distinct(ctx, "_id", bson.M{"userId": adminID})
+	if err != nil {
+		return nil, err
+	}
+	applicationIDs, err := db.DB.Collection("applications").Distinct(ctx, "_id", bson.M{"jobId": bson.M{"$in": jobIDs}})
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{"actor.id": adminID},
+		bson.M{"targetType": models.AuditTargetUser, "targetId": adminID},
+		bson.M{"targetType": models.AuditTargetJob, "targetId": bson.M{"$in": jobIDs}},
+		bson.M{"targetType": models.AuditTargetApplication, "targetId": bson.M{"$in": applicationIDs}},
+	}}, nil
+}
+
+// AuditValueDoc decodes a stored before or after value for display.
+func AuditValueDoc(raw bson.Raw) bson.M {
+	if len(raw) == 0 {
+		return nil
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil
+	}
+	return doc
+}
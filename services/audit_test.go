@@ -0,0 +1,153 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAuditHash(t *testing.T) {
+	entry := models.AuditEntry{
+		Seq:        7,
+		PrevHash:   "previous",
+		CreatedAt:  time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Actor:      models.AuditActor{ID: primitive.NewObjectID(), Role: "admin"},
+		Action:     models.AuditApplicationUpdate,
+		TargetType: models.AuditTargetApplication,
+		TargetID:   primitive.NewObjectID(),
+		RequestID:  "req-1",
+	}
+	hash := AuditHash(entry)
+	if hash == "" || AuditHash(entry) != hash {
+		t.Fatalf("AuditHash() = %q is not stable", hash)
+	}
+
+	changed := entry
+	changed.Action = models.AuditApplicationDelete
+	if AuditHash(changed) == hash {
+		t.Error("hash does not cover the action")
+	}
+	changed = entry
+	changed.PrevHash = "other"
+	if AuditHash(changed) == hash {
+		t.Error("hash does not cover the previous hash")
+	}
+	changed = entry
+	changed.TargetID = primitive.NewObjectID()
+	if AuditHash(changed) == hash {
+		t.Error("hash does not cover the target")
+	}
+	changed = entry
+	changed.Seq++
+	if AuditHash(changed) == hash {
+		t.Error("hash does not cover the sequence number")
+	}
+}
+
+func sealedEntry(t *testing.T) models.AuditEntry {
+	t.Helper()
+	before, err := auditValue(bson.M{"name": "Jane Doe", "email": "jane@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := auditValue(bson.M{"name": "Jane Roe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := models.AuditEntry{
+		Seq:        7,
+		PrevHash:   "previous",
+		CreatedAt:  time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Actor:      models.AuditActor{ID: primitive.NewObjectID(), Email: "jane@example.com", Role: "user"},
+		Action:     models.AuditApplicationUpdate,
+		TargetType: models.AuditTargetApplication,
+		TargetID:   primitive.NewObjectID(),
+		Before:     before,
+		After:      after,
+		IP:         "203.0.113.9",
+		RequestID:  "req-1",
+	}
+	SealAuditEntry(&entry)
+	return entry
+}
+
+func TestSealAuditEntry(t *testing.T) {
+	entry := sealedEntry(t)
+	if entry.Hash == "" || entry.BeforeDigest == "" || entry.AfterDigest == "" || entry.ActorDigest == "" {
+		t.Fatalf("SealAuditEntry() left fields empty: %+v", entry)
+	}
+	if entry.Hash != AuditHash(entry) {
+		t.Error("hash does not verify")
+	}
+	if !auditValuesIntact(entry) {
+		t.Error("values do not match their digests")
+	}
+}
+
+func TestAuditEntryErasure(t *testing.T) {
+	entry := sealedEntry(t)
+
+	// what an erasure removes
+	erased := entry
+	erased.Before = nil
+	erased.After = nil
+	erased.Actor.Email = ""
+	erased.IP = ""
+	if AuditHash(erased) != entry.Hash {
+		t.Error("erasing the personal values breaks the hash")
+	}
+	if !auditValuesIntact(erased) {
+		t.Error("erased entry is reported as modified")
+	}
+}
+
+func TestAuditEntryTampering(t *testing.T) {
+	entry := sealedEntry(t)
+	other, err := auditValue(bson.M{"name": "Someone Else"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := entry
+	tampered.After = other
+	if auditValuesIntact(tampered) {
+		t.Error("replaced value is not detected")
+	}
+
+	tampered = entry
+	tampered.Actor.Email = "someone@example.com"
+	if auditValuesIntact(tampered) {
+		t.Error("replaced actor email is not detected")
+	}
+
+	tampered = entry
+	tampered.Actor.Email = ""
+	tampered.IP = "198.51.100.1"
+	if auditValuesIntact(tampered) {
+		t.Error("replaced ip is not detected")
+	}
+}
+
+func TestAuditValueRedacts(t *testing.T) {
+	raw, err := auditValue(bson.M{"name": "Jane", "password": "hash", "resumeText": "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := AuditValueDoc(raw)
+	if _, ok := doc["password"]; ok {
+		t.Error("password is stored")
+	}
+	if _, ok := doc["resumeText"]; ok {
+		t.Error("resume text is stored")
+	}
+	if doc["name"] != "Jane" {
+		t.Errorf("name = %v, want Jane", doc["name"])
+	}
+
+	if raw, err := auditValue(nil); err != nil || raw != nil {
+		t.Errorf("auditValue(nil) = %v, %v", raw, err)
+	}
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	redactedEmail = "[email]"
+	redactedPhone = "[phone]"
+	redactedLink  = "[link]"
+	redactedName  = "[name]"
+	// minPhoneDigits keeps year ranges like 2019 - 2021 out of the phone
+	// number redaction
+	minPhoneDigits = 9
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	linkPattern  = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+|\b(?:linkedin\.com|github\.com)/\S*`)
+	phonePattern = regexp.MustCompile(`\+?\d[\d\s().\-]{7,}\d`)
+)
+
+// ValidateBlindReview lists what is wrong with a blind review setting.
+func ValidateBlindReview(blindReview *models.BlindReview) []string {
+	problems := []string{}
+	if blindReview != nil && blindReview.Enabled && strings.TrimSpace(blindReview.RevealStatus) == "" {
+		problems = append(problems, "revealStatus is required when blind review is enabled")
+	}
+	return problems
+}
+
+// IdentityHidden reports whether the recruiters of job may not see who made
+// the application yet. Moving an application to or on from the reveal
+// status records the reveal, see RevealsIdentity, so the status alone only
+// matters for applications that were already there when it was configured.
+func IdentityHidden(job *models.Job, application models.Application) bool {
+	if job == nil || job.BlindReview == nil || !job.BlindReview.Enabled {
+		return false
+	}
+	if application.IdentityRevealedAt != nil {
+		return false
+	}
+	reveal := job.BlindReview.RevealStatus
+	if application.Status == models.StatusClosed && application.StatusBeforeClose != "" {
+		return application.StatusBeforeClose != reveal
+	}
+	return application.Status != reveal
+}
+
+// RevealsIdentity reports whether moving an application to status reveals
+// its candidate for good: it reaches the reveal status, or leaves it.
+// Statuses are free text without an order, so leaving the reveal status is
+// the only way to tell an application got past it.
+func RevealsIdentity(job *models.Job, application models.Application, status string) bool {
+	if job == nil || job.BlindReview == nil || !job.BlindReview.Enabled || application.IdentityRevealedAt != nil {
+		return false
+	}
+	reveal := job.BlindReview.RevealStatus
+	return status == reveal || application.Status == reveal
+}
+
+// RevealAtStatus reveals the candidates of the job's applications that are
+// at the reveal status of its blind review, or were when the job closed
+// them, and returns their ids. They are visible already; recording the
+// reveal keeps them visible once they move on.
+func RevealAtStatus(ctx context.Context, job *models.Job, actorID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	if job.BlindReview == nil || !job.BlindReview.Enabled {
+		return nil, nil
+	}
+	reveal := job.BlindReview.RevealStatus
+	filter := bson.M{
+		"jobId":              job.ID,
+		"identityRevealedAt": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"status": reveal},
+			bson.M{"status": models.StatusClosed, "statusBeforeClose": reveal},
+		},
+	}
+	applications := db.DB.Collection("applications")
+	values, err := applications.Distinct(ctx, "_id", filter)
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	filter["_id"] = bson.M{"$in": ids}
+	now := time.Now().UTC()
+	if _, err := applications.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"identityRevealedAt": now, "identityRevealedBy": actorID}}); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// HiddenApplications returns the applications among ids whose candidate
+// recruiters may not see, with just enough loaded to mask them.
+func HiddenApplications(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]models.Application, error) {
+	hidden := map[primitive.ObjectID]models.Application{}
+	if len(ids) == 0 {
+		return hidden, nil
+	}
+	opts := options.Find().SetProjection(bson.M{
+		"jobId": 1, "userId": 1, "name": 1, "email": 1,
+		"status": 1, "statusBeforeClose": 1, "identityRevealedAt": 1,
+	})
+	cursor, err := db.DB.Collection("applications").Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var applications []models.Application
+	if err := cursor.All(ctx, &applications); err != nil {
+		return nil, err
+	}
+
+	jobIDs := make([]primitive.ObjectID, 0, len(applications))
+	for _, application := range applications {
+		jobIDs = append(jobIDs, application.JobID)
+	}
+	cursor, err = db.DB.Collection("jobs").Find(ctx, bson.M{"_id": bson.M{"$in": jobIDs}, "blindReview.enabled": true},
+		options.Find().SetProjection(bson.M{"blindReview": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var jobs []models.Job
+	if err := cursor.All(ctx, &jobs); err != nil {
+		return nil, err
+	}
+	jobsByID := map[primitive.ObjectID]*models.Job{}
+	for i := range jobs {
+		jobsByID[jobs[i].ID] = &jobs[i]
+	}
+
+	for _, application := range applications {
+		if IdentityHidden(jobsByID[application.JobID], application) {
+			hidden[application.ID] = application
+		}
+	}
+	return hidden, nil
+}
+
+// CandidateAlias is the name shown for a candidate under blind review. It
+// is stable for an application so recruiters can tell candidates apart.
+func CandidateAlias(applicationID primitive.ObjectID) string {
+	hex := applicationID.Hex()
+	return "Candidate " + strings.ToUpper(hex[len(hex)-6:])
+}
+
+// MaskApplication returns a copy of application with the candidate's
+// identity removed: name, email and account id, the resume file and its
+// name, profile links, and PII in the resume text and profile summary.
+func MaskApplication(application models.Application) models.Application {
+	names := identityTerms(application)
+
+	masked := application
+	masked.Name = CandidateAlias(application.ID)
+	masked.Email = ""
+	masked.UserID = primitive.NilObjectID
+	masked.Resume = models.PDF{
+		Filename:    "resume" + path.Ext(application.Resume.Filename),
+		ContentType: application.Resume.ContentType,
+	}
+	masked.ResumeText = RedactPII(application.ResumeText, names...)
+	if application.ProfileSnapshot != nil {
+		snapshot := *application.ProfileSnapshot
+		snapshot.Links = nil
+		snapshot.Headline = RedactPII(snapshot.Headline, names...)
+		snapshot.Summary = RedactPII(snapshot.Summary, names...)
+		masked.ProfileSnapshot = &snapshot
+	}
+	masked.Blind = true
+	return masked
+}
+
+// MaskMessage returns a copy of a candidate's message in a blind thread
+// with their name replaced by the alias, PII in the body redacted and the
+// attachment names dropped. The recruiter's own messages are left alone.
+func MaskMessage(application models.Application, message models.Message) models.Message {
+	if message.SenderID != message.CandidateID {
+		return message
+	}
+	masked := message
+	masked.SenderName = CandidateAlias(application.ID)
+	masked.Body = RedactPII(message.Body, identityTerms(application)...)
+	masked.Attachments = make([]models.MessageAttachment, len(message.Attachments))
+	for i, attachment := range message.Attachments {
+		attachment.Filename = "attachment" + path.Ext(attachment.Filename)
+		masked.Attachments[i] = attachment
+	}
+	return masked
+}
+
+// MaskInterview returns a copy of interview as its interviewers see it
+// under blind review, with the candidate named by alias and no contact.
+func MaskInterview(interview models.Interview) models.Interview {
+	masked := interview
+	masked.CandidateID = primitive.NilObjectID
+	masked.CandidateName = CandidateAlias(interview.ApplicationID)
+	masked.CandidateEmail = ""
+	return masked
+}
+
+// identityTerms collects the words that identify the candidate of an
+// application: the parts of their name and of their email address.
+func identityTerms(application models.Application) []string {
+	terms := strings.Fields(application.Name)
+	if local, _, ok := strings.Cut(application.Email, "@"); ok {
+		terms = append(terms, local)
+	}
+	return terms
+}
+
+// RedactPII replaces email addresses, phone numbers, links and the given
+// names in text with placeholders.
+func RedactPII(text string, names ...string) string {
+	if text == "" {
+		return text
+	}
+	text = emailPattern.ReplaceAllString(text, redactedEmail)
+	text = linkPattern.ReplaceAllString(text, redactedLink)
+	text = phonePattern.ReplaceAllStringFunc(text, func(match string) string {
+		digits := 0
+		for _, r := range match {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		if digits < minPhoneDigits {
+			return match
+		}
+		return redactedPhone
+	})
+	for _, name := range names {
+		// initials and short fragments would redact ordinary words
+		if len([]rune(name)) < 3 {
+			continue
+		}
+		pattern := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(name) + `\b`)
+		text = pattern.ReplaceAllString(text, redactedName)
+	}
+	return text
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func blindJob() *models.Job {
+	return &models.Job{BlindReview: &models.BlindReview{Enabled: true, RevealStatus: "Interview"}}
+}
+
+func TestIdentityHidden(t *testing.T) {
+	revealedAt := time.Now()
+	tests := []struct {
+		name        string
+		job         *models.Job
+		application models.Application
+		want        bool
+	}{
+		{"no job", nil, models.Application{Status: "Pending"}, false},
+		{"blind review off", &models.Job{BlindReview: &models.BlindReview{RevealStatus: "Interview"}}, models.Application{Status: "Pending"}, false},
+		{"before reveal status", blindJob(), models.Application{Status: "Pending"}, true},
+		{"at reveal status", blindJob(), models.Application{Status: "Interview"}, false},
+		{"past reveal status", blindJob(), models.Application{Status: "Offer", IdentityRevealedAt: &revealedAt}, false},
+		{"revealed by hand", blindJob(), models.Application{Status: "Pending", IdentityRevealedAt: &revealedAt}, false},
+		{"closed at reveal status", blindJob(), models.Application{Status: models.StatusClosed, StatusBeforeClose: "Interview"}, false},
+		{"closed before reveal status", blindJob(), models.Application{Status: models.StatusClosed, StatusBeforeClose: "Pending"}, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := IdentityHidden(test.job, test.application); got != test.want {
+				t.Errorf("IdentityHidden() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRevealsIdentity(t *testing.T) {
+	revealedAt := time.Now()
+	tests := []struct {
+		name        string
+		application models.Application
+		status      string
+		want        bool
+	}{
+		{"reaches reveal status", models.Application{Status: "Pending"}, "Interview", true},
+		{"leaves reveal status", models.Application{Status: "Interview"}, "Offer", true},
+		{"before reveal status", models.Application{Status: "Pending"}, "Screening", false},
+		{"already revealed", models.Application{Status: "Pending", IdentityRevealedAt: &revealedAt}, "Interview", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := RevealsIdentity(blindJob(), test.application, test.status); got != test.want {
+				t.Errorf("RevealsIdentity() = %v, want %v", got, test.want)
+			}
+		})
+	}
+	if RevealsIdentity(&models.Job{}, models.Application{Status: "Pending"}, "Interview") {
+		t.Error("RevealsIdentity() reveals without blind review")
+	}
+}
+
+func TestMaskApplication(t *testing.T) {
+	application := models.Application{
+		ID:         primitive.NewObjectID(),
+		UserID:     primitive.NewObjectID(),
+		Name:       "Jane Doe",
+		Email:      "jdoe@example.com",
+		Resume:     models.PDF{Filename: "Jane_Doe_CV.pdf", ContentType: "application/pdf", Data: []byte("file")},
+		ResumeText: "Jane Doe, jdoe@example.com, +1 555 010 9999, github.com/jdoe. Worked 2019 - 2021.",
+	}
+	masked := MaskApplication(application)
+
+	if masked.Name != CandidateAlias(application.ID) || masked.Email != "" || !masked.UserID.IsZero() {
+		t.Errorf("identity not masked: %q %q %v", masked.Name, masked.Email, masked.UserID)
+	}
+	if masked.Resume.Filename != "resume.pdf" || masked.Resume.Data != nil {
+		t.Errorf("resume not masked: %+v", masked.Resume)
+	}
+	for _, leak := range []string{"Jane", "Doe", "jdoe", "555", "github"} {
+		if strings.Contains(masked.ResumeText, leak) {
+			t.Errorf("resume text %q still contains %q", masked.ResumeText, leak)
+		}
+	}
+	if !strings.Contains(masked.ResumeText, "2019 - 2021") {
+		t.Errorf("resume text %q lost a year range", masked.ResumeText)
+	}
+	if !masked.Blind {
+		t.Error("masked application is not marked blind")
+	}
+}
+
+func TestMaskMessage(t *testing.T) {
+	application := models.Application{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), Name: "Jane Doe", Email: "jane@example.com"}
+	recruiter := primitive.NewObjectID()
+	fromCandidate := models.Message{
+		SenderID:    application.UserID,
+		CandidateID: application.UserID,
+		SenderName:  "Jane Doe",
+		Body:        "Thanks, Jane here. Reach me at jane@example.com",
+		Attachments: []models.MessageAttachment{{Filename: "Jane Doe portfolio.pdf"}},
+	}
+	masked := MaskMessage(application, fromCandidate)
+	if masked.SenderName != CandidateAlias(application.ID) {
+		t.Errorf("sender name = %q", masked.SenderName)
+	}
+	if strings.Contains(masked.Body, "Jane") || strings.Contains(masked.Body, "example.com") {
+		t.Errorf("body = %q still names the candidate", masked.Body)
+	}
+	if masked.Attachments[0].Filename != "attachment.pdf" {
+		t.Errorf("attachment name = %q", masked.Attachments[0].Filename)
+	}
+	if fromCandidate.Attachments[0].Filename != "Jane Doe portfolio.pdf" {
+		t.Error("MaskMessage changed the original attachments")
+	}
+
+	fromRecruiter := models.Message{SenderID: recruiter, CandidateID: application.UserID, SenderName: "Sam Recruiter", Body: "Hi Jane"}
+	if got := MaskMessage(application, fromRecruiter); got.SenderName != "Sam Recruiter" || got.Body != "Hi Jane" {
+		t.Errorf("recruiter message was masked: %+v", got)
+	}
+}
+
+func TestMaskInterview(t *testing.T) {
+	interview := models.Interview{ApplicationID: primitive.NewObjectID(), CandidateID: primitive.NewObjectID(), CandidateName: "Jane Doe", CandidateEmail: "jane@example.com"}
+	masked := MaskInterview(interview)
+	if masked.CandidateName != CandidateAlias(interview.ApplicationID) || masked.CandidateEmail != "" || !masked.CandidateID.IsZero() {
+		t.Errorf("MaskInterview() = %+v", masked)
+	}
+}
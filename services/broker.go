@@ -0,0 +1,102 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Broker fans notifications out to the streams a user has open. The
+// in-process MemoryBroker only reaches streams served by the same instance;
+// a distributed broker can be swapped in with SetBroker.
+type Broker interface {
+	Publish(userID primitive.ObjectID, notification models.Notification)
+	// Subscribe returns the notifications for userID and a function that
+	// ends the subscription.
+	Subscribe(userID primitive.ObjectID) (<-chan models.Notification, func())
+}
+
+var (
+	brokerMu sync.RWMutex
+	broker   Broker = NewMemoryBroker()
+)
+
+// SetBroker replaces the broker notifications are published to.
+func SetBroker(b Broker) {
+	brokerMu.Lock()
+	defer brokerMu.Unlock()
+	broker = b
+}
+
+// GetBroker returns the broker notifications are published to.
+func GetBroker() Broker {
+	brokerMu.RLock()
+	defer brokerMu.RUnlock()
+	return broker
+}
+
+var (
+	streamsClosed    = make(chan struct{})
+	closeStreamsOnce sync.Once
+)
+
+// CloseStreams ends every open notification stream. The server shutdown
+// waits for running requests and a stream only ends with its client, so
+// it is called when the server shuts down.
+func CloseStreams() {
+	closeStreamsOnce.Do(func() { close(streamsClosed) })
+}
+
+// StreamsClosed is closed once the notification streams have to end.
+func StreamsClosed() <-chan struct{} {
+	return streamsClosed
+}
+
+const subscriberBuffer = 16
+
+type MemoryBroker struct {
+	mu          sync.Mutex
+	subscribers map[primitive.ObjectID]map[chan models.Notification]struct{}
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{subscribers: map[primitive.ObjectID]map[chan models.Notification]struct{}{}}
+}
+
+// Publish delivers to every subscriber of the user. Subscribers that fall
+// behind miss events; they still find them in the notifications collection.
+func (b *MemoryBroker) Publish(userID primitive.ObjectID, notification models.Notification) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers[userID] {
+		select {
+		case ch <- notification:
+		default:
+		}
+	}
+}
+
+func (b *MemoryBroker) Subscribe(userID primitive.ObjectID) (<-chan models.Notification, func()) {
+	ch := make(chan models.Notification, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = map[chan models.Notification]struct{}{}
+	}
+	b.subscribers[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subscribers[userID], ch)
+			if len(b.subscribers[userID]) == 0 {
+				delete(b.subscribers, userID)
+			}
+			close(ch)
+		})
+	}
+}
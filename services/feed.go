@@ -0,0 +1,388 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/models"
+)
+
+// FeedBaseURL is the public site the feeds link back to.
+func FeedBaseURL() string {
+	if base := os.Getenv("PUBLIC_BASE_URL"); base != "" {
+		return strings.TrimRight(base, "/")
+	}
+	return "http://localhost:5173"
+}
+
+// JobURL is the public page of a job posting.
+func JobURL(job models.Job) string {
+	return FeedBaseURL() + "/jobs/" + job.ID.Hex()
+}
+
+// RSS 2.0
+
+type RSS struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel RSSChannel `xml:"channel"`
+}
+
+type RSSChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	SelfLink      AtomLink  `xml:"atom:link"`
+	Items         []RSSItem `xml:"item"`
+}
+
+type RSSItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        RSSGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Description string   `xml:"description"`
+	Author      string   `xml:"author,omitempty"`
+	Categories  []string `xml:"category"`
+}
+
+type RSSGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// Atom 1.0
+
+type AtomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []AtomLink  `xml:"link"`
+	Entries []AtomEntry `xml:"entry"`
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type AtomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Link       AtomLink       `xml:"link"`
+	Author     AtomPerson     `xml:"author"`
+	Summary    AtomText       `xml:"summary"`
+	Categories []AtomCategory `xml:"category"`
+}
+
+type AtomPerson struct {
+	Name string `xml:"name"`
+}
+
+type AtomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+type AtomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// Aggregator job feed, in the <source><job> layout used by Indeed and most
+// other job boards.
+
+type JobFeed struct {
+	XMLName       xml.Name     `xml:"source"`
+	Publisher     string       `xml:"publisher"`
+	PublisherURL  string       `xml:"publisherurl"`
+	LastBuildDate string       `xml:"lastBuildDate"`
+	Jobs          []JobFeedJob `xml:"job"`
+}
+
+type JobFeedJob struct {
+	Title           CData  `xml:"title"`
+	Date            CData  `xml:"date"`
+	ReferenceNumber CData  `xml:"referencenumber"`
+	URL             CData  `xml:"url"`
+	Company         CData  `xml:"company"`
+	City            CData  `xml:"city"`
+	Country         CData  `xml:"country"`
+	Description     CData  `xml:"description"`
+	Salary          CData  `xml:"salary"`
+	JobType         CData  `xml:"jobtype"`
+	Category        CData  `xml:"category"`
+	Requirements    CData  `xml:"requirements"`
+	Sponsored       string `xml:"sponsored,omitempty"`
+}
+
+type CData struct {
+	Value string `xml:",cdata"`
+}
+
+// BuildRSS renders jobs as an RSS 2.0 channel.
+func BuildRSS(title, selfURL string, jobs []models.Job) RSS {
+	channel := RSSChannel{
+		Title:         title,
+		Link:          FeedBaseURL(),
+		Description:   "Latest job postings on Jobly",
+		LastBuildDate: time.Now().UTC().Format(time.RFC1123Z),
+		SelfLink:      AtomLink{Href: selfURL, Rel: "self", Type: "application/rss+xml"},
+		Items:         []RSSItem{},
+	}
+
+	for _, job := range jobs {
+		channel.Items = append(channel.Items, RSSItem{
+			Title:       fmt.Sprintf("%s at %s", job.JobName, job.Company),
+			Link:        JobURL(job),
+			GUID:        RSSGUID{IsPermaLink: true, Value: JobURL(job)},
+			PubDate:     job.ID.Timestamp().UTC().Format(time.RFC1123Z),
+			Description: jobSummary(job),
+			Categories:  jobCategories(job),
+		})
+	}
+
+	return RSS{Version: "2.0", Atom: "http://www.w3.org/2005/Atom", Channel: channel}
+}
+
+// BuildAtom renders jobs as an Atom 1.0 feed.
+func BuildAtom(title, selfURL string, jobs []models.Job) AtomFeed {
+	feed := AtomFeed{
+		ID:      selfURL,
+		Title:   title,
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Links: []AtomLink{
+			{Href: selfURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: FeedBaseURL(), Rel: "alternate", Type: "text/html"},
+		},
+		Entries: []AtomEntry{},
+	}
+
+	for _, job := range jobs {
+		var categories []AtomCategory
+		for _, term := range jobCategories(job) {
+			categories = append(categories, AtomCategory{Term: term})
+		}
+		posted := job.ID.Timestamp().UTC().Format(time.RFC3339)
+		feed.Entries = append(feed.Entries, AtomEntry{
+			ID:         "urn:jobly:job:" + job.ID.Hex(),
+			Title:      fmt.Sprintf("%s at %s", job.JobName, job.Company),
+			Updated:    posted,
+			Published:  posted,
+			Link:       AtomLink{Href: JobURL(job), Rel: "alternate", Type: "text/html"},
+			Author:     AtomPerson{Name: job.Company},
+			Summary:    AtomText{Type: "text", Value: jobSummary(job)},
+			Categories: categories,
+		})
+	}
+
+	return feed
+}
+
+// BuildJobFeed renders jobs in the aggregator XML layout.
+func BuildJobFeed(jobs []models.Job) JobFeed {
+	feed := JobFeed{
+		Publisher:     "Jobly",
+		PublisherURL:  FeedBaseURL(),
+		LastBuildDate: time.Now().UTC().Format(time.RFC1123Z),
+		Jobs:          []JobFeedJob{},
+	}
+
+	for _, job := range jobs {
+		city, country := splitLocation(job.Location)
+		entry := JobFeedJob{
+			Title:           CData{job.JobName},
+			Date:            CData{job.ID.Timestamp().UTC().Format(time.RFC1123Z)},
+			ReferenceNumber: CData{job.ID.Hex()},
+			URL:             CData{JobURL(job)},
+			Company:         CData{job.Company},
+			City:            CData{city},
+			Country:         CData{country},
+			Description:     CData{job.JobDescription},
+			Salary:          CData{salaryText(job)},
+			JobType:         CData{job.Type},
+			Category:        CData{job.Industry},
+			Requirements:    CData{strings.Join(job.MandatoryRequirements, "; ")},
+		}
+		if job.Sponsored {
+			entry.Sponsored = "yes"
+		}
+		feed.Jobs = append(feed.Jobs, entry)
+	}
+
+	return feed
+}
+
+// BuildJobPosting renders a job as a schema.org JobPosting for JSON-LD.
+func BuildJobPosting(job models.Job) map[string]interface{} {
+	city, country := splitLocation(job.Location)
+	posted := job.ID.Timestamp().UTC()
+
+	posting := map[string]interface{}{
+		"@context":       "https://schema.org/",
+		"@type":          "JobPosting",
+		"title":          job.JobName,
+		"description":    job.JobDescription,
+		"identifier":     map[string]interface{}{"@type": "PropertyValue", "name": job.Company, "value": job.ID.Hex()},
+		"datePosted":     posted.Format("2006-01-02"),
+		"validThrough":   posted.AddDate(0, 0, 60).Format(time.RFC3339),
+		"employmentType": employmentType(job.Type),
+		"industry":       job.Industry,
+		"url":            JobURL(job),
+		"hiringOrganization": map[string]interface{}{
+			"@type":  "Organization",
+			"name":   job.Company,
+			"logo":   job.ImageLink,
+			"sameAs": FeedBaseURL(),
+		},
+	}
+
+	if strings.Contains(strings.ToLower(job.Location), "remote") {
+		posting["jobLocationType"] = "TELECOMMUTE"
+	} else {
+		address := map[string]interface{}{"@type": "PostalAddress", "addressLocality": city}
+		if country != "" {
+			address["addressCountry"] = country
+		}
+		posting["jobLocation"] = map[string]interface{}{"@type": "Place", "address": address}
+	}
+
+	if len(job.MandatoryRequirements) > 0 {
+		posting["qualifications"] = strings.Join(job.MandatoryRequirements, "; ")
+	}
+	if len(job.OptionalRequirements) > 0 {
+		posting["skills"] = strings.Join(job.OptionalRequirements, "; ")
+	}
+
+	low, lowOK := ParseSalary(job.SalaryLow)
+	high, highOK := ParseSalary(job.SalaryHigh)
+	if lowOK || highOK {
+		value := map[string]interface{}{"@type": "QuantitativeValue", "unitText": "YEAR"}
+		if lowOK {
+			value["minValue"] = low
+		}
+		if highOK {
+			value["maxValue"] = high
+		}
+		posting["baseSalary"] = map[string]interface{}{
+			"@type":    "MonetaryAmount",
+			"currency": string(job.Currency),
+			"value":    value,
+		}
+	}
+
+	return posting
+}
+
+// SalaryAmount parses a salary string for the stored numeric amount, nil
+// when it is not a number.
+func SalaryAmount(value string) *float64 {
+	amount, ok := ParseSalary(value)
+	if !ok {
+		return nil
+	}
+	return &amount
+}
+
+// ParseSalary reads salary strings such as "120000", "120,000", "$120k" or
+// "1.2M" into a number.
+func ParseSalary(value string) (float64, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(value, "k"):
+		multiplier = 1_000
+		value = strings.TrimSuffix(value, "k")
+	case strings.HasSuffix(value, "m"):
+		multiplier = 1_000_000
+		value = strings.TrimSuffix(value, "m")
+	}
+
+	value = strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == '.' {
+			return r
+		}
+		return -1
+	}, value)
+	if value == "" {
+		return 0, false
+	}
+
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	return amount * multiplier, true
+}
+
+func employmentType(jobType string) string {
+	normalized := strings.ToUpper(strings.NewReplacer("-", "_", " ", "_").Replace(strings.TrimSpace(jobType)))
+	switch normalized {
+	case "FULL_TIME", "FULLTIME", "PERMANENT":
+		return "FULL_TIME"
+	case "PART_TIME", "PARTTIME":
+		return "PART_TIME"
+	case "CONTRACT", "CONTRACTOR", "FREELANCE":
+		return "CONTRACTOR"
+	case "TEMPORARY", "TEMP":
+		return "TEMPORARY"
+	case "INTERN", "INTERNSHIP":
+		return "INTERN"
+	case "VOLUNTEER":
+		return "VOLUNTEER"
+	case "PER_DIEM":
+		return "PER_DIEM"
+	}
+	return "OTHER"
+}
+
+func splitLocation(location string) (city, country string) {
+	parts := strings.Split(location, ",")
+	city = strings.TrimSpace(parts[0])
+	if len(parts) > 1 {
+		country = strings.TrimSpace(parts[len(parts)-1])
+	}
+	return city, country
+}
+
+func salaryText(job models.Job) string {
+	switch {
+	case job.SalaryLow != "" && job.SalaryHigh != "":
+		return fmt.Sprintf("%s %s - %s", job.Currency, job.SalaryLow, job.SalaryHigh)
+	case job.SalaryHigh != "":
+		return fmt.Sprintf("%s %s", job.Currency, job.SalaryHigh)
+	case job.SalaryLow != "":
+		return fmt.Sprintf("%s %s", job.Currency, job.SalaryLow)
+	}
+	return ""
+}
+
+func jobSummary(job models.Job) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s | %s | %s\n", job.Company, job.Location, job.Type)
+	if salary := salaryText(job); salary != "" {
+		fmt.Fprintf(&b, "Salary: %s\n", salary)
+	}
+	b.WriteString("\n")
+	b.WriteString(job.JobDescription)
+	return b.String()
+}
+
+func jobCategories(job models.Job) []string {
+	var categories []string
+	for _, category := range []string{job.Industry, job.Type} {
+		if category != "" {
+			categories = append(categories, category)
+		}
+	}
+	return categories
+}
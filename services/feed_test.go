@@ -0,0 +1,35 @@
+package services
+
+import "testing"
+
+func TestParseSalary(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   float64
+		wantOK bool
+	}{
+		{"120000", 120000, true},
+		{"120,000", 120000, true},
+		{"$120k", 120000, true},
+		{" 95K ", 95000, true},
+		{"1.2M", 1200000, true},
+		{"", 0, false},
+		{"competitive", 0, false},
+		{"k", 0, false},
+	}
+	for _, test := range tests {
+		got, ok := ParseSalary(test.value)
+		if ok != test.wantOK || (ok && got != test.want) {
+			t.Errorf("ParseSalary(%q) = %v, %v, want %v, %v", test.value, got, ok, test.want, test.wantOK)
+		}
+	}
+}
+
+func TestSalaryAmount(t *testing.T) {
+	if amount := SalaryAmount("negotiable"); amount != nil {
+		t.Errorf("SalaryAmount(negotiable) = %v, want nil", *amount)
+	}
+	if amount := SalaryAmount("80k"); amount == nil || *amount != 80000 {
+		t.Errorf("SalaryAmount(80k) = %v, want 80000", amount)
+	}
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FileGCInterval is how often NewFileCollector looks for orphaned files.
+const FileGCInterval = 6 * time.Hour
+
+// CollectOrphanFiles deletes the GridFS files uploaded before olderThan that
+// no document references any more and returns how many it deleted. Files
+// that are younger may still be waiting for their document to be written.
+func CollectOrphanFiles(ctx context.Context, olderThan time.Time) (int, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := db.DB.Collection("fs.files").Find(ctx, bson.M{"uploadDate": bson.M{"$lt": olderThan}}, opts)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	deleted := 0
+	for cursor.Next(ctx) {
+		var file struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&file); err != nil {
+			return deleted, err
+		}
+		referenced, err := FileReferenced(ctx, file.ID)
+		if err != nil {
+			return deleted, err
+		}
+		if referenced {
+			continue
+		}
+		if err := DeleteFile(ctx, file.ID); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, cursor.Err()
+}
+
+// NewFileCollector returns a worker that periodically deletes orphaned
+// GridFS files, such as resumes left behind by a crash between upload and
+// insert.
+func NewFileCollector() *PeriodicWorker {
+	return NewPeriodicWorker("gridfs garbage collection", FileGCInterval, func(ctx context.Context) error {
+		deleted, err := CollectOrphanFiles(ctx, time.Now().Add(-time.Hour))
+		if deleted > 0 {
+			log.Printf("deleted %d orphaned gridfs files", deleted)
+		}
+		return err
+	})
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+)
+
+var ErrNoResumeFile = errors.New("resume has no stored file")
+
+func bucket() (*gridfs.Bucket, error) {
+	return gridfs.NewBucket(db.DB)
+}
+
+// UploadFile stores data in GridFS and returns the id of the new file.
+func UploadFile(ctx context.Context, filename string, data []byte) (primitive.ObjectID, error) {
+	b, err := bucket()
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	uploadStream, err := b.OpenUploadStream(filename)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		uploadStream.SetWriteDeadline(deadline)
+	}
+	if _, err := uploadStream.Write(data); err != nil {
+		uploadStream.Abort()
+		return primitive.NilObjectID, err
+	}
+	if err := uploadStream.Close(); err != nil {
+		return primitive.NilObjectID, err
+	}
+	return uploadStream.FileID.(primitive.ObjectID), nil
+}
+
+// DownloadFile reads a GridFS file into memory.
+func DownloadFile(ctx context.Context, fileID primitive.ObjectID) ([]byte, error) {
+	b, err := bucket()
+	if err != nil {
+		return nil, err
+	}
+	downloadStream, err := b.OpenDownloadStream(fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer downloadStream.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		downloadStream.SetReadDeadline(deadline)
+	}
+
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(downloadStream); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DeleteFile removes a GridFS file. Deleting a file that is already gone is
+// not an error.
+func DeleteFile(ctx context.Context, fileID primitive.ObjectID) error {
+	b, err := bucket()
+	if err != nil {
+		return err
+	}
+	err = b.DeleteContext(ctx, fileID)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil
+	}
+	return err
+}
+
+// CopyFile duplicates a GridFS file under a new id.
+func CopyFile(ctx context.Context, fileID primitive.ObjectID, filename string) (primitive.ObjectID, error) {
+	data, err := DownloadFile(ctx, fileID)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	return UploadFile(ctx, filename, data)
+}
+
+// ResumeFileID returns the GridFS id of a stored resume. Stored resumes keep
+// the hex encoded file id in PDF.Data.
+func ResumeFileID(resume models.PDF) (primitive.ObjectID, error) {
+	if len(resume.Data) == 0 {
+		return primitive.NilObjectID, ErrNoResumeFile
+	}
+	return primitive.ObjectIDFromHex(string(resume.Data))
+}
+
+// StoreResume uploads the raw resume bytes in PDF.Data to GridFS and returns
+// the PDF as it is stored on documents, with the file id in Data.
+func StoreResume(ctx context.Context, resume models.PDF) (models.PDF, error) {
+	fileID, err := UploadFile(ctx, resume.Filename, resume.Data)
+	if err != nil {
+		return models.PDF{}, err
+	}
+	return models.PDF{
+		Filename:    resume.Filename,
+		ContentType: resume.ContentType,
+		Data:        []byte(fileID.Hex()),
+	}, nil
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+const (
+	CheckOK   = "ok"
+	CheckFail = "fail"
+
+	// healthCheckTimeout bounds each dependency check so a hanging
+	// dependency cannot hold up the probe
+	healthCheckTimeout         = 2 * time.Second
+	defaultReadinessDrainDelay = 5 * time.Second
+)
+
+// WorkerStatus is what a background worker reports about itself.
+type WorkerStatus struct {
+	Name      string     `json:"name"`
+	Running   bool       `json:"running"`
+	LastRunAt *time.Time `json:"lastRunAt,omitempty"`
+	LastError string     `json:"lastError,omitempty"`
+}
+
+// StatusReporter is a background worker watched by the readiness check.
+type StatusReporter interface {
+	Status() WorkerStatus
+}
+
+// CheckResult is the outcome of one readiness check.
+type CheckResult struct {
+	Name      string         `json:"name"`
+	Status    string         `json:"status"`
+	LatencyMs int64          `json:"latencyMs"`
+	Error     string         `json:"error,omitempty"`
+	Warnings  []string       `json:"warnings,omitempty"`
+	Workers   []WorkerStatus `json:"workers,omitempty"`
+}
+
+// Readiness is the detailed answer of the readiness probe.
+type Readiness struct {
+	Ready        bool          `json:"ready"`
+	ShuttingDown bool          `json:"shuttingDown"`
+	Checks       []CheckResult `json:"checks"`
+	CheckedAt    time.Time     `json:"checkedAt"`
+}
+
+var (
+	shuttingDown atomic.Bool
+
+	watchedMu sync.Mutex
+	watched   []StatusReporter
+)
+
+// WatchWorkers adds background workers to the readiness check. A worker
+// that is not running makes the instance not ready.
+func WatchWorkers(workers ...StatusReporter) {
+	watchedMu.Lock()
+	defer watchedMu.Unlock()
+	watched = append(watched, workers...)
+}
+
+// BeginShutdown marks the instance as not ready, so load balancers stop
+// sending it traffic before the server stops accepting connections.
+func BeginShutdown() {
+	shuttingDown.Store(true)
+}
+
+// ReadinessDrainDelay is how long the server keeps serving after
+// BeginShutdown for load balancers to notice, read from
+// READINESS_DRAIN_SECONDS.
+func ReadinessDrainDelay() time.Duration {
+	delay, problem := envSeconds("READINESS_DRAIN_SECONDS")
+	if problem != "" {
+		log.Println(problem + ", using the default")
+	}
+	if delay < 0 {
+		return defaultReadinessDrainDelay
+	}
+	return delay
+}
+
+// CheckReadiness checks the database, GridFS, the background workers and
+// the configuration. During shutdown the instance is never ready.
+func CheckReadiness(ctx context.Context) Readiness {
+	readiness := Readiness{
+		ShuttingDown: shuttingDown.Load(),
+		CheckedAt:    time.Now().UTC(),
+	}
+	readiness.Checks = []CheckResult{
+		runCheck(ctx, "mongo", checkMongo),
+		runCheck(ctx, "gridfs", checkGridFS),
+		checkWorkers(),
+		checkConfig(),
+	}
+
+	readiness.Ready = !readiness.ShuttingDown
+	for _, check := range readiness.Checks {
+		if check.Status != CheckOK {
+			readiness.Ready = false
+		}
+	}
+	return readiness
+}
+
+func runCheck(ctx context.Context, name string, check func(ctx context.Context) error) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	started := time.Now()
+	err := check(ctx)
+	result := CheckResult{Name: name, Status: CheckOK, LatencyMs: time.Since(started).Milliseconds()}
+	if err != nil {
+		result.Status = CheckFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func checkMongo(ctx context.Context) error {
+	if db.Client == nil {
+		return errors.New("not connected")
+	}
+	return db.Client.Ping(ctx, readpref.Primary())
+}
+
+// checkGridFS reads from the files collection of the bucket resumes and
+// attachments are stored in.
+func checkGridFS(ctx context.Context) error {
+	if db.DB == nil {
+		return errors.New("not connected")
+	}
+	b, err := bucket()
+	if err != nil {
+		return err
+	}
+	opts := options.FindOne().SetProjection(bson.M{"_id": 1})
+	err = b.GetFilesCollection().FindOne(ctx, bson.M{}, opts).Err()
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	return err
+}
+
+// checkWorkers fails when a watched worker is not running. The last error
+// of a worker is reported but does not fail the check, the next run may
+// well succeed.
+func checkWorkers() CheckResult {
+	watchedMu.Lock()
+	workers := append([]StatusReporter(nil), watched...)
+	watchedMu.Unlock()
+
+	result := CheckResult{Name: "workers", Status: CheckOK, Workers: []WorkerStatus{}}
+	for _, worker := range workers {
+		status := worker.Status()
+		if !status.Running {
+			result.Status = CheckFail
+			result.Error = status.Name + " is not running"
+		}
+		result.Workers = append(result.Workers, status)
+	}
+	return result
+}
+
+// checkConfig fails without the settings the server cannot work without and
+// warns about settings that fall back to their defaults.
+func checkConfig() CheckResult {
+	result := CheckResult{Name: "config", Status: CheckOK}
+	if os.Getenv("SECRET_KEY") == "" {
+		result.Status = CheckFail
+		result.Error = "SECRET_KEY is not set"
+	}
+
+	if value := os.Getenv("TRASH_RETENTION_DAYS"); value != "" {
+		if days, err := strconv.Atoi(value); err != nil || days <= 0 {
+			result.Warnings = append(result.Warnings, "invalid TRASH_RETENTION_DAYS, using the default")
+		}
+	}
+	if value := os.Getenv("ERASURE_DELAY_DAYS"); value != "" {
+		if days, err := strconv.Atoi(value); err != nil || days < 0 {
+			result.Warnings = append(result.Warnings, "invalid ERASURE_DELAY_DAYS, using the default")
+		}
+	}
+	if _, problem := envSeconds("READINESS_DRAIN_SECONDS"); problem != "" {
+		result.Warnings = append(result.Warnings, problem+", using the default")
+	}
+	if host := os.Getenv("SMTP_HOST"); host == "" {
+		result.Warnings = append(result.Warnings, "SMTP_HOST is not set, emails are only logged")
+	} else if port := os.Getenv("SMTP_PORT"); port != "" {
+		if _, err := net.LookupPort("tcp", port); err != nil {
+			result.Warnings = append(result.Warnings, "invalid SMTP_PORT")
+		}
+	}
+	return result
+}
+
+// envSeconds reads a number of seconds from an environment variable. It
+// returns -1 when the variable is not set.
+func envSeconds(name string) (time.Duration, string) {
+	value := os.Getenv(name)
+	if value == "" {
+		return -1, ""
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return -1, "invalid " + name
+	}
+	return time.Duration(seconds) * time.Second, ""
+}
+
+// workerState tracks what a background worker reports through Status.
+type workerState struct {
+	mu        sync.Mutex
+	running   bool
+	lastRunAt *time.Time
+	lastError string
+}
+
+func (s *workerState) setRunning(running bool) {
+	s.mu.Lock()
+	s.running = running
+	s.mu.Unlock()
+}
+
+func (s *workerState) recordRun(err error) {
+	now := time.Now().UTC()
+	s.mu.Lock()
+	s.lastRunAt = &now
+	s.lastError = ""
+	if err != nil {
+		s.lastError = err.Error()
+	}
+	s.mu.Unlock()
+}
+
+func (s *workerState) status(name string) WorkerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return WorkerStatus{Name: name, Running: s.running, LastRunAt: s.lastRunAt, LastError: s.lastError}
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	ICSMethodRequest = "REQUEST"
+	ICSMethodCancel  = "CANCEL"
+)
+
+// ICSAttendee is a participant of a calendar event.
+type ICSAttendee struct {
+	Name  string
+	Email string
+}
+
+// ICSEvent describes a single event of an iCalendar invite.
+type ICSEvent struct {
+	UID         string
+	Sequence    int
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Organizer   ICSAttendee
+	Attendees   []ICSAttendee
+	Created     time.Time
+}
+
+// BuildICS renders an RFC 5545 calendar with one event. Times are written in
+// UTC so no VTIMEZONE component is needed. method is REQUEST for invites and
+// updates and CANCEL for cancellations; updates must use the UID of the
+// original invite with a higher sequence.
+func BuildICS(method string, event ICSEvent) []byte {
+	var buf bytes.Buffer
+	line := func(content string) {
+		buf.WriteString(foldICSLine(content))
+		buf.WriteString("\r\n")
+	}
+
+	status := "CONFIRMED"
+	if method == ICSMethodCancel {
+		status = "CANCELLED"
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Jobly//Interview Scheduling//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:" + method)
+	line("BEGIN:VEVENT")
+	line("UID:" + event.UID)
+	line("SEQUENCE:" + strconv.Itoa(event.Sequence))
+	line("DTSTAMP:" + icsTime(time.Now()))
+	if !event.Created.IsZero() {
+		line("CREATED:" + icsTime(event.Created))
+	}
+	line("DTSTART:" + icsTime(event.Start))
+	line("DTEND:" + icsTime(event.End))
+	line("SUMMARY:" + escapeICSText(event.Summary))
+	if event.Description != "" {
+		line("DESCRIPTION:" + escapeICSText(event.Description))
+	}
+	if event.Location != "" {
+		line("LOCATION:" + escapeICSText(event.Location))
+	}
+	if event.URL != "" {
+		line("URL:" + event.URL)
+	}
+	if event.Organizer.Email != "" {
+		line("ORGANIZER" + icsName(event.Organizer.Name) + ":mailto:" + event.Organizer.Email)
+	}
+	for _, attendee := range event.Attendees {
+		line("ATTENDEE" + icsName(attendee.Name) + ";ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:" + attendee.Email)
+	}
+	line("STATUS:" + status)
+	line("TRANSP:OPAQUE")
+	line("END:VEVENT")
+	line("END:VCALENDAR")
+
+	return buf.Bytes()
+}
+
+func icsTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func icsName(name string) string {
+	if name == "" {
+		return ""
+	}
+	name = strings.NewReplacer(`"`, "'", "\r", "", "\n", " ").Replace(name)
+	return `;CN="` + name + `"`
+}
+
+// escapeICSText escapes a TEXT property value (RFC 5545 section 3.3.11).
+func escapeICSText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(value)
+}
+
+// foldICSLine splits content lines longer than 75 octets, continuing them
+// on lines that start with a space, without breaking UTF-8 sequences.
+func foldICSLine(content string) string {
+	if len(content) <= 75 {
+		return content
+	}
+	var b strings.Builder
+	limit := 75
+	for len(content) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		b.WriteString(content[:cut])
+		b.WriteString("\r\n ")
+		content = content[cut:]
+		// continuation lines lose one octet to the leading space
+		limit = 74
+	}
+	b.WriteString(content)
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestBuildICS(t *testing.T) {
+	start := time.Date(2026, 5, 4, 9, 30, 0, 0, time.FixedZone("EAT", 3*60*60))
+	ics := string(BuildICS(ICSMethodRequest, ICSEvent{
+		UID:         "interview-1@jobly",
+		Sequence:    2,
+		Start:       start,
+		End:         start.Add(time.Hour),
+		Summary:     "Interview; Go, backend",
+		Description: "First line\nSecond line",
+		Organizer:   ICSAttendee{Name: "Acme", Email: "jobs@acme.test"},
+		Attendees:   []ICSAttendee{{Name: `Jane "JD" Doe`, Email: "jane@example.com"}},
+	}))
+
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"METHOD:REQUEST\r\n",
+		"UID:interview-1@jobly\r\n",
+		"SEQUENCE:2\r\n",
+		"DTSTART:20260504T063000Z\r\n",
+		"DTEND:20260504T073000Z\r\n",
+		`SUMMARY:Interview\; Go\, backend` + "\r\n",
+		`DESCRIPTION:First line\nSecond line` + "\r\n",
+		`ORGANIZER;CN="Acme":mailto:jobs@acme.test` + "\r\n",
+		`ATTENDEE;CN="Jane 'JD' Doe"`,
+		"STATUS:CONFIRMED\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("calendar is missing %q:\n%s", want, ics)
+		}
+	}
+
+	cancel := string(BuildICS(ICSMethodCancel, ICSEvent{UID: "x", Start: start, End: start}))
+	if !strings.Contains(cancel, "METHOD:CANCEL\r\n") || !strings.Contains(cancel, "STATUS:CANCELLED\r\n") {
+		t.Errorf("cancellation is not marked cancelled:\n%s", cancel)
+	}
+}
+
+func TestFoldICSLine(t *testing.T) {
+	content := "DESCRIPTION:" + strings.Repeat("é", 100)
+	folded := foldICSLine(content)
+	for i, line := range strings.Split(folded, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line %d is %d octets long", i, len(line))
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("line %d splits a UTF-8 sequence", i)
+		}
+		if i > 0 && !strings.HasPrefix(line, " ") {
+			t.Errorf("continuation line %d does not start with a space", i)
+		}
+	}
+	if unfolded := strings.ReplaceAll(folded, "\r\n ", ""); unfolded != content {
+		t.Errorf("unfolding gives %q", unfolded)
+	}
+}
+
+func TestInterviewICSMaskedCandidate(t *testing.T) {
+	slot := models.InterviewSlot{ID: primitive.NewObjectID(), Start: time.Now(), End: time.Now().Add(time.Hour)}
+	interview := models.Interview{ID: primitive.NewObjectID(), ApplicationID: primitive.NewObjectID(), CandidateName: "Jane Doe", CandidateEmail: "jane@example.com"}
+	interviewers := []models.User{{Name: "Sam", Email: "sam@acme.test"}}
+
+	ics := string(InterviewICS(MaskInterview(interview), ICSMethodRequest, slot, interviewers))
+	ics = strings.ReplaceAll(ics, "\r\n ", "")
+	if strings.Contains(ics, "jane@example.com") || strings.Contains(ics, "Jane") {
+		t.Errorf("masked invite names the candidate:\n%s", ics)
+	}
+	if !strings.Contains(ics, "mailto:sam@acme.test") {
+		t.Errorf("masked invite lost the interviewers:\n%s", ics)
+	}
+	if strings.Contains(ics, "mailto:\r\n") {
+		t.Errorf("masked invite has an attendee without address:\n%s", ics)
+	}
+}
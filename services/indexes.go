@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// IndexSpec declares an index the application relies on.
+type IndexSpec struct {
+	Collection string
+	Name       string
+	Keys       bson.D
+	Unique     bool
+	// Expires removes documents once the date in the indexed field passes
+	Expires bool
+}
+
+// Indexes lists every index EnsureIndexes creates. Unique indexes back the
+// duplicate checks of the handlers, the others support frequent queries.
+var Indexes = []IndexSpec{
+	// deletedAt is part of the key so trashed applications do not block
+	// applying again
+	{Collection: "applications", Name: "userId_jobId_deletedAt_unique", Keys: bson.D{{Key: "userId", Value: 1}, {Key: "jobId", Value: 1}, {Key: "deletedAt", Value: 1}}, Unique: true},
+	{Collection: "applications", Name: "jobId", Keys: bson.D{{Key: "jobId", Value: 1}}},
+	{Collection: "bookmarks", Name: "userId_jobId_unique", Keys: bson.D{{Key: "userId", Value: 1}, {Key: "jobId", Value: 1}}, Unique: true},
+	{Collection: "bookmarks", Name: "jobId", Keys: bson.D{{Key: "jobId", Value: 1}}},
+	{Collection: "users", Name: "email_unique", Keys: bson.D{{Key: "email", Value: 1}}, Unique: true},
+	{Collection: "jobs", Name: "userId", Keys: bson.D{{Key: "userId", Value: 1}}},
+	{Collection: "jobs", Name: "sponsored", Keys: bson.D{{Key: "sponsored", Value: 1}}},
+	{Collection: "searchlog", Name: "userId", Keys: bson.D{{Key: "userId", Value: 1}}},
+	{Collection: "searchlog", Name: "jobId", Keys: bson.D{{Key: "jobId", Value: 1}}},
+	{Collection: "notifications", Name: "userId", Keys: bson.D{{Key: "userId", Value: 1}}},
+	// the file references FileReferenced looks up for every GridFS file
+	{Collection: "applications", Name: "resume.data", Keys: bson.D{{Key: "resume.data", Value: 1}}},
+	{Collection: "profiles", Name: "defaultResume.data", Keys: bson.D{{Key: "defaultResume.data", Value: 1}}},
+	{Collection: "messages", Name: "attachments.fileId", Keys: bson.D{{Key: "attachments.fileId", Value: 1}}},
+	{Collection: "streamTickets", Name: "expiresAt_ttl", Keys: bson.D{{Key: "expiresAt", Value: 1}}, Expires: true},
+}
+
+func (spec IndexSpec) model() mongo.IndexModel {
+	opts := options.Index().SetName(spec.Name)
+	if spec.Unique {
+		opts.SetUnique(true)
+	}
+	if spec.Expires {
+		opts.SetExpireAfterSeconds(0)
+	}
+	return mongo.IndexModel{Keys: spec.Keys, Options: opts}
+}
+
+// EnsureIndexes creates the declared indexes that do not exist yet. It
+// carries on past failures, e.g. a unique index over existing duplicates,
+// and returns them together.
+func EnsureIndexes(ctx context.Context) error {
+	var errs []error
+	for _, spec := range Indexes {
+		_, err := db.DB.Collection(spec.Collection).Indexes().CreateOne(ctx, spec.model())
+		if err == nil {
+			continue
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			err = fmt.Errorf("%w: remove the duplicate documents first", err)
+		}
+		errs = append(errs, fmt.Errorf("index %s.%s: %w", spec.Collection, spec.Name, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RebuildIndexes drops the declared indexes and creates them again, e.g.
+// after their definition changed or a unique index failed to build.
+func RebuildIndexes(ctx context.Context) error {
+	var errs []error
+	for _, spec := range Indexes {
+		_, err := db.DB.Collection(spec.Collection).Indexes().DropOne(ctx, spec.Name)
+		var commandErr mongo.CommandError
+		// a missing collection or index has nothing to drop
+		if errors.As(err, &commandErr) && (commandErr.Code == 26 || commandErr.Code == 27) {
+			err = nil
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("drop index %s.%s: %w", spec.Collection, spec.Name, err))
+		}
+		if ctx.Err() != nil {
+			return errors.Join(errs...)
+		}
+	}
+	if err := EnsureIndexes(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
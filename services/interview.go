@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	_ "time/tzdata" // interview timezones must resolve without system zoneinfo
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	maxInterviewSlots    = 10
+	maxInterviewDuration = 8 * time.Hour
+)
+
+const (
+	InterviewEventProposed    = "proposed"
+	InterviewEventScheduled   = "scheduled"
+	InterviewEventRescheduled = "rescheduled"
+	InterviewEventCancelled   = "cancelled"
+)
+
+// NormalizeInterviewSlots validates proposed slots, assigns ids to new ones
+// and sorts them by start time.
+func NormalizeInterviewSlots(slots []models.InterviewSlot, now time.Time) []string {
+	var problems []string
+
+	if len(slots) == 0 {
+		problems = append(problems, "at least one slot is required")
+	}
+	if len(slots) > maxInterviewSlots {
+		problems = append(problems, fmt.Sprintf("at most %d slots can be proposed", maxInterviewSlots))
+	}
+
+	for i := range slots {
+		slot := &slots[i]
+		label := fmt.Sprintf("slot %d", i+1)
+		if slot.ID.IsZero() {
+			slot.ID = primitive.NewObjectID()
+		}
+		slot.Start = slot.Start.UTC()
+		slot.End = slot.End.UTC()
+		if slot.Start.IsZero() || slot.End.IsZero() {
+			problems = append(problems, label+": start and end are required")
+			continue
+		}
+		if !slot.End.After(slot.Start) {
+			problems = append(problems, label+": end must be after start")
+		}
+		if slot.End.Sub(slot.Start) > maxInterviewDuration {
+			problems = append(problems, label+": interviews cannot be longer than 8 hours")
+		}
+		if !slot.Start.After(now) {
+			problems = append(problems, label+": start must be in the future")
+		}
+	}
+
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Start.Before(slots[j].Start) })
+	for i := 1; i < len(slots); i++ {
+		if slots[i].Start.Equal(slots[i-1].Start) {
+			problems = append(problems, "slots must not start at the same time")
+			break
+		}
+	}
+
+	return problems
+}
+
+// ValidateInterviewDetails checks the timezone and where the interview
+// takes place.
+func ValidateInterviewDetails(interview *models.Interview) []string {
+	var problems []string
+
+	interview.Title = strings.TrimSpace(interview.Title)
+	interview.Location = strings.TrimSpace(interview.Location)
+	interview.VideoLink = strings.TrimSpace(interview.VideoLink)
+
+	if interview.Timezone == "" {
+		interview.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(interview.Timezone); err != nil {
+		problems = append(problems, fmt.Sprintf("unknown timezone %q", interview.Timezone))
+	}
+
+	if interview.Location == "" && interview.VideoLink == "" {
+		problems = append(problems, "a location or video link is required")
+	}
+	if interview.VideoLink != "" {
+		u, err := url.Parse(interview.VideoLink)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, "videoLink must be an absolute http(s) url")
+		}
+	}
+
+	return problems
+}
+
+// FindInterviewers loads the users taking part in an interview. It returns
+// the ids that do not belong to an admin user.
+func FindInterviewers(ctx context.Context, ids []primitive.ObjectID) ([]models.User, []primitive.ObjectID, error) {
+	users := []models.User{}
+	if len(ids) == 0 {
+		return users, nil, nil
+	}
+
+	cursor, err := db.DB.Collection("users").Find(ctx, bson.M{"_id": bson.M{"$in": ids}, "role": "admin"})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, nil, err
+	}
+
+	found := map[primitive.ObjectID]bool{}
+	for _, user := range users {
+		found[user.ID] = true
+	}
+	var unknown []primitive.ObjectID
+	for _, id := range ids {
+		if !found[id] {
+			unknown = append(unknown, id)
+		}
+	}
+	return users, unknown, nil
+}
+
+// InterviewICS renders the calendar invite of an interview for slot.
+func InterviewICS(interview models.Interview, method string, slot models.InterviewSlot, interviewers []models.User) []byte {
+	// a masked candidate has no address to invite
+	attendees := []ICSAttendee{}
+	if interview.CandidateEmail != "" {
+		attendees = append(attendees, ICSAttendee{Name: interview.CandidateName, Email: interview.CandidateEmail})
+	}
+	for _, interviewer := range interviewers {
+		attendees = append(attendees, ICSAttendee{Name: interviewer.Name, Email: interviewer.Email})
+	}
+
+	description := fmt.Sprintf("Interview for %s at %s.", interview.JobName, interview.Company)
+	if interview.VideoLink != "" {
+		description += "\nJoin: " + interview.VideoLink
+	}
+	if interview.Notes != "" {
+		description += "\n\n" + interview.Notes
+	}
+	location := interview.Location
+	if location == "" {
+		location = interview.VideoLink
+	}
+
+	return BuildICS(method, ICSEvent{
+		UID:         "interview-" + interview.ID.Hex() + "@jobly",
+		Sequence:    interview.Sequence,
+		Start:       slot.Start,
+		End:         slot.End,
+		Summary:     interview.Title,
+		Description: description,
+		Location:    location,
+		URL:         interview.VideoLink,
+		Organizer:   ICSAttendee{Name: interview.Company, Email: MailFrom()},
+		Attendees:   attendees,
+		Created:     interview.CreatedAt,
+	})
+}
+
+// NotifyInterview emails the participants about a change to an interview.
+// previous is the slot that was booked before the change, if any; its
+// calendar entry is cancelled when the interview goes back to proposed or
+// is cancelled. Under blind review the interviewers get their own copy,
+// naming the candidate by alias.
+func NotifyInterview(ctx context.Context, interview models.Interview, event string, previous *models.InterviewSlot) error {
+	interviewers, _, err := FindInterviewers(ctx, append([]primitive.ObjectID{interview.OrganizerID}, interview.InterviewerIDs...))
+	if err != nil {
+		return err
+	}
+	staff := []string{}
+	for _, interviewer := range interviewers {
+		staff = append(staff, interviewer.Email)
+	}
+
+	hidden, err := HiddenApplications(ctx, []primitive.ObjectID{interview.ApplicationID})
+	if err != nil {
+		return err
+	}
+	_, blind := hidden[interview.ApplicationID]
+
+	loc, err := time.LoadLocation(interview.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	when := func(slot models.InterviewSlot) string {
+		return fmt.Sprintf("%s - %s (%s)", slot.Start.In(loc).Format("Mon 2 Jan 2006 15:04"), slot.End.In(loc).Format("15:04"), interview.Timezone)
+	}
+	cancellation := func(interview models.Interview) []Attachment {
+		if previous == nil {
+			return nil
+		}
+		return []Attachment{{
+			Filename:    "cancel.ics",
+			ContentType: "text/calendar; charset=utf-8; method=CANCEL",
+			Data:        InterviewICS(interview, ICSMethodCancel, *previous, interviewers),
+		}}
+	}
+	// mailEveryone sends the mail render makes of interview to the candidate
+	// and the interviewers
+	mailEveryone := func(subject string, render func(models.Interview) (string, []Attachment)) error {
+		body, attachments := render(interview)
+		if !blind {
+			to := append([]string{interview.CandidateEmail}, staff...)
+			return SendMail(ctx, Mail{To: to, Subject: subject, Body: body, Attachments: attachments})
+		}
+		if err := SendMail(ctx, Mail{To: []string{interview.CandidateEmail}, Subject: subject, Body: body, Attachments: attachments}); err != nil {
+			return err
+		}
+		body, attachments = render(MaskInterview(interview))
+		return SendMail(ctx, Mail{To: staff, Subject: subject, Body: body, Attachments: attachments})
+	}
+
+	switch event {
+	case InterviewEventProposed:
+		var lines []string
+		for _, slot := range interview.Slots {
+			lines = append(lines, "- "+when(slot))
+		}
+		render := func(interview models.Interview) (string, []Attachment) {
+			body := fmt.Sprintf("Hi %s,\n\nWe would like to interview you for %s at %s. Please pick one of these times:\n\n%s\n",
+				interview.CandidateName, interview.JobName, interview.Company, strings.Join(lines, "\n"))
+			if previous != nil {
+				body += "\nThe previously scheduled time (" + when(*previous) + ") no longer applies.\n"
+			}
+			return body, cancellation(interview)
+		}
+		if previous != nil {
+			return mailEveryone("Interview rescheduled: "+interview.Title, render)
+		}
+		body, _ := render(interview)
+		return SendMail(ctx, Mail{To: []string{interview.CandidateEmail}, Subject: "Interview invitation: " + interview.Title, Body: body})
+
+	case InterviewEventScheduled, InterviewEventRescheduled:
+		if interview.SelectedSlot == nil {
+			return nil
+		}
+		subject := "Interview scheduled: " + interview.Title
+		if event == InterviewEventRescheduled {
+			subject = "Interview rescheduled: " + interview.Title
+		}
+		body := fmt.Sprintf("The interview for %s at %s takes place on %s.\n", interview.JobName, interview.Company, when(*interview.SelectedSlot))
+		if interview.Location != "" {
+			body += "Location: " + interview.Location + "\n"
+		}
+		if interview.VideoLink != "" {
+			body += "Video link: " + interview.VideoLink + "\n"
+		}
+		return mailEveryone(subject, func(interview models.Interview) (string, []Attachment) {
+			return body, []Attachment{{
+				Filename:    "invite.ics",
+				ContentType: "text/calendar; charset=utf-8; method=REQUEST",
+				Data:        InterviewICS(interview, ICSMethodRequest, *interview.SelectedSlot, interviewers),
+			}}
+		})
+
+	case InterviewEventCancelled:
+		body := fmt.Sprintf("The interview for %s at %s has been cancelled.\n", interview.JobName, interview.Company)
+		if interview.CancelReason != "" {
+			body += "Reason: " + interview.CancelReason + "\n"
+		}
+		subject := "Interview cancelled: " + interview.Title
+		if previous == nil {
+			return SendMail(ctx, Mail{To: []string{interview.CandidateEmail}, Subject: subject, Body: body})
+		}
+		return mailEveryone(subject, func(interview models.Interview) (string, []Attachment) {
+			return body, cancellation(interview)
+		})
+	}
+
+	return fmt.Errorf("unknown interview event %q", event)
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/cache"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// JobCacheTTL bounds how stale a cached job can get, e.g. when another
+	// instance changed it and the cache is not shared.
+	JobCacheTTL  = time.Minute
+	jobCacheSize = 1000
+
+	SponsoredJobsCacheKey = "jobs:sponsored"
+)
+
+var (
+	jobCacheMu sync.RWMutex
+	jobCache   cache.Cache = cache.NewLRU(jobCacheSize)
+)
+
+// SetJobCache replaces the cache of the job read paths, e.g. with a shared
+// backend when several instances run.
+func SetJobCache(c cache.Cache) {
+	jobCacheMu.Lock()
+	defer jobCacheMu.Unlock()
+	jobCache = c
+}
+
+// JobCache returns the cache of the job read paths.
+func JobCache() cache.Cache {
+	jobCacheMu.RLock()
+	defer jobCacheMu.RUnlock()
+	return jobCache
+}
+
+func JobCacheKey(jobID primitive.ObjectID) string {
+	return "job:" + jobID.Hex()
+}
+
+// InvalidateJobCache drops the cached responses that contain the jobs. Call
+// it once the write is committed. A read that started before the write can
+// still cache the old job, JobCacheTTL bounds how long it is served.
+func InvalidateJobCache(ctx context.Context, jobIDs ...primitive.ObjectID) {
+	keys := []string{SponsoredJobsCacheKey}
+	for _, jobID := range jobIDs {
+		keys = append(keys, JobCacheKey(jobID))
+	}
+	JobCache().Delete(ctx, keys...)
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrJobDeleted = errors.New("job is deleted")
+
+// JobDeleteImpact describes what deleting a job touches.
+type JobDeleteImpact struct {
+	Applications        int64 `json:"applications"`
+	ApplicationsToClose int64 `json:"applicationsToClose"`
+	Bookmarks           int64 `json:"bookmarks"`
+	SearchLogs          int64 `json:"searchLogs"`
+}
+
+// openApplications matches the applications of a job that are closed when
+// the job is deleted.
+func openApplications(jobID primitive.ObjectID) bson.M {
+	return bson.M{"jobId": jobID, "status": bson.M{"$nin": bson.A{models.StatusRejected, models.StatusClosed}}}
+}
+
+// PreviewJobDelete counts what SoftDeleteJob would change for a job.
+func PreviewJobDelete(ctx context.Context, jobID primitive.ObjectID) (JobDeleteImpact, error) {
+	var impact JobDeleteImpact
+	var err error
+	if impact.Applications, err = db.DB.Collection("applications").CountDocuments(ctx, bson.M{"jobId": jobID}); err != nil {
+		return impact, err
+	}
+	if impact.ApplicationsToClose, err = db.DB.Collection("applications").CountDocuments(ctx, openApplications(jobID)); err != nil {
+		return impact, err
+	}
+	if impact.Bookmarks, err = db.DB.Collection("bookmarks").CountDocuments(ctx, bson.M{"jobId": jobID}); err != nil {
+		return impact, err
+	}
+	if impact.SearchLogs, err = db.DB.Collection("searchlog").CountDocuments(ctx, bson.M{"jobId": jobID}); err != nil {
+		return impact, err
+	}
+	return impact, nil
+}
+
+// SoftDeleteJob takes a job down: it moves to the trash of its owner, the
+// open applications are closed and their candidates notified, and
+// bookmarks and search log entries of the job are removed. Run it in
+// WithTransaction so the cascade is all or nothing.
+func SoftDeleteJob(ctx context.Context, job models.Job, actorID primitive.ObjectID) (JobDeleteImpact, error) {
+	var impact JobDeleteImpact
+	now := time.Now().UTC()
+
+	result, err := db.DB.Collection("jobs").UpdateOne(ctx,
+		NotDeleted(bson.M{"_id": job.ID}),
+		bson.M{"$set": bson.M{"deletedAt": now, "deletedBy": actorID, "updatedAt": now}})
+	if err != nil {
+		return impact, err
+	}
+	if result.MatchedCount == 0 {
+		return impact, ErrJobDeleted
+	}
+
+	applications := db.DB.Collection("applications")
+	if impact.Applications, err = applications.CountDocuments(ctx, bson.M{"jobId": job.ID}); err != nil {
+		return impact, err
+	}
+	cursor, err := applications.Find(ctx, openApplications(job.ID))
+	if err != nil {
+		return impact, err
+	}
+	var open []models.Application
+	if err := cursor.All(ctx, &open); err != nil {
+		return impact, err
+	}
+	if len(open) > 0 {
+		// the previous status is kept so restoring the job reopens them
+		closeApplications := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+			"statusBeforeClose": "$status",
+			"status":            models.StatusClosed,
+			"statusChangedAt":   now,
+			"version":           nextVersion,
+		}}}}
+		updated, err := applications.UpdateMany(ctx, openApplications(job.ID), closeApplications)
+		if err != nil {
+			return impact, err
+		}
+		impact.ApplicationsToClose = updated.ModifiedCount
+		for _, application := range open {
+			if err := NotifyJobClosed(ctx, application); err != nil {
+				return impact, err
+			}
+		}
+	}
+
+	deleted, err := db.DB.Collection("bookmarks").DeleteMany(ctx, bson.M{"jobId": job.ID})
+	if err != nil {
+		return impact, err
+	}
+	impact.Bookmarks = deleted.DeletedCount
+	deleted, err = db.DB.Collection("searchlog").DeleteMany(ctx, bson.M{"jobId": job.ID})
+	if err != nil {
+		return impact, err
+	}
+	impact.SearchLogs = deleted.DeletedCount
+
+	job.DeletedAt = &now
+	data := JobWebhookData(job)
+	data["closedApplications"] = impact.ApplicationsToClose
+	return impact, QueueWebhookEvent(ctx, actorID, models.EventJobDeleted, data)
+}
+
+// RestoreJob takes a job out of the trash and reopens the applications that
+// were closed when it was deleted. Bookmarks and search log entries are not
+// brought back. Run it in WithTransaction.
+func RestoreJob(ctx context.Context, jobID, actorID primitive.ObjectID) (*models.Job, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	update := bson.M{
+		"$set":   bson.M{"updatedAt": time.Now().UTC()},
+		"$unset": bson.M{"deletedAt": "", "deletedBy": ""},
+	}
+
+	var job models.Job
+	err := db.DB.Collection("jobs").FindOneAndUpdate(ctx, Deleted(bson.M{"_id": jobID}), update, opts).Decode(&job)
+	if err != nil {
+		return nil, err
+	}
+
+	reopen := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"status": "$statusBeforeClose", "statusChangedAt": time.Now().UTC(), "version": nextVersion}}},
+		{{Key: "$unset", Value: "statusBeforeClose"}},
+	}
+	_, err = db.DB.Collection("applications").UpdateMany(ctx,
+		bson.M{"jobId": jobID, "status": models.StatusClosed, "statusBeforeClose": bson.M{"$exists": true}},
+		reopen)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, QueueWebhookEvent(ctx, actorID, models.EventJobRestored, JobWebhookData(job))
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/utils"
+)
+
+var (
+	ErrEmptyImport         = errors.New("import file contains no rows")
+	ErrUnsupportedFormat   = errors.New("unsupported import format")
+	ErrMissingCSVHeader    = errors.New("csv header row is missing required columns")
+	jobImportRequiredNames = []string{"jobName", "type", "location", "salaryHigh", "salaryLow", "company", "imageLink", "currency", "mandatoryRequirements", "jobDescription", "industry"}
+)
+
+// MaxJobImportRows caps the size of a single upload.
+const MaxJobImportRows = 5000
+
+// JobImportRow is a single parsed row of an import file together with the
+// validation errors found for it. Line is the 1-based line in the source file.
+type JobImportRow struct {
+	Line   int        `json:"line"`
+	Job    models.Job `json:"-"`
+	Errors []string   `json:"errors,omitempty"`
+
+	malformed bool
+}
+
+// ParseJobImport reads an import file in the given format ("csv" or "jsonl")
+// and validates every row. Parse problems on a row are reported on that row
+// instead of failing the whole import.
+func ParseJobImport(format string, r io.Reader) ([]JobImportRow, error) {
+	var rows []JobImportRow
+	var err error
+
+	switch strings.ToLower(format) {
+	case "csv":
+		rows, err = parseJobsCSV(r)
+	case "jsonl", "ndjson", "json":
+		rows, err = parseJobsJSONLines(r)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, ErrEmptyImport
+	}
+	if len(rows) > MaxJobImportRows {
+		return nil, fmt.Errorf("import is limited to %d rows, got %d", MaxJobImportRows, len(rows))
+	}
+
+	seen := map[string]int{}
+	for i := range rows {
+		if rows[i].malformed {
+			continue
+		}
+		job := &rows[i].Job
+		rows[i].Errors = append(rows[i].Errors, ValidateJob(job)...)
+
+		if job.ExternalRef == "" {
+			job.ExternalRef = JobExternalRef(job)
+		}
+		if line, ok := seen[job.ExternalRef]; ok {
+			rows[i].Errors = append(rows[i].Errors, fmt.Sprintf("externalRef %q duplicates line %d", job.ExternalRef, line))
+			continue
+		}
+		seen[job.ExternalRef] = rows[i].Line
+	}
+
+	return rows, nil
+}
+
+// ValidateJob applies the models.Job rules to a job and returns a list of
+// human readable problems. The currency is normalised to upper case.
+func ValidateJob(job *models.Job) []string {
+	var problems []string
+
+	minLength := func(field, value string, min int) {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			problems = append(problems, field+" is required")
+		} else if len(value) < min {
+			problems = append(problems, fmt.Sprintf("%s must be at least %d characters", field, min))
+		}
+	}
+
+	minLength("jobName", job.JobName, 3)
+	minLength("type", job.Type, 3)
+	minLength("location", job.Location, 3)
+	minLength("company", job.Company, 3)
+	minLength("salaryHigh", job.SalaryHigh, 1)
+	minLength("salaryLow", job.SalaryLow, 1)
+	minLength("imageLink", job.ImageLink, 1)
+	minLength("jobDescription", job.JobDescription, 1)
+	minLength("industry", job.Industry, 1)
+
+	job.Currency = utils.Currency(utils.UpperCaseString(strings.TrimSpace(string(job.Currency))))
+	if job.Currency == "" {
+		problems = append(problems, "currency is required")
+	} else if !job.Currency.IsValid() {
+		problems = append(problems, fmt.Sprintf("currency %q is not supported", job.Currency))
+	}
+
+	if len(job.MandatoryRequirements) == 0 {
+		problems = append(problems, "mandatoryRequirements is required")
+	}
+
+	return append(problems, ValidateJobScreening(job)...)
+}
+
+// JobExternalRef derives a stable reference for rows that do not carry one,
+// so that re-uploading the same file updates jobs instead of duplicating them.
+func JobExternalRef(job *models.Job) string {
+	key := strings.ToLower(strings.Join([]string{
+		strings.TrimSpace(job.Company),
+		strings.TrimSpace(job.JobName),
+		strings.TrimSpace(job.Location),
+	}, "|"))
+	sum := sha1.Sum([]byte(key))
+	return "auto:" + hex.EncodeToString(sum[:])
+}
+
+func parseJobsCSV(r io.Reader) ([]JobImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrEmptyImport
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+	for _, name := range jobImportRequiredNames {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingCSVHeader, name)
+		}
+	}
+
+	var rows []JobImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rows = append(rows, JobImportRow{Line: parseErr.Line, Errors: []string{parseErr.Err.Error()}, malformed: true})
+				continue
+			}
+			return nil, err
+		}
+
+		value := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row := JobImportRow{Line: line}
+		row.Job = models.Job{
+			JobName:               value("jobName"),
+			Type:                  value("type"),
+			Location:              value("location"),
+			SalaryHigh:            value("salaryHigh"),
+			SalaryLow:             value("salaryLow"),
+			Company:               value("company"),
+			ImageLink:             value("imageLink"),
+			Currency:              utils.Currency(value("currency")),
+			MandatoryRequirements: splitList(value("mandatoryRequirements")),
+			OptionalRequirements:  splitList(value("optionalRequirements")),
+			JobDescription:        value("jobDescription"),
+			Industry:              value("industry"),
+			ExternalRef:           value("externalRef"),
+		}
+		row.Job.SalaryHighAmount = SalaryAmount(row.Job.SalaryHigh)
+		row.Job.SalaryLowAmount = SalaryAmount(row.Job.SalaryLow)
+		if sponsored := value("sponsored"); sponsored != "" {
+			row.Job.Sponsored, err = strconv.ParseBool(sponsored)
+			if err != nil {
+				row.Errors = append(row.Errors, fmt.Sprintf("sponsored %q is not a boolean", sponsored))
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseJobsJSONLines(r io.Reader) ([]JobImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var rows []JobImportRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		// a line holds what a recruiter may post, ids, versions, deletion
+		// and blind review are never taken from the file
+		var record struct {
+			models.JobRequest
+			ExternalRef string `json:"externalRef"`
+		}
+		row := JobImportRow{Line: line}
+		if err := json.Unmarshal([]byte(text), &record); err != nil {
+			row.Errors = []string{"invalid json: " + err.Error()}
+			row.malformed = true
+		}
+		row.Job = models.Job{
+			JobName:               record.JobName,
+			Type:                  record.Type,
+			Location:              record.Location,
+			SalaryHigh:            record.SalaryHigh,
+			SalaryLow:             record.SalaryLow,
+			SalaryHighAmount:      SalaryAmount(record.SalaryHigh),
+			SalaryLowAmount:       SalaryAmount(record.SalaryLow),
+			Company:               record.Company,
+			ImageLink:             record.ImageLink,
+			Sponsored:             record.Sponsored,
+			Currency:              record.Currency,
+			MandatoryRequirements: record.MandatoryRequirements,
+			OptionalRequirements:  record.OptionalRequirements,
+			JobDescription:        record.JobDescription,
+			Industry:              record.Industry,
+			ScreeningQuestions:    ScreeningQuestionsFromDetails(record.ScreeningQuestions),
+			KnockoutAction:        record.KnockoutAction,
+			ExternalRef:           strings.TrimSpace(record.ExternalRef),
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+// splitList splits a CSV cell holding several values separated by ";" or "|".
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == '|' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
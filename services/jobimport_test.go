@@ -0,0 +1,53 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseJobImportJSONLines(t *testing.T) {
+	line := `{"jobName":"Backend Engineer","type":"Full-time","location":"Nairobi","salaryHigh":"120k","salaryLow":"90,000",` +
+		`"company":"Acme","imageLink":"https://acme.test/logo.png","currency":"usd","mandatoryRequirements":["Go"],` +
+		`"jobDescription":"Build APIs","industry":"Software","externalRef":" ext-1 ",` +
+		`"_id":"6650a0c2f1d2e3a4b5c6d7e8","userId":"6650a0c2f1d2e3a4b5c6d7e9","version":9,` +
+		`"deletedAt":"2026-01-01T00:00:00Z","blindReview":{"enabled":true},"salaryHighAmount":1}`
+
+	rows, err := ParseJobImport("jsonl", strings.NewReader(line+"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || len(rows[0].Errors) != 0 {
+		t.Fatalf("ParseJobImport() = %+v", rows)
+	}
+
+	job := rows[0].Job
+	if !job.ID.IsZero() || !job.UserID.IsZero() || job.Version != 0 {
+		t.Errorf("ids or version taken from the file: %v %v %d", job.ID, job.UserID, job.Version)
+	}
+	if job.DeletedAt != nil || job.BlindReview != nil {
+		t.Errorf("deletion or blind review taken from the file: %v %v", job.DeletedAt, job.BlindReview)
+	}
+	if job.SalaryHighAmount == nil || *job.SalaryHighAmount != 120000 || job.SalaryLowAmount == nil || *job.SalaryLowAmount != 90000 {
+		t.Errorf("salary amounts = %v, %v", job.SalaryHighAmount, job.SalaryLowAmount)
+	}
+	if job.ExternalRef != "ext-1" || job.Currency != "USD" {
+		t.Errorf("externalRef = %q, currency = %q", job.ExternalRef, job.Currency)
+	}
+}
+
+func TestParseJobImportReportsRows(t *testing.T) {
+	input := "{\"jobName\":\"x\"}\nnot json\n"
+	rows, err := ParseJobImport("jsonl", strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	if rows[0].Line != 1 || len(rows[0].Errors) == 0 {
+		t.Errorf("invalid job on line 1 not reported: %+v", rows[0])
+	}
+	if rows[1].Line != 2 || !strings.HasPrefix(rows[1].Errors[0], "invalid json") {
+		t.Errorf("malformed line 2 not reported: %+v", rows[1])
+	}
+}
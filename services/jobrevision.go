@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// revisionIgnoredFields are bookkeeping fields that change on every write and
+// are left out of revision diffs.
+var revisionIgnoredFields = map[string]bool{
+	"_id":       true,
+	"version":   true,
+	"updatedAt": true,
+	// derived from salaryHigh and salaryLow
+	"salaryHighAmount": true,
+	"salaryLowAmount":  true,
+}
+
+func jobRevisions() *mongo.Collection {
+	return db.DB.Collection("jobRevisions")
+}
+
+// DiffJobs lists the stored fields whose values differ between two jobs.
+func DiffJobs(before, after models.Job) ([]models.FieldChange, error) {
+	from, err := jobDocument(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := jobDocument(after)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]bool{}
+	for field := range from {
+		fields[field] = true
+	}
+	for field := range to {
+		fields[field] = true
+	}
+
+	var names []string
+	for field := range fields {
+		if !revisionIgnoredFields[field] {
+			names = append(names, field)
+		}
+	}
+	sort.Strings(names)
+
+	changes := []models.FieldChange{}
+	for _, field := range names {
+		if !reflect.DeepEqual(from[field], to[field]) {
+			changes = append(changes, models.FieldChange{Field: field, From: from[field], To: to[field]})
+		}
+	}
+	return changes, nil
+}
+
+func jobDocument(job models.Job) (bson.M, error) {
+	raw, err := bson.Marshal(job)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// NewJobRevision builds the immutable revision for a job as it is after a
+// write. previous is the job before the write, or nil when it was created.
+func NewJobRevision(job models.Job, previous *models.Job, action string, authorID primitive.ObjectID) (models.JobRevision, error) {
+	revision := models.JobRevision{
+		ID:        primitive.NewObjectID(),
+		JobID:     job.ID,
+		Version:   job.Version,
+		Action:    action,
+		Snapshot:  job,
+		Changes:   []models.FieldChange{},
+		AuthorID:  authorID,
+		CreatedAt: time.Now().UTC(),
+	}
+	if previous != nil {
+		changes, err := DiffJobs(*previous, job)
+		if err != nil {
+			return revision, err
+		}
+		revision.Changes = changes
+	}
+	return revision, nil
+}
+
+// RecordJobRevisions stores revisions in the jobRevisions collection.
+func RecordJobRevisions(ctx context.Context, revisions ...models.JobRevision) error {
+	if len(revisions) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, len(revisions))
+	for i := range revisions {
+		docs[i] = revisions[i]
+	}
+	_, err := jobRevisions().InsertMany(ctx, docs)
+	return err
+}
+
+// RecordJobBaseline stores the current state of a job that predates revision
+// history as its first revision, so later diffs have something to start from.
+// Only the caller whose write moves the job to version 1 stores the revision.
+func RecordJobBaseline(ctx context.Context, job *models.Job) error {
+	if job.Version > 0 {
+		return nil
+	}
+	jobs := db.DB.Collection("jobs")
+	recorded := false
+	err := WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		recorded = false
+		result, err := jobs.UpdateOne(sc,
+			AtVersion(bson.M{"_id": job.ID}, 0),
+			bson.M{"$set": bson.M{"version": 1}})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return nil
+		}
+		baseline := *job
+		baseline.Version = 1
+		revision, err := NewJobRevision(baseline, nil, models.RevisionBaseline, job.UserID)
+		if err != nil {
+			return err
+		}
+		if err := RecordJobRevisions(sc, revision); err != nil {
+			return err
+		}
+		recorded = true
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// the job as read is version 1 either way; when another request got
+	// there first its baseline revision stands for it, and a conditional
+	// write based on it conflicts if the job has changed since
+	job.Version = 1
+	if recorded {
+		InvalidateJobCache(ctx, job.ID)
+	}
+	return nil
+}
+
+// GetJobRevisions returns every revision of a job, newest first.
+func GetJobRevisions(ctx context.Context, jobID primitive.ObjectID) ([]models.JobRevision, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: -1}})
+	cursor, err := jobRevisions().Find(ctx, bson.M{"jobId": jobID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	revisions := []models.JobRevision{}
+	if err := cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+	return revisions, nil
+}
+
+// GetJobRevision returns a single revision of a job.
+func GetJobRevision(ctx context.Context, jobID primitive.ObjectID, version int) (*models.JobRevision, error) {
+	var revision models.JobRevision
+	err := jobRevisions().FindOne(ctx, bson.M{"jobId": jobID, "version": version}).Decode(&revision)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrRevisionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Attachment is a file sent along with an email.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Mail is an outgoing email.
+type Mail struct {
+	To          []string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+// Mailer delivers emails. The default mailer only logs them, SMTPMailer is
+// used when SMTP_HOST is set.
+type Mailer interface {
+	Send(ctx context.Context, mail Mail) error
+}
+
+var (
+	mailerMu sync.RWMutex
+	mailer   Mailer = LogMailer{}
+)
+
+// SetMailer replaces the mailer used by SendMail.
+func SetMailer(m Mailer) {
+	mailerMu.Lock()
+	defer mailerMu.Unlock()
+	mailer = m
+}
+
+// SendMail delivers mail through the configured mailer.
+func SendMail(ctx context.Context, mail Mail) error {
+	mailerMu.RLock()
+	m := mailer
+	mailerMu.RUnlock()
+	return m.Send(ctx, mail)
+}
+
+// MailerFromEnv returns an SMTPMailer configured from SMTP_HOST, SMTP_PORT,
+// SMTP_USERNAME, SMTP_PASSWORD and MAIL_FROM, or a LogMailer without
+// SMTP_HOST.
+func MailerFromEnv() Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return LogMailer{}
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	return &SMTPMailer{Addr: host + ":" + port, From: MailFrom(), Auth: auth}
+}
+
+// MailFrom is the sender address of outgoing emails.
+func MailFrom() string {
+	if from := os.Getenv("MAIL_FROM"); from != "" {
+		return from
+	}
+	return "no-reply@jobly.local"
+}
+
+// LogMailer writes emails to the log instead of sending them.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, mail Mail) error {
+	var names []string
+	for _, attachment := range mail.Attachments {
+		names = append(names, attachment.Filename)
+	}
+	log.Printf("mail to %s: %q attachments=%v", strings.Join(mail.To, ", "), mail.Subject, names)
+	return nil
+}
+
+// SMTPMailer sends emails through an SMTP server.
+type SMTPMailer struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, mail Mail) error {
+	if len(mail.To) == 0 {
+		return nil
+	}
+	message, err := BuildMIMEMessage(m.From, mail)
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(m.Addr, m.Auth, m.From, mail.To, message)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// BuildMIMEMessage renders mail as a multipart/mixed message with a plain
+// text body. Calendar attachments are also added as an inline text/calendar
+// part so mail clients show the invite.
+func BuildMIMEMessage(from string, mail Mail) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(mail.To, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", mail.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeBase64(part, []byte(mail.Body)); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range mail.Attachments {
+		if strings.HasPrefix(attachment.ContentType, "text/calendar") {
+			part, err := writer.CreatePart(textproto.MIMEHeader{
+				"Content-Type":              {attachment.ContentType},
+				"Content-Transfer-Encoding": {"base64"},
+			})
+			if err != nil {
+				return nil, err
+			}
+			if err := writeBase64(part, attachment.Data); err != nil {
+				return nil, err
+			}
+		}
+
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {attachment.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64(part, attachment.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64 writes data base64 encoded in lines of 76 characters.
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		if _, err := fmt.Fprintf(w, "%s\r\n", encoded[:76]); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := fmt.Fprintf(w, "%s\r\n", encoded)
+	return err
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Notify stores a notification and publishes it to the user's open streams.
+// A notification that was already stored under the same id is skipped.
+func Notify(ctx context.Context, notification models.Notification) error {
+	if notification.ID.IsZero() {
+		notification.ID = primitive.NewObjectID()
+	}
+	notification.Read = false
+	notification.ReadAt = nil
+	notification.CreatedAt = time.Now().UTC()
+
+	_, err := db.DB.Collection("notifications").InsertOne(ctx, notification)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	GetBroker().Publish(notification.UserID, notification)
+	return nil
+}
+
+// NotifyNewApplication tells the recruiter of a job about a new applicant,
+// by alias under blind review.
+func NotifyNewApplication(ctx context.Context, job models.Job, application models.Application) error {
+	name := application.Name
+	if IdentityHidden(&job, application) {
+		name = CandidateAlias(application.ID)
+	}
+	return QueueNotification(ctx, models.Notification{
+		UserID: job.UserID,
+		Type:   models.NotificationNewApplication,
+		Title:  fmt.Sprintf("New application for %s", job.JobName),
+		Body:   fmt.Sprintf("%s applied for %s.", name, job.JobName),
+		Data: map[string]string{
+			"applicationId": application.ID.Hex(),
+			"jobId":         job.ID.Hex(),
+		},
+	})
+}
+
+// NotifyStatusChange tells a candidate that the status of their
+// application changed.
+func NotifyStatusChange(ctx context.Context, application models.Application, status string) error {
+	return QueueNotification(ctx, models.Notification{
+		UserID: application.UserID,
+		Type:   models.NotificationStatusChanged,
+		Title:  fmt.Sprintf("Your application for %s is now %s", application.JobName, status),
+		Data: map[string]string{
+			"applicationId":  application.ID.Hex(),
+			"jobId":          application.JobID.Hex(),
+			"previousStatus": application.Status,
+			"status":         status,
+		},
+	})
+}
+
+// NotifyJobClosed tells a candidate that their application was closed
+// because the job was taken down.
+func NotifyJobClosed(ctx context.Context, application models.Application) error {
+	return QueueNotification(ctx, models.Notification{
+		UserID: application.UserID,
+		Type:   models.NotificationStatusChanged,
+		Title:  fmt.Sprintf("%s is no longer open", application.JobName),
+		Body:   fmt.Sprintf("The employer took down %s, so your application was closed.", application.JobName),
+		Data: map[string]string{
+			"applicationId":  application.ID.Hex(),
+			"jobId":          application.JobID.Hex(),
+			"previousStatus": application.Status,
+			"status":         models.StatusClosed,
+		},
+	})
+}
+
+// NotifyNewMessage tells the other side of a thread about a new message.
+func NotifyNewMessage(ctx context.Context, message models.Message) error {
+	recipient := message.CandidateID
+	if message.SenderID == message.CandidateID {
+		recipient = message.RecruiterID
+	}
+
+	body := message.Body
+	if len(body) > 140 {
+		body = utils.TruncateString(body, 140) + "..."
+	}
+	return QueueNotification(ctx, models.Notification{
+		UserID: recipient,
+		Type:   models.NotificationNewMessage,
+		Title:  fmt.Sprintf("New message from %s about %s", message.SenderName, message.JobName),
+		Body:   body,
+		Data: map[string]string{
+			"applicationId": message.ApplicationID.Hex(),
+			"messageId":     message.ID.Hex(),
+		},
+	})
+}
+
+// MatchesJobAlert reports whether a job satisfies every criterion of alert.
+func MatchesJobAlert(alert models.JobAlert, job models.Job) bool {
+	contains := func(value, criterion string) bool {
+		return criterion == "" || strings.Contains(strings.ToLower(value), strings.ToLower(strings.TrimSpace(criterion)))
+	}
+	if !contains(job.Location, alert.Location) || !contains(job.Type, alert.Type) ||
+		!contains(job.Industry, alert.Industry) || !contains(job.Company, alert.Company) {
+		return false
+	}
+
+	text := strings.ToLower(strings.Join([]string{
+		job.JobName,
+		job.JobDescription,
+		strings.Join(job.MandatoryRequirements, " "),
+		strings.Join(job.OptionalRequirements, " "),
+	}, " "))
+	for _, keyword := range alert.Keywords {
+		if !strings.Contains(text, strings.ToLower(keyword)) {
+			return false
+		}
+	}
+	return true
+}
+
+// NotifyJobAlerts raises a notification for every active alert the new
+// jobs match. It runs as a TaskJobAlerts task.
+func NotifyJobAlerts(ctx context.Context, jobs ...models.Job) error {
+	cursor, err := db.DB.Collection("jobAlerts").Find(ctx, bson.M{"active": true})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var alert models.JobAlert
+		if err := cursor.Decode(&alert); err != nil {
+			return err
+		}
+
+		matched := false
+		for _, job := range jobs {
+			if !MatchesJobAlert(alert, job) {
+				continue
+			}
+			matched = true
+			err := Notify(ctx, models.Notification{
+				UserID: alert.UserID,
+				Type:   models.NotificationJobAlert,
+				Title:  fmt.Sprintf("New job matching %q: %s at %s", alert.Name, job.JobName, job.Company),
+				Data: map[string]string{
+					"alertId": alert.ID.Hex(),
+					"jobId":   job.ID.Hex(),
+				},
+			})
+			if err != nil {
+				log.Println("error sending job alert", err)
+			}
+		}
+
+		if matched {
+			now := time.Now().UTC()
+			_, err := db.DB.Collection("jobAlerts").UpdateOne(ctx, bson.M{"_id": alert.ID}, bson.M{"$set": bson.M{"lastMatchedAt": now}})
+			if err != nil {
+				log.Println("error updating job alert", err)
+			}
+		}
+	}
+	return cursor.Err()
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var warnNoTransactions sync.Once
+
+// WithTransaction runs fn in a multi-document transaction. Tasks enqueued
+// with the session context are committed together with the domain writes,
+// so follow-up work is queued if and only if the write happened. fn may be
+// retried on transient errors and must not write the HTTP response.
+//
+// Standalone servers do not support transactions; there fn runs without
+// one.
+func WithTransaction(ctx context.Context, fn func(ctx mongo.SessionContext) error) error {
+	session, err := db.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	if transactionsUnsupported(err) {
+		warnNoTransactions.Do(func() {
+			log.Println("transactions are not supported by the database, writes are not atomic")
+		})
+		return mongo.WithSession(ctx, session, fn)
+	}
+	return err
+}
+
+// transactionsUnsupported detects the IllegalOperation error a standalone
+// server answers the first write of a transaction with.
+func transactionsUnsupported(err error) bool {
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) {
+		return commandErr.Code == 20 || strings.Contains(commandErr.Message, "Transaction numbers")
+	}
+	var writeErr mongo.WriteException
+	if errors.As(err, &writeErr) && writeErr.WriteConcernError == nil {
+		for _, e := range writeErr.WriteErrors {
+			if e.Code == 20 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// PeriodicWorker runs a maintenance function on a fixed interval until it
+// is stopped. Runs never overlap.
+type PeriodicWorker struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+	stop     chan struct{}
+	done     sync.WaitGroup
+	state    workerState
+}
+
+func NewPeriodicWorker(name string, interval time.Duration, run func(ctx context.Context) error) *PeriodicWorker {
+	return &PeriodicWorker{
+		name:     name,
+		interval: interval,
+		run:      run,
+		stop:     make(chan struct{}),
+	}
+}
+
+func (w *PeriodicWorker) Start() {
+	w.state.setRunning(true)
+	w.done.Add(1)
+	go func() {
+		defer w.done.Done()
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+			}
+			w.runOnce()
+		}
+	}()
+}
+
+func (w *PeriodicWorker) runOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), w.interval)
+	defer cancel()
+
+	// stopping cancels a run in progress
+	go func() {
+		select {
+		case <-w.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := w.run(ctx)
+	if err != nil {
+		log.Printf("%s failed: %v", w.name, err)
+	}
+	w.state.recordRun(err)
+}
+
+// Stop cancels the run in progress and waits for it to return.
+func (w *PeriodicWorker) Stop(ctx context.Context) error {
+	w.state.setRunning(false)
+	close(w.stop)
+	finished := make(chan struct{})
+	go func() {
+		w.done.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *PeriodicWorker) Status() WorkerStatus {
+	return w.state.status(w.name)
+}
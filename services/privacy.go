@@ -0,0 +1,620 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	TaskEraseUser = "privacy.erase"
+
+	defaultErasureDelayDays = 7
+	erasedMessageBody       = "[erased]"
+	erasedNoteBody          = "[erased]"
+)
+
+var ErrErasurePending = errors.New("an erasure request is already pending")
+
+type eraseUserTask struct {
+	RequestID primitive.ObjectID `bson:"requestId"`
+}
+
+// exportedUser is the account record as it appears in an export, without
+// the password hash.
+type exportedUser struct {
+	ID        primitive.ObjectID `json:"_id"`
+	Name      string             `json:"name"`
+	Email     string             `json:"email"`
+	Phone     string             `json:"phone"`
+	Address   string             `json:"address"`
+	Role      string             `json:"role"`
+	DeletedAt *time.Time         `json:"deletedAt,omitempty"`
+}
+
+// exportedFile is an entry of files.json, mapping a stored file to the
+// record it belongs to.
+type exportedFile struct {
+	Path     string             `json:"path"`
+	Source   string             `json:"source"`
+	SourceID primitive.ObjectID `json:"sourceId"`
+	Filename string             `json:"filename"`
+}
+
+func init() {
+	RegisterTaskHandler(TaskEraseUser, func(ctx context.Context, task models.Task) error {
+		var payload eraseUserTask
+		if err := DecodeTaskPayload(task, &payload); err != nil {
+			return err
+		}
+		return runErasure(ctx, payload.RequestID)
+	})
+}
+
+func erasureRequests() *mongo.Collection {
+	return db.DB.Collection("erasureRequests")
+}
+
+// ErasureDelay is the cooling-off period between an erasure request and the
+// erasure, read from ERASURE_DELAY_DAYS. The request can be cancelled until
+// then.
+func ErasureDelay() time.Duration {
+	days := defaultErasureDelayDays
+	if value := os.Getenv("ERASURE_DELAY_DAYS"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			days = parsed
+		} else {
+			log.Println("invalid ERASURE_DELAY_DAYS, using the default", value)
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// WriteDataExport writes a ZIP archive of everything stored about a
+// candidate to w: one JSON file per collection, the original resume and
+// attachment files, and files.json listing where each file came from.
+func WriteDataExport(ctx context.Context, w io.Writer, userID primitive.ObjectID) error {
+	archive := zip.NewWriter(w)
+
+	var user models.User
+	if err := db.DB.Collection("users").FindOne(ctx, bson.M{"_id": userID}).Decode(&user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	err := writeJSON(archive, "user.json", exportedUser{
+		ID:        user.ID,
+		Name:      user.Name,
+		Email:     user.Email,
+		Phone:     user.Phone,
+		Address:   user.Address,
+		Role:      user.Role,
+		DeletedAt: user.DeletedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	files := []exportedFile{}
+	addFile := func(dir string, sourceID primitive.ObjectID, source, filename string, fileID primitive.ObjectID) error {
+		data, err := DownloadFile(ctx, fileID)
+		if err != nil {
+			// a file that is gone is listed without a path
+			log.Println("error reading file for export", fileID.Hex(), err)
+			files = append(files, exportedFile{Source: source, SourceID: sourceID, Filename: filename})
+			return nil
+		}
+		name := path.Join(dir, sourceID.Hex()+"-"+safeFilename(filename))
+		entry, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(data); err != nil {
+			return err
+		}
+		files = append(files, exportedFile{Path: name, Source: source, SourceID: sourceID, Filename: filename})
+		return nil
+	}
+
+	var profiles []models.Profile
+	if err := findAll(ctx, "profiles", bson.M{"userId": userID}, &profiles); err != nil {
+		return err
+	}
+	for i, profile := range profiles {
+		if profile.DefaultResume == nil {
+			continue
+		}
+		if fileID, err := ResumeFileID(*profile.DefaultResume); err == nil {
+			if err := addFile("resumes", profile.ID, "profile", profile.DefaultResume.Filename, fileID); err != nil {
+				return err
+			}
+		}
+		profiles[i].DefaultResume.Data = nil
+	}
+	if err := writeJSON(archive, "profile.json", profiles); err != nil {
+		return err
+	}
+
+	var applications []models.Application
+	if err := findAll(ctx, "applications", bson.M{"userId": userID}, &applications); err != nil {
+		return err
+	}
+	for i, application := range applications {
+		if fileID, err := ResumeFileID(application.Resume); err == nil {
+			if err := addFile("resumes", application.ID, "application", application.Resume.Filename, fileID); err != nil {
+				return err
+			}
+		}
+		applications[i].Resume.Data = nil
+		// recruiter notes and ratings are the recruiter's working data
+		applications[i].Notes = nil
+		applications[i].Scorecards = nil
+		applications[i].AverageRating = 0
+	}
+	if err := writeJSON(archive, "applications.json", applications); err != nil {
+		return err
+	}
+
+	var messages []models.Message
+	if err := findAll(ctx, "messages", bson.M{"candidateId": userID}, &messages); err != nil {
+		return err
+	}
+	for _, message := range messages {
+		for _, attachment := range message.Attachments {
+			if err := addFile("attachments", message.ID, "message", attachment.Filename, attachment.FileID); err != nil {
+				return err
+			}
+		}
+	}
+	if err := writeJSON(archive, "messages.json", messages); err != nil {
+		return err
+	}
+
+	var interviews []models.Interview
+	if err := findAll(ctx, "interviews", bson.M{"candidateId": userID}, &interviews); err != nil {
+		return err
+	}
+	if err := writeJSON(archive, "interviews.json", interviews); err != nil {
+		return err
+	}
+
+	var bookmarks []models.Bookmark
+	if err := findAll(ctx, "bookmarks", bson.M{"userId": userID}, &bookmarks); err != nil {
+		return err
+	}
+	if err := writeJSON(archive, "bookmarks.json", bookmarks); err != nil {
+		return err
+	}
+
+	var searchLogs []models.SearchLog
+	if err := findAll(ctx, "searchlog", bson.M{"userId": userID}, &searchLogs); err != nil {
+		return err
+	}
+	if err := writeJSON(archive, "searchlog.json", searchLogs); err != nil {
+		return err
+	}
+
+	var notifications []models.Notification
+	if err := findAll(ctx, "notifications", bson.M{"userId": userID}, &notifications); err != nil {
+		return err
+	}
+	if err := writeJSON(archive, "notifications.json", notifications); err != nil {
+		return err
+	}
+
+	var alerts []models.JobAlert
+	if err := findAll(ctx, "jobAlerts", bson.M{"userId": userID}, &alerts); err != nil {
+		return err
+	}
+	if err := writeJSON(archive, "job-alerts.json", alerts); err != nil {
+		return err
+	}
+
+	var requests []models.ErasureRequest
+	if err := findAll(ctx, "erasureRequests", bson.M{"userId": userID}, &requests); err != nil {
+		return err
+	}
+	if err := writeJSON(archive, "erasure-requests.json", requests); err != nil {
+		return err
+	}
+
+	if err := writeJSON(archive, "files.json", files); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+func findAll(ctx context.Context, collection string, filter bson.M, results interface{}) error {
+	cursor, err := db.DB.Collection(collection).Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, results)
+}
+
+func writeJSON(archive *zip.Writer, name string, v interface{}) error {
+	entry, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// safeFilename keeps the base name of an uploaded file so it cannot escape
+// its directory in the archive.
+func safeFilename(filename string) string {
+	name := path.Base(strings.ReplaceAll(filename, "\\", "/"))
+	if name == "." || name == "/" || name == ".." {
+		return "file"
+	}
+	return name
+}
+
+// RequestErasure schedules the erasure of a candidate's data after the
+// cooling-off period.
+func RequestErasure(ctx context.Context, userID primitive.ObjectID) (*models.ErasureRequest, error) {
+	count, err := erasureRequests().CountDocuments(ctx, bson.M{"userId": userID, "status": models.ErasurePending})
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrErasurePending
+	}
+
+	now := time.Now().UTC()
+	request := models.ErasureRequest{
+		ID:           primitive.NewObjectID(),
+		UserID:       userID,
+		Status:       models.ErasurePending,
+		RequestedAt:  now,
+		ScheduledFor: now.Add(ErasureDelay()),
+	}
+	err = WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		if _, err := erasureRequests().InsertOne(sc, request); err != nil {
+			return err
+		}
+		return EnqueueTask(sc, TaskEraseUser, eraseUserTask{RequestID: request.ID}, TaskOptions{RunAt: request.ScheduledFor})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// GetErasureRequest returns the latest erasure request of a candidate, or
+// mongo.ErrNoDocuments if there is none.
+func GetErasureRequest(ctx context.Context, userID primitive.ObjectID) (*models.ErasureRequest, error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "requestedAt", Value: -1}})
+	var request models.ErasureRequest
+	if err := erasureRequests().FindOne(ctx, bson.M{"userId": userID}, opts).Decode(&request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// CancelErasure cancels the pending erasure request of a candidate. It
+// returns mongo.ErrNoDocuments if nothing is pending. The queued task finds
+// the request cancelled and does nothing.
+func CancelErasure(ctx context.Context, userID primitive.ObjectID) (*models.ErasureRequest, error) {
+	update := bson.M{"$set": bson.M{"status": models.ErasureCancelled, "cancelledAt": time.Now().UTC()}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var request models.ErasureRequest
+	err := erasureRequests().FindOneAndUpdate(ctx, bson.M{"userId": userID, "status": models.ErasurePending}, update, opts).Decode(&request)
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func runErasure(ctx context.Context, requestID primitive.ObjectID) error {
+	var request models.ErasureRequest
+	err := erasureRequests().FindOne(ctx, bson.M{"_id": requestID}).Decode(&request)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if request.Status != models.ErasurePending {
+		return nil
+	}
+
+	var report models.ErasureReport
+	err = WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		var err error
+		if report, err = EraseUser(sc, request.UserID); err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		_, err = erasureRequests().UpdateOne(sc, bson.M{"_id": request.ID},
+			bson.M{"$set": bson.M{"status": models.ErasureCompleted, "completedAt": now, "report": report}})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	meta := AuditMeta{Actor: models.AuditActor{Role: "system"}}
+	if err := RecordAudit(ctx, meta, models.AuditUserErase, models.AuditTargetUser, request.UserID, nil, report); err != nil {
+		log.Println("error writing audit log", models.AuditUserErase, request.UserID.Hex(), err)
+	}
+	return nil
+}
+
+// EraseUser removes the personal data of a candidate. Records that feed
+// statistics are kept but anonymized: applications keep their job, status,
+// match and review data, messages keep their place in the thread, and
+// interviews keep their schedule. They still carry the user id, which no
+// longer resolves to anyone. Everything else is deleted. Files are deleted
+// by the task queue. The candidate's identity is also removed from the
+// audit log, the recruiters' notifications, webhook deliveries and queued
+// tasks, see eraseTraces.
+func EraseUser(ctx context.Context, userID primitive.ObjectID) (models.ErasureReport, error) {
+	var report models.ErasureReport
+
+	applicationIDs, err := db.DB.Collection("applications").Distinct(ctx, "_id", bson.M{"userId": userID})
+	if err != nil {
+		return report, err
+	}
+
+	anonymized, files, err := anonymizeApplications(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return report, err
+	}
+	report.ApplicationsAnonymized = anonymized
+	report.FilesDeleted += files
+
+	var messages []models.Message
+	if err := findAll(ctx, "messages", bson.M{"senderId": userID}, &messages); err != nil {
+		return report, err
+	}
+	for _, message := range messages {
+		for _, attachment := range message.Attachments {
+			if err := QueueFileDeletion(ctx, attachment.FileID); err != nil {
+				return report, err
+			}
+			report.FilesDeleted++
+		}
+	}
+	result, err := db.DB.Collection("messages").UpdateMany(ctx, bson.M{"senderId": userID}, bson.M{
+		"$set": bson.M{
+			"senderName":  models.ErasedName,
+			"body":        erasedMessageBody,
+			"attachments": []models.MessageAttachment{},
+		},
+	})
+	if err != nil {
+		return report, err
+	}
+	report.MessagesRedacted = result.ModifiedCount
+
+	result, err = db.DB.Collection("interviews").UpdateMany(ctx, bson.M{"candidateId": userID}, bson.M{
+		"$set": bson.M{"candidateName": models.ErasedName, "candidateEmail": ""},
+	})
+	if err != nil {
+		return report, err
+	}
+	report.InterviewsAnonymized = result.ModifiedCount
+
+	var profile models.Profile
+	err = db.DB.Collection("profiles").FindOneAndDelete(ctx, bson.M{"userId": userID}).Decode(&profile)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return report, err
+	}
+	if err == nil {
+		report.RecordsDeleted++
+		if profile.DefaultResume != nil {
+			if fileID, err := ResumeFileID(*profile.DefaultResume); err == nil {
+				if err := QueueFileDeletion(ctx, fileID); err != nil {
+					return report, err
+				}
+				report.FilesDeleted++
+			}
+		}
+	}
+
+	for _, name := range []string{"bookmarks", "searchlog", "notifications", "jobAlerts", "users"} {
+		filter := bson.M{"userId": userID}
+		if name == "users" {
+			filter = bson.M{"_id": userID}
+		}
+		deleted, err := db.DB.Collection(name).DeleteMany(ctx, filter)
+		if err != nil {
+			return report, err
+		}
+		report.RecordsDeleted += deleted.DeletedCount
+	}
+	forgetAccount(ctx, userID)
+
+	if err := eraseTraces(ctx, userID, applicationIDs, &report); err != nil {
+		return report, err
+	}
+	return report, nil
+}
+
+// eraseTraces removes the candidate's identity from the records that copy
+// it about other users' business: the values of audit entries about the
+// candidate and their applications and the email and ip they acted from,
+// which the hash chain only covers through digests; notifications to
+// recruiters about the applications; and the webhook payloads, delivered
+// or not, and queued tasks carrying the candidate.
+func eraseTraces(ctx context.Context, userID primitive.ObjectID, applicationIDs []interface{}, report *models.ErasureReport) error {
+	auditLog := db.DB.Collection("auditLog")
+	result, err := auditLog.UpdateMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"targetType": models.AuditTargetUser, "targetId": userID},
+		bson.M{"targetType": models.AuditTargetApplication, "targetId": bson.M{"$in": applicationIDs}},
+	}}, bson.M{"$unset": bson.M{"before": "", "after": ""}})
+	if err != nil {
+		return err
+	}
+	report.AuditEntriesRedacted += result.ModifiedCount
+	result, err = auditLog.UpdateMany(ctx, bson.M{"actor.id": userID}, bson.M{"$unset": bson.M{"actor.email": "", "ip": ""}})
+	if err != nil {
+		return err
+	}
+	report.AuditEntriesRedacted += result.ModifiedCount
+
+	applications := make([]string, 0, len(applicationIDs))
+	for _, id := range applicationIDs {
+		if id, ok := id.(primitive.ObjectID); ok {
+			applications = append(applications, id.Hex())
+		}
+	}
+	redacted, err := eraseNotificationText(ctx, db.DB.Collection("notifications"), "", bson.M{}, applications)
+	if err != nil {
+		return err
+	}
+	report.NotificationsRedacted += redacted
+
+	// the remaining notifications to the candidate were never delivered
+	deleted, err := tasks().DeleteMany(ctx, bson.M{"type": TaskSendNotification, "payload.userId": userID})
+	if err != nil {
+		return err
+	}
+	report.TasksRedacted += deleted.DeletedCount
+	redacted, err = eraseNotificationText(ctx, tasks(), "payload.", bson.M{"type": TaskSendNotification}, applications)
+	if err != nil {
+		return err
+	}
+	report.TasksRedacted += redacted
+
+	// the application payloads name the candidate next to their user id
+	mentions := primitive.Regex{Pattern: regexp.QuoteMeta(`"userId":"` + userID.Hex() + `"`)}
+	var deliveries []models.WebhookDelivery
+	if err := findAll(ctx, "webhookDeliveries", bson.M{"payload": mentions}, &deliveries); err != nil {
+		return err
+	}
+	for _, delivery := range deliveries {
+		payload, err := redactWebhookPayload(delivery.Payload)
+		if err != nil {
+			return err
+		}
+		if _, err := webhookDeliveries().UpdateOne(ctx, bson.M{"_id": delivery.ID}, bson.M{"$set": bson.M{"payload": payload}}); err != nil {
+			return err
+		}
+		report.WebhookPayloadsRedacted++
+	}
+	var queued []models.Task
+	if err := findAll(ctx, "tasks", bson.M{"type": TaskWebhookEvent, "payload.payload": mentions}, &queued); err != nil {
+		return err
+	}
+	for _, task := range queued {
+		var event webhookEventTask
+		if err := DecodeTaskPayload(task, &event); err != nil {
+			return err
+		}
+		payload, err := redactWebhookPayload(event.Payload)
+		if err != nil {
+			return err
+		}
+		if _, err := tasks().UpdateOne(ctx, bson.M{"_id": task.ID}, bson.M{"$set": bson.M{"payload.payload": payload}}); err != nil {
+			return err
+		}
+		report.TasksRedacted++
+	}
+	return nil
+}
+
+// eraseNotificationText drops the text of the notifications about the given
+// applications, which names the candidate or quotes their messages. The
+// notifications live in collection under prefix.
+func eraseNotificationText(ctx context.Context, collection *mongo.Collection, prefix string, filter bson.M, applicationIDs []string) (int64, error) {
+	about := bson.M{prefix + "data.applicationId": bson.M{"$in": applicationIDs}}
+	for key, value := range filter {
+		about[key] = value
+	}
+	result, err := collection.UpdateMany(ctx, about, bson.M{"$unset": bson.M{prefix + "body": ""}})
+	if err != nil {
+		return 0, err
+	}
+	// the candidate's side of the thread is all that is left
+	about[prefix+"type"] = models.NotificationNewMessage
+	titled, err := collection.UpdateMany(ctx, about,
+		bson.M{"$set": bson.M{prefix + "title": "New message from " + models.ErasedName}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount + titled.ModifiedCount, nil
+}
+
+// redactWebhookPayload replaces the candidate's name and email in a
+// rendered webhook payload.
+func redactWebhookPayload(payload string) (string, error) {
+	var envelope map[string]interface{}
+	if err := json.Unmarshal([]byte(payload), &envelope); err != nil {
+		return "", err
+	}
+	if data, ok := envelope["data"].(map[string]interface{}); ok {
+		if _, ok := data["name"]; ok {
+			data["name"] = models.ErasedName
+		}
+		if _, ok := data["email"]; ok {
+			data["email"] = ""
+		}
+	}
+	redacted, err := json.Marshal(envelope)
+	return string(redacted), err
+}
+
+// anonymizeApplications strips the candidate's identity, resume, answers
+// and what recruiter notes say from the applications matching filter and
+// queues their resume files for deletion. It returns how many applications and files it handled.
+func anonymizeApplications(ctx context.Context, filter bson.M) (int64, int64, error) {
+	var applications []models.Application
+	if err := findAll(ctx, "applications", filter, &applications); err != nil {
+		return 0, 0, err
+	}
+	var files int64
+	for _, application := range applications {
+		if fileID, err := ResumeFileID(application.Resume); err == nil {
+			if err := QueueFileDeletion(ctx, fileID); err != nil {
+				return 0, 0, err
+			}
+			files++
+		}
+	}
+	result, err := db.DB.Collection("applications").UpdateMany(ctx, filter, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"name":     models.ErasedName,
+			"email":    "",
+			"resume":   models.PDF{},
+			"erasedAt": time.Now().UTC(),
+			// recruiter notes keep their author and time, not what they
+			// say about the candidate
+			"notes": bson.M{"$cond": bson.A{
+				bson.M{"$isArray": "$notes"},
+				bson.M{"$map": bson.M{"input": "$notes", "as": "note", "in": bson.M{
+					"$mergeObjects": bson.A{"$$note", bson.M{"body": erasedNoteBody}},
+				}}},
+				"$$REMOVE",
+			}},
+			// edits based on the identity read before are refused
+			"version": nextVersion,
+		}}},
+		// knockout reasons quote the answers
+		{{Key: "$unset", Value: bson.A{"resumeText", "profileSnapshot", "answers", "knockoutReasons"}}},
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return result.ModifiedCount, files, nil
+}
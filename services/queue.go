@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DefaultTaskAttempts = 5
+	taskBaseBackoff     = 10 * time.Second
+	taskMaxBackoff      = time.Hour
+	taskLease           = 5 * time.Minute
+	taskPollInterval    = time.Second
+)
+
+// TaskHandler runs a task. Returning an error retries the task with backoff
+// until it runs out of attempts; wrap the error with Permanent to
+// dead-letter it right away.
+type TaskHandler func(ctx context.Context, task models.Task) error
+
+// TaskOptions tune a single enqueued task.
+type TaskOptions struct {
+	// RunAt delays the task; the zero value runs it as soon as possible.
+	RunAt time.Time
+	// MaxAttempts defaults to DefaultTaskAttempts.
+	MaxAttempts int
+}
+
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a task error as not worth retrying.
+func Permanent(err error) error {
+	return permanentError{err}
+}
+
+var (
+	taskHandlersMu sync.RWMutex
+	taskHandlers   = map[string]TaskHandler{}
+)
+
+// RegisterTaskHandler sets the handler for a task type.
+func RegisterTaskHandler(taskType string, handler TaskHandler) {
+	taskHandlersMu.Lock()
+	defer taskHandlersMu.Unlock()
+	taskHandlers[taskType] = handler
+}
+
+func taskHandler(taskType string) (TaskHandler, bool) {
+	taskHandlersMu.RLock()
+	defer taskHandlersMu.RUnlock()
+	handler, ok := taskHandlers[taskType]
+	return handler, ok
+}
+
+func tasks() *mongo.Collection {
+	return db.DB.Collection("tasks")
+}
+
+// EnqueueTask stores a task for the workers. Called with the session
+// context of WithTransaction it is part of the transaction, which makes the
+// tasks collection the outbox of the domain write.
+func EnqueueTask(ctx context.Context, taskType string, payload interface{}, opts ...TaskOptions) error {
+	raw, err := bson.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	task := models.Task{
+		ID:          primitive.NewObjectID(),
+		Type:        taskType,
+		Payload:     raw,
+		Status:      models.TaskPending,
+		MaxAttempts: DefaultTaskAttempts,
+		RunAt:       now,
+		CreatedAt:   now,
+	}
+	for _, opt := range opts {
+		if !opt.RunAt.IsZero() {
+			task.RunAt = opt.RunAt.UTC()
+		}
+		if opt.MaxAttempts > 0 {
+			task.MaxAttempts = opt.MaxAttempts
+		}
+	}
+
+	_, err = tasks().InsertOne(ctx, task)
+	return err
+}
+
+// DecodeTaskPayload unmarshals the payload of a task into v.
+func DecodeTaskPayload(task models.Task, v interface{}) error {
+	if err := bson.Unmarshal(task.Payload, v); err != nil {
+		return Permanent(fmt.Errorf("decoding %s payload: %w", task.Type, err))
+	}
+	return nil
+}
+
+// TaskBackoff is the delay before retrying a task that failed attempt times.
+func TaskBackoff(attempt int) time.Duration {
+	delay := taskBaseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= taskMaxBackoff {
+			return taskMaxBackoff
+		}
+	}
+	return delay
+}
+
+// RequeueDeadTask gives a dead-lettered task a fresh set of attempts.
+func RequeueDeadTask(ctx context.Context, taskID primitive.ObjectID) error {
+	update := bson.M{
+		"$set":   bson.M{"status": models.TaskPending, "attempts": 0, "runAt": time.Now().UTC()},
+		"$unset": bson.M{"completedAt": ""},
+	}
+	result, err := tasks().UpdateOne(ctx, bson.M{"_id": taskID, "status": models.TaskDead}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// TaskQueue runs workers that claim due tasks with a lease. A task whose
+// worker died is picked up again once its lease expires, so handlers must
+// be safe to run more than once.
+type TaskQueue struct {
+	workers  int
+	workerID string
+	stop     chan struct{}
+	done     sync.WaitGroup
+	state    workerState
+}
+
+func NewTaskQueue(workers int) *TaskQueue {
+	host, _ := os.Hostname()
+	return &TaskQueue{
+		workers:  workers,
+		workerID: fmt.Sprintf("%s-%d", host, os.Getpid()),
+		stop:     make(chan struct{}),
+	}
+}
+
+func (q *TaskQueue) Start() {
+	q.state.setRunning(true)
+	for i := 0; i < q.workers; i++ {
+		q.done.Add(1)
+		go q.work()
+	}
+}
+
+// Drain stops claiming new tasks and waits for the running ones to finish.
+// Tasks still running when ctx ends are retried after their lease expires.
+func (q *TaskQueue) Drain(ctx context.Context) error {
+	q.state.setRunning(false)
+	close(q.stop)
+	finished := make(chan struct{})
+	go func() {
+		q.done.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *TaskQueue) Status() WorkerStatus {
+	return q.state.status("task queue")
+}
+
+func (q *TaskQueue) work() {
+	defer q.done.Done()
+	ticker := time.NewTicker(taskPollInterval)
+	defer ticker.Stop()
+	for {
+		for q.runNext() {
+			select {
+			case <-q.stop:
+				return
+			default:
+			}
+		}
+		select {
+		case <-q.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNext claims and runs one due task. It reports whether there was one.
+func (q *TaskQueue) runNext() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), taskLease)
+	defer cancel()
+
+	now := time.Now().UTC()
+	filter := bson.M{
+		"status":      bson.M{"$in": bson.A{models.TaskPending, models.TaskRunning}},
+		"runAt":       bson.M{"$lte": now},
+		"lockedUntil": bson.M{"$lte": now},
+	}
+	update := bson.M{
+		"$set": bson.M{"status": models.TaskRunning, "lockedUntil": now.Add(taskLease), "lockedBy": q.workerID},
+		"$inc": bson.M{"attempts": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "runAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var task models.Task
+	err := tasks().FindOneAndUpdate(ctx, filter, update, opts).Decode(&task)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Println("error claiming task", err)
+		}
+		return false
+	}
+
+	err = q.run(ctx, task)
+	if err := q.finish(ctx, task, err); err != nil {
+		log.Println("error recording task result", err)
+	}
+	return true
+}
+
+func (q *TaskQueue) run(ctx context.Context, task models.Task) (err error) {
+	handler, ok := taskHandler(task.Type)
+	if !ok {
+		return Permanent(fmt.Errorf("no handler for task type %q", task.Type))
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+	return handler(ctx, task)
+}
+
+func (q *TaskQueue) finish(ctx context.Context, task models.Task, runErr error) error {
+	now := time.Now().UTC()
+	filter := bson.M{"_id": task.ID, "lockedBy": q.workerID}
+
+	if runErr == nil {
+		update := bson.M{"$set": bson.M{"status": models.TaskDone, "completedAt": now, "lockedUntil": time.Time{}}}
+		_, err := tasks().UpdateOne(ctx, filter, update)
+		return err
+	}
+
+	set := bson.M{"status": models.TaskPending, "runAt": now.Add(TaskBackoff(task.Attempts)), "lockedUntil": time.Time{}}
+	var permanent permanentError
+	if errors.As(runErr, &permanent) || task.Attempts >= task.MaxAttempts {
+		set = bson.M{"status": models.TaskDead, "completedAt": now, "lockedUntil": time.Time{}}
+		log.Printf("task %s (%s) dead-lettered: %v", task.ID.Hex(), task.Type, runErr)
+	}
+
+	update := bson.M{
+		"$set":  set,
+		"$push": bson.M{"errors": models.TaskError{At: now, Attempt: task.Attempts, Error: runErr.Error()}},
+	}
+	_, err := tasks().UpdateOne(ctx, filter, update)
+	return err
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"github.com/weldonkipchirchir/job-listing-server/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	mandatoryWeight = 0.7
+	optionalWeight  = 0.3
+	// maxResumeText caps how much extracted text is stored on an application.
+	maxResumeText = 200_000
+)
+
+var keywordStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "has": true, "have": true, "in": true, "is": true, "it": true,
+	"of": true, "on": true, "or": true, "the": true, "to": true, "with": true, "will": true,
+	"you": true, "your": true, "we": true, "our": true, "must": true, "should": true, "able": true,
+	"ability": true, "experience": true, "experienced": true, "knowledge": true, "strong": true,
+	"good": true, "excellent": true, "year": true, "years": true, "plus": true, "least": true,
+	"proficiency": true, "proficient": true, "understanding": true, "skills": true, "skill": true,
+	"working": true, "work": true, "using": true, "use": true, "etc": true, "including": true,
+}
+
+// ExtractKeywords splits requirement text into lower case keywords, keeping
+// tokens such as "c++", "c#" and "node.js" intact and dropping filler words
+// and plain numbers.
+func ExtractKeywords(text string) []string {
+	var keywords []string
+	seen := map[string]bool{}
+	for _, token := range tokenize(text) {
+		if keywordStopWords[token] || seen[token] || isNumber(token) {
+			continue
+		}
+		seen[token] = true
+		keywords = append(keywords, token)
+	}
+	return keywords
+}
+
+func tokenize(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '+' || r == '#' || r == '.')
+	})
+
+	var tokens []string
+	for _, field := range fields {
+		field = strings.Trim(field, ".")
+		if len(field) > 1 || field == "c" || field == "r" {
+			tokens = append(tokens, field)
+		}
+	}
+	return tokens
+}
+
+func isNumber(token string) bool {
+	for _, r := range token {
+		if !unicode.IsDigit(r) && r != '+' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// ScoreResume compares resume text with the requirements of a job. The score
+// runs from 0 to 100, weighting mandatory keywords at 70% and optional ones at
+// 30% when the job has both.
+func ScoreResume(text string, mandatory, optional []string) models.ResumeMatch {
+	present := map[string]bool{}
+	for _, token := range tokenize(text) {
+		present[token] = true
+	}
+
+	match := models.ResumeMatch{Matched: []string{}, Missing: []string{}}
+
+	check := func(requirements []string) (matched, total int) {
+		var keywords []string
+		for _, requirement := range requirements {
+			keywords = append(keywords, ExtractKeywords(requirement)...)
+		}
+		seen := map[string]bool{}
+		for _, keyword := range keywords {
+			if seen[keyword] {
+				continue
+			}
+			seen[keyword] = true
+			total++
+			if present[keyword] {
+				matched++
+				match.Matched = append(match.Matched, keyword)
+			} else {
+				match.Missing = append(match.Missing, keyword)
+			}
+		}
+		return matched, total
+	}
+
+	mandatoryMatched, mandatoryTotal := check(mandatory)
+	optionalMatched, optionalTotal := check(optional)
+
+	match.MandatoryMatched = mandatoryMatched
+	match.MandatoryTotal = mandatoryTotal
+	match.OptionalMatched = optionalMatched
+	match.OptionalTotal = optionalTotal
+
+	switch {
+	case mandatoryTotal > 0 && optionalTotal > 0:
+		match.Score = 100 * (mandatoryWeight*float64(mandatoryMatched)/float64(mandatoryTotal) +
+			optionalWeight*float64(optionalMatched)/float64(optionalTotal))
+	case mandatoryTotal > 0:
+		match.Score = 100 * float64(mandatoryMatched) / float64(mandatoryTotal)
+	case optionalTotal > 0:
+		match.Score = 100 * float64(optionalMatched) / float64(optionalTotal)
+	}
+	match.Score = float64(int(match.Score*10+0.5)) / 10
+
+	sort.Strings(match.Matched)
+	sort.Strings(match.Missing)
+	match.AnalyzedAt = time.Now().UTC()
+	return match
+}
+
+// AnalyzeResume extracts the text of a PDF resume and scores it against the
+// requirements of the job.
+func AnalyzeResume(data []byte, job models.Job) (string, models.ResumeMatch, error) {
+	text, err := utils.ExtractPDFText(data)
+	if err != nil {
+		return "", models.ResumeMatch{}, err
+	}
+	text = utils.TruncateString(text, maxResumeText)
+	return text, ScoreResume(text, job.MandatoryRequirements, job.OptionalRequirements), nil
+}
+
+// AnalyzeStoredResume is AnalyzeResume for a resume already stored in GridFS.
+func AnalyzeStoredResume(ctx context.Context, resume models.PDF, job models.Job) (string, models.ResumeMatch, error) {
+	fileID, err := ResumeFileID(resume)
+	if err != nil {
+		return "", models.ResumeMatch{}, err
+	}
+	data, err := DownloadFile(ctx, fileID)
+	if err != nil {
+		return "", models.ResumeMatch{}, err
+	}
+	return AnalyzeResume(data, job)
+}
+
+// analyzeApplicationResume stores the text and match of the resume file of
+// an application. Nothing is stored when the application has moved on to
+// another resume in the meantime.
+func analyzeApplicationResume(ctx context.Context, applicationID, fileID primitive.ObjectID) error {
+	applications := db.DB.Collection("applications")
+	var application models.Application
+	err := applications.FindOne(ctx, NotDeleted(bson.M{"_id": applicationID})).Decode(&application)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if current, err := ResumeFileID(application.Resume); err != nil || current != fileID {
+		return nil
+	}
+
+	var job models.Job
+	err = db.DB.Collection("jobs").FindOne(ctx, bson.M{"_id": application.JobID}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	data, err := DownloadFile(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	text, match, err := AnalyzeResume(data, job)
+	if err != nil {
+		// the file will not read any better on a retry
+		log.Println("error analyzing resume", applicationID.Hex(), err)
+		return nil
+	}
+
+	hex := fileID.Hex()
+	filter := bson.M{"_id": applicationID, "resume.data": bson.M{"$in": bson.A{hex, primitive.Binary{Data: []byte(hex)}}}}
+	_, err = applications.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"resumeText": text, "match": match}})
+	return err
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	retentionInterval  = 24 * time.Hour
+	retentionBatchSize = 200
+	// retentionPreviewIDs caps the application ids listed in a dry run
+	retentionPreviewIDs = 100
+)
+
+var retentionActions = map[string]bool{
+	models.RetentionDeleteResume:      true,
+	models.RetentionAnonymize:         true,
+	models.RetentionDeleteApplication: true,
+}
+
+func retentionReports() *mongo.Collection {
+	return db.DB.Collection("retentionReports")
+}
+
+// ValidateRetentionPolicy lists what is wrong with a policy.
+func ValidateRetentionPolicy(policy models.RetentionPolicy) []string {
+	problems := []string{}
+	if policy.AfterDays < 1 {
+		problems = append(problems, "afterDays must be at least 1")
+	}
+	if !retentionActions[policy.Action] {
+		problems = append(problems, "action must be delete_resume, anonymize or delete_application")
+	}
+	return problems
+}
+
+// retentionFilter selects the applications a policy applies to as of now:
+// applications for its owner's jobs, deleted ones included, whose status
+// last changed before the cutoff and which the action has not handled yet.
+// Applications from before status changes were timestamped are aged by
+// their creation time.
+func retentionFilter(ctx context.Context, policy models.RetentionPolicy, now time.Time) (bson.M, time.Time, error) {
+	cutoff := now.Add(-time.Duration(policy.AfterDays) * 24 * time.Hour)
+
+	jobs := bson.M{"userId": policy.CreatedBy}
+	if policy.Company != "" {
+		jobs["company"] = policy.Company
+	}
+	jobIDs, err := db.DB.Collection("jobs").Distinct(ctx, "_id", jobs)
+	if err != nil {
+		return nil, cutoff, err
+	}
+	if jobIDs == nil {
+		jobIDs = []interface{}{}
+	}
+
+	filter := bson.M{
+		"jobId": bson.M{"$in": jobIDs},
+		"$or": bson.A{
+			bson.M{"statusChangedAt": bson.M{"$lt": cutoff}},
+			bson.M{"statusChangedAt": bson.M{"$exists": false}, "_id": bson.M{"$lt": primitive.NewObjectIDFromTimestamp(cutoff)}},
+		},
+	}
+	if policy.Status != "" {
+		filter["status"] = policy.Status
+	}
+	switch policy.Action {
+	case models.RetentionDeleteResume:
+		filter["resumePurgedAt"] = bson.M{"$exists": false}
+		filter["erasedAt"] = bson.M{"$exists": false}
+	case models.RetentionAnonymize:
+		filter["erasedAt"] = bson.M{"$exists": false}
+	}
+	return filter, cutoff, nil
+}
+
+// ApplyRetentionPolicy purges the applications a policy applies to. With
+// dryRun nothing changes and the report lists the affected applications.
+func ApplyRetentionPolicy(ctx context.Context, policy models.RetentionPolicy, dryRun bool) (models.RetentionReport, error) {
+	now := time.Now().UTC()
+	report := models.RetentionReport{
+		PolicyID:  policy.ID,
+		OwnerID:   policy.CreatedBy,
+		Company:   policy.Company,
+		Status:    policy.Status,
+		Action:    policy.Action,
+		AfterDays: policy.AfterDays,
+		DryRun:    dryRun,
+		RanAt:     now,
+	}
+
+	filter, cutoff, err := retentionFilter(ctx, policy, now)
+	report.Cutoff = cutoff
+	if err != nil {
+		return report, err
+	}
+
+	if dryRun {
+		return report, previewRetention(ctx, filter, &report)
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(retentionBatchSize)
+	for {
+		var applications []models.Application
+		cursor, err := db.DB.Collection("applications").Find(ctx, filter, opts)
+		if err != nil {
+			return report, err
+		}
+		if err := cursor.All(ctx, &applications); err != nil {
+			return report, err
+		}
+		if len(applications) == 0 {
+			return report, nil
+		}
+
+		handled, files, err := applyRetentionAction(ctx, policy.Action, applications)
+		report.Applications += handled
+		report.Files += files
+		if err != nil {
+			return report, err
+		}
+		// whatever is left no longer matches, stop instead of looping
+		if handled == 0 || len(applications) < retentionBatchSize {
+			return report, nil
+		}
+	}
+}
+
+func previewRetention(ctx context.Context, filter bson.M, report *models.RetentionReport) error {
+	opts := options.Find().SetProjection(bson.M{"resume": 1}).SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := db.DB.Collection("applications").Find(ctx, filter, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	report.ApplicationIDs = []primitive.ObjectID{}
+	for cursor.Next(ctx) {
+		var application models.Application
+		if err := cursor.Decode(&application); err != nil {
+			return err
+		}
+		report.Applications++
+		if _, err := ResumeFileID(application.Resume); err == nil {
+			report.Files++
+		}
+		if len(report.ApplicationIDs) < retentionPreviewIDs {
+			report.ApplicationIDs = append(report.ApplicationIDs, application.ID)
+		}
+	}
+	return cursor.Err()
+}
+
+// applyRetentionAction runs a policy action on a batch of applications. It
+// returns how many applications and files it handled.
+func applyRetentionAction(ctx context.Context, action string, applications []models.Application) (int64, int64, error) {
+	ids := make([]primitive.ObjectID, 0, len(applications))
+	for _, application := range applications {
+		ids = append(ids, application.ID)
+	}
+	batch := bson.M{"_id": bson.M{"$in": ids}}
+
+	var handled, files int64
+	var err error
+	switch action {
+	case models.RetentionDeleteResume:
+		err = WithTransaction(ctx, func(sc mongo.SessionContext) error {
+			handled, files = 0, 0
+			for _, application := range applications {
+				if fileID, err := ResumeFileID(application.Resume); err == nil {
+					if err := QueueFileDeletion(sc, fileID); err != nil {
+						return err
+					}
+					files++
+				}
+			}
+			result, err := db.DB.Collection("applications").UpdateMany(sc, batch, bson.M{
+				"$set":   bson.M{"resumePurgedAt": time.Now().UTC()},
+				"$unset": bson.M{"resume.data": "", "resumeText": ""},
+				"$inc":   bson.M{"version": 1},
+			})
+			if err != nil {
+				return err
+			}
+			handled = result.ModifiedCount
+			return nil
+		})
+	case models.RetentionAnonymize:
+		err = WithTransaction(ctx, func(sc mongo.SessionContext) error {
+			var err error
+			handled, files, err = anonymizeApplications(sc, batch)
+			return err
+		})
+	case models.RetentionDeleteApplication:
+		for _, application := range applications {
+			if err = purgeApplication(ctx, application); err != nil {
+				break
+			}
+			handled++
+			if _, err := ResumeFileID(application.Resume); err == nil {
+				files++
+			}
+		}
+	}
+	return handled, files, err
+}
+
+// RunRetention applies the active policies, limited to those of ownerID
+// unless it is zero. Unless dryRun is set the reports are stored under a
+// common run id. A failing policy is reported and does not stop the others.
+func RunRetention(ctx context.Context, ownerID primitive.ObjectID, dryRun bool) ([]models.RetentionReport, error) {
+	filter := bson.M{"active": true}
+	if !ownerID.IsZero() {
+		filter["createdBy"] = ownerID
+	}
+	var policies []models.RetentionPolicy
+	if err := findAll(ctx, "retentionPolicies", filter, &policies); err != nil {
+		return nil, err
+	}
+
+	runID := primitive.NewObjectID()
+	reports := []models.RetentionReport{}
+	for _, policy := range policies {
+		report, err := ApplyRetentionPolicy(ctx, policy, dryRun)
+		if err != nil {
+			if ctx.Err() != nil {
+				return reports, err
+			}
+			log.Println("error applying retention policy", policy.ID.Hex(), err)
+			report.Error = err.Error()
+		}
+		report.RunID = runID
+		if !dryRun {
+			report.ID = primitive.NewObjectID()
+			if _, err := retentionReports().InsertOne(ctx, report); err != nil {
+				return reports, err
+			}
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// NewRetentionEnforcer returns a worker that applies the retention policies
+// once a day.
+func NewRetentionEnforcer() *PeriodicWorker {
+	return NewPeriodicWorker("retention enforcement", retentionInterval, func(ctx context.Context) error {
+		reports, err := RunRetention(ctx, primitive.NilObjectID, false)
+		for _, report := range reports {
+			if report.Applications > 0 {
+				log.Printf("retention policy %s (%s) purged %d applications and %d files", report.PolicyID.Hex(), report.Action, report.Applications, report.Files)
+			}
+		}
+		return err
+	})
+}
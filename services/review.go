@@ -0,0 +1,127 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/weldonkipchirchir/job-listing-server/models"
+)
+
+const maxTagLength = 32
+
+// NormalizeTags trims and lower cases tags and drops empty and duplicate ones.
+func NormalizeTags(tags []string) ([]string, []string) {
+	var problems []string
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagLength {
+			problems = append(problems, fmt.Sprintf("tag %q is longer than %d characters", tag, maxTagLength))
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized, problems
+}
+
+// ScorecardCriteria lists the requirements of a job a scorecard rates.
+func ScorecardCriteria(job models.Job) []models.CriterionRating {
+	criteria := []models.CriterionRating{}
+	seen := map[string]bool{}
+	add := func(requirements []string, mandatory bool) {
+		for _, requirement := range requirements {
+			requirement = strings.TrimSpace(requirement)
+			if requirement == "" || seen[strings.ToLower(requirement)] {
+				continue
+			}
+			seen[strings.ToLower(requirement)] = true
+			criteria = append(criteria, models.CriterionRating{Criterion: requirement, Mandatory: mandatory})
+		}
+	}
+	add(job.MandatoryRequirements, true)
+	add(job.OptionalRequirements, false)
+	return criteria
+}
+
+// NormalizeScorecard checks the ratings of a scorecard against the job's
+// requirements, uses the job's spelling of each criterion and computes the
+// scorecard average.
+func NormalizeScorecard(card *models.Scorecard, job models.Job) []string {
+	var problems []string
+
+	criteria := ScorecardCriteria(job)
+	rated := map[string]bool{}
+	ratings := []models.CriterionRating{}
+	for _, rating := range card.Ratings {
+		var criterion *models.CriterionRating
+		for i := range criteria {
+			if strings.EqualFold(criteria[i].Criterion, strings.TrimSpace(rating.Criterion)) {
+				criterion = &criteria[i]
+				break
+			}
+		}
+		if criterion == nil {
+			problems = append(problems, fmt.Sprintf("%q is not a requirement of the job", rating.Criterion))
+			continue
+		}
+		if rated[criterion.Criterion] {
+			problems = append(problems, fmt.Sprintf("%q is rated more than once", criterion.Criterion))
+			continue
+		}
+		rated[criterion.Criterion] = true
+		if rating.Rating < models.MinRating || rating.Rating > models.MaxRating {
+			problems = append(problems, fmt.Sprintf("rating for %q must be between %d and %d", criterion.Criterion, models.MinRating, models.MaxRating))
+		}
+		ratings = append(ratings, models.CriterionRating{
+			Criterion: criterion.Criterion,
+			Mandatory: criterion.Mandatory,
+			Rating:    rating.Rating,
+			Comment:   strings.TrimSpace(rating.Comment),
+		})
+	}
+	if len(card.Ratings) == 0 {
+		problems = append(problems, "at least one rating is required")
+	}
+
+	switch card.Recommendation {
+	case "", models.RecommendStrongYes, models.RecommendYes, models.RecommendNo, models.RecommendStrongNo:
+	default:
+		problems = append(problems, "recommendation must be strong_yes, yes, no or strong_no")
+	}
+
+	card.Ratings = ratings
+	card.Summary = strings.TrimSpace(card.Summary)
+
+	total := 0
+	for _, rating := range ratings {
+		total += rating.Rating
+	}
+	card.Average = 0
+	if len(ratings) > 0 {
+		card.Average = roundRating(float64(total) / float64(len(ratings)))
+	}
+
+	return problems
+}
+
+// AverageRating is the mean of the scorecard averages of an application.
+func AverageRating(cards []models.Scorecard) float64 {
+	if len(cards) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, card := range cards {
+		total += card.Average
+	}
+	return roundRating(total / float64(len(cards)))
+}
+
+func roundRating(value float64) float64 {
+	return math.Round(value*100) / 100
+}
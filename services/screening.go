@@ -0,0 +1,275 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ScreeningResult is the outcome of checking an application's answers
+// against a job's screening questions.
+type ScreeningResult struct {
+	// Problems are answers that are missing or do not fit the question type.
+	Problems []string
+	// KnockoutReasons explain which knockout questions the candidate failed.
+	KnockoutReasons []string
+}
+
+// NormalizeScreeningQuestions validates a recruiter supplied question set,
+// assigns ids to new questions and lower cases yes/no answers.
+func NormalizeScreeningQuestions(questions []models.ScreeningQuestion) []string {
+	var problems []string
+	seen := map[primitive.ObjectID]bool{}
+
+	for i := range questions {
+		q := &questions[i]
+		label := fmt.Sprintf("question %d", i+1)
+
+		if q.ID.IsZero() {
+			q.ID = primitive.NewObjectID()
+		}
+		if seen[q.ID] {
+			problems = append(problems, label+": duplicate _id")
+		}
+		seen[q.ID] = true
+
+		q.Prompt = strings.TrimSpace(q.Prompt)
+		if q.Prompt == "" {
+			problems = append(problems, label+": prompt is required")
+		}
+
+		switch q.Type {
+		case models.QuestionYesNo:
+			for j, answer := range q.AcceptedAnswers {
+				answer = strings.ToLower(strings.TrimSpace(answer))
+				if answer != "yes" && answer != "no" {
+					problems = append(problems, label+": accepted answers must be yes or no")
+				}
+				q.AcceptedAnswers[j] = answer
+			}
+			if q.Knockout && len(q.AcceptedAnswers) == 0 {
+				q.AcceptedAnswers = []string{"yes"}
+			}
+		case models.QuestionMultipleChoice:
+			if len(q.Options) < 2 {
+				problems = append(problems, label+": multiple choice needs at least two options")
+			}
+			for _, answer := range q.AcceptedAnswers {
+				if !containsFold(q.Options, answer) {
+					problems = append(problems, fmt.Sprintf("%s: accepted answer %q is not an option", label, answer))
+				}
+			}
+			if q.Knockout && len(q.AcceptedAnswers) == 0 {
+				problems = append(problems, label+": knockout multiple choice needs accepted answers")
+			}
+		case models.QuestionNumeric:
+			if !finite(q.Min) || !finite(q.Max) {
+				problems = append(problems, label+": min and max must be finite numbers")
+			} else if q.Min != nil && q.Max != nil && *q.Min > *q.Max {
+				problems = append(problems, label+": min is greater than max")
+			}
+			if q.Knockout && q.Min == nil && q.Max == nil {
+				problems = append(problems, label+": knockout numeric question needs a min or max")
+			}
+		case models.QuestionFreeText:
+			if q.Knockout {
+				problems = append(problems, label+": free text questions cannot be knockouts")
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("%s: unknown type %q", label, q.Type))
+		}
+	}
+
+	return problems
+}
+
+// ValidateJobScreening normalises the screening questions and knockout
+// action a job is created or imported with, the same way they are when set
+// on their own.
+func ValidateJobScreening(job *models.Job) []string {
+	action, ok := NormalizeKnockoutAction(job.KnockoutAction)
+	if len(job.ScreeningQuestions) == 0 && job.KnockoutAction == "" {
+		return nil
+	}
+	job.KnockoutAction = action
+
+	var problems []string
+	if !ok {
+		problems = append(problems, "knockoutAction must be reject or flag")
+	}
+	return append(problems, NormalizeScreeningQuestions(job.ScreeningQuestions)...)
+}
+
+// QuestionsFromRequirements adds a yes/no knockout question for every
+// mandatory requirement that no existing question covers yet.
+func QuestionsFromRequirements(requirements []string, questions []models.ScreeningQuestion) []models.ScreeningQuestion {
+	covered := map[string]bool{}
+	for _, q := range questions {
+		if q.Requirement != "" {
+			covered[strings.ToLower(q.Requirement)] = true
+		}
+	}
+
+	for _, requirement := range requirements {
+		requirement = strings.TrimSpace(requirement)
+		if requirement == "" || covered[strings.ToLower(requirement)] {
+			continue
+		}
+		covered[strings.ToLower(requirement)] = true
+		questions = append(questions, models.ScreeningQuestion{
+			ID:              primitive.NewObjectID(),
+			Prompt:          fmt.Sprintf("Do you meet this requirement: %s?", requirement),
+			Type:            models.QuestionYesNo,
+			Required:        true,
+			Knockout:        true,
+			AcceptedAnswers: []string{"yes"},
+			Requirement:     requirement,
+		})
+	}
+	return questions
+}
+
+// ScreeningQuestionViews strips the knockout criteria before questions are
+// shown to candidates.
+func ScreeningQuestionViews(questions []models.ScreeningQuestion) []models.ScreeningQuestionView {
+	views := []models.ScreeningQuestionView{}
+	for _, q := range questions {
+		views = append(views, models.ScreeningQuestionView{
+			ID:       q.ID,
+			Prompt:   q.Prompt,
+			Type:     q.Type,
+			Options:  q.Options,
+			Required: q.Required || q.Knockout,
+		})
+	}
+	return views
+}
+
+// ScreeningQuestionDetails adds the knockout criteria to questions shown to
+// the owner of the job.
+func ScreeningQuestionDetails(questions []models.ScreeningQuestion) []models.ScreeningQuestionDetail {
+	details := []models.ScreeningQuestionDetail{}
+	for _, q := range questions {
+		details = append(details, models.ScreeningQuestionDetail{
+			ScreeningQuestion: q,
+			Knockout:          q.Knockout,
+			AcceptedAnswers:   q.AcceptedAnswers,
+			Min:               q.Min,
+			Max:               q.Max,
+		})
+	}
+	return details
+}
+
+// ScreeningQuestionsFromDetails takes the questions an owner sent, criteria
+// included.
+func ScreeningQuestionsFromDetails(details []models.ScreeningQuestionDetail) []models.ScreeningQuestion {
+	if details == nil {
+		return nil
+	}
+	questions := make([]models.ScreeningQuestion, len(details))
+	for i, detail := range details {
+		questions[i] = detail.ScreeningQuestion
+		questions[i].Knockout = detail.Knockout
+		questions[i].AcceptedAnswers = detail.AcceptedAnswers
+		questions[i].Min = detail.Min
+		questions[i].Max = detail.Max
+	}
+	return questions
+}
+
+// NormalizeKnockoutAction defaults an empty knockout action to reject and
+// reports whether the action is known.
+func NormalizeKnockoutAction(action string) (string, bool) {
+	if action == "" {
+		action = models.KnockoutReject
+	}
+	return action, action == models.KnockoutReject || action == models.KnockoutFlag
+}
+
+// EvaluateScreening checks answers against the questions of a job. Answers
+// are normalised in place; answers to unknown questions are dropped.
+func EvaluateScreening(questions []models.ScreeningQuestion, answers []models.ScreeningAnswer) ([]models.ScreeningAnswer, ScreeningResult) {
+	var result ScreeningResult
+
+	byQuestion := map[primitive.ObjectID]string{}
+	for _, answer := range answers {
+		byQuestion[answer.QuestionID] = strings.TrimSpace(answer.Value)
+	}
+
+	kept := []models.ScreeningAnswer{}
+	for _, q := range questions {
+		value, answered := byQuestion[q.ID]
+		if !answered || value == "" {
+			if q.Required || q.Knockout {
+				result.Problems = append(result.Problems, fmt.Sprintf("%q requires an answer", q.Prompt))
+			}
+			continue
+		}
+
+		switch q.Type {
+		case models.QuestionYesNo:
+			value = strings.ToLower(value)
+			if value != "yes" && value != "no" {
+				result.Problems = append(result.Problems, fmt.Sprintf("%q must be answered yes or no", q.Prompt))
+				continue
+			}
+			if q.Knockout && !containsFold(q.AcceptedAnswers, value) {
+				result.KnockoutReasons = append(result.KnockoutReasons, knockoutReason(q, value))
+			}
+		case models.QuestionMultipleChoice:
+			option, ok := findFold(q.Options, value)
+			if !ok {
+				result.Problems = append(result.Problems, fmt.Sprintf("%q must be one of: %s", q.Prompt, strings.Join(q.Options, ", ")))
+				continue
+			}
+			value = option
+			if q.Knockout && !containsFold(q.AcceptedAnswers, value) {
+				result.KnockoutReasons = append(result.KnockoutReasons, knockoutReason(q, value))
+			}
+		case models.QuestionNumeric:
+			// NaN would pass every min and max comparison
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+				result.Problems = append(result.Problems, fmt.Sprintf("%q must be a number", q.Prompt))
+				continue
+			}
+			if q.Knockout && ((q.Min != nil && number < *q.Min) || (q.Max != nil && number > *q.Max)) {
+				result.KnockoutReasons = append(result.KnockoutReasons, knockoutReason(q, value))
+			}
+		}
+
+		kept = append(kept, models.ScreeningAnswer{QuestionID: q.ID, Value: value})
+	}
+
+	return kept, result
+}
+
+func knockoutReason(q models.ScreeningQuestion, value string) string {
+	if q.Requirement != "" {
+		return fmt.Sprintf("Does not meet mandatory requirement %q (answered %q)", q.Requirement, value)
+	}
+	return fmt.Sprintf("Knockout question %q answered %q", q.Prompt, value)
+}
+
+func finite(value *float64) bool {
+	return value == nil || !(math.IsNaN(*value) || math.IsInf(*value, 0))
+}
+
+func containsFold(values []string, value string) bool {
+	_, ok := findFold(values, value)
+	return ok
+}
+
+func findFold(values []string, value string) (string, bool) {
+	for _, v := range values {
+		if strings.EqualFold(strings.TrimSpace(v), strings.TrimSpace(value)) {
+			return v, true
+		}
+	}
+	return "", false
+}
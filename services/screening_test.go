@@ -0,0 +1,144 @@
+package services
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestEvaluateScreening(t *testing.T) {
+	min, max := 2.0, 10.0
+	relocate := models.ScreeningQuestion{ID: primitive.NewObjectID(), Prompt: "Can you relocate?", Type: models.QuestionYesNo, Knockout: true, AcceptedAnswers: []string{"yes"}}
+	level := models.ScreeningQuestion{ID: primitive.NewObjectID(), Prompt: "Level", Type: models.QuestionMultipleChoice, Options: []string{"Junior", "Senior"}, Knockout: true, AcceptedAnswers: []string{"Senior"}}
+	years := models.ScreeningQuestion{ID: primitive.NewObjectID(), Prompt: "Years of Go", Type: models.QuestionNumeric, Knockout: true, Min: &min, Max: &max, Requirement: "2+ years of Go"}
+	note := models.ScreeningQuestion{ID: primitive.NewObjectID(), Prompt: "Anything else?", Type: models.QuestionFreeText}
+	questions := []models.ScreeningQuestion{relocate, level, years, note}
+
+	answer := func(q models.ScreeningQuestion, value string) models.ScreeningAnswer {
+		return models.ScreeningAnswer{QuestionID: q.ID, Value: value}
+	}
+
+	tests := []struct {
+		name         string
+		answers      []models.ScreeningAnswer
+		wantKept     []models.ScreeningAnswer
+		wantProblems int
+		wantKnockout int
+	}{
+		{
+			name:     "passes",
+			answers:  []models.ScreeningAnswer{answer(relocate, " YES "), answer(level, "senior"), answer(years, "4"), answer(note, "hi")},
+			wantKept: []models.ScreeningAnswer{answer(relocate, "yes"), answer(level, "Senior"), answer(years, "4"), answer(note, "hi")},
+		},
+		{
+			name:         "knocked out",
+			answers:      []models.ScreeningAnswer{answer(relocate, "no"), answer(level, "Junior"), answer(years, "1")},
+			wantKept:     []models.ScreeningAnswer{answer(relocate, "no"), answer(level, "Junior"), answer(years, "1")},
+			wantKnockout: 3,
+		},
+		{
+			name:         "above max",
+			answers:      []models.ScreeningAnswer{answer(relocate, "yes"), answer(level, "Senior"), answer(years, "11")},
+			wantKept:     []models.ScreeningAnswer{answer(relocate, "yes"), answer(level, "Senior"), answer(years, "11")},
+			wantKnockout: 1,
+		},
+		{
+			name:         "missing knockout answers",
+			answers:      []models.ScreeningAnswer{answer(note, "hi")},
+			wantKept:     []models.ScreeningAnswer{answer(note, "hi")},
+			wantProblems: 3,
+		},
+		{
+			name:         "invalid answers",
+			answers:      []models.ScreeningAnswer{answer(relocate, "maybe"), answer(level, "Lead"), answer(years, "many")},
+			wantKept:     []models.ScreeningAnswer{},
+			wantProblems: 3,
+		},
+		{
+			name:         "not a finite number",
+			answers:      []models.ScreeningAnswer{answer(relocate, "yes"), answer(level, "Senior"), answer(years, "NaN")},
+			wantKept:     []models.ScreeningAnswer{answer(relocate, "yes"), answer(level, "Senior")},
+			wantProblems: 1,
+		},
+		{
+			name:         "infinite number",
+			answers:      []models.ScreeningAnswer{answer(relocate, "yes"), answer(level, "Senior"), answer(years, "+Inf")},
+			wantKept:     []models.ScreeningAnswer{answer(relocate, "yes"), answer(level, "Senior")},
+			wantProblems: 1,
+		},
+		{
+			name:     "unknown question",
+			answers:  []models.ScreeningAnswer{answer(relocate, "yes"), answer(level, "Senior"), answer(years, "3"), {QuestionID: primitive.NewObjectID(), Value: "x"}},
+			wantKept: []models.ScreeningAnswer{answer(relocate, "yes"), answer(level, "Senior"), answer(years, "3")},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kept, result := EvaluateScreening(questions, test.answers)
+			if !reflect.DeepEqual(kept, test.wantKept) {
+				t.Errorf("kept = %v, want %v", kept, test.wantKept)
+			}
+			if len(result.Problems) != test.wantProblems {
+				t.Errorf("problems = %q, want %d", result.Problems, test.wantProblems)
+			}
+			if len(result.KnockoutReasons) != test.wantKnockout {
+				t.Errorf("knockout reasons = %q, want %d", result.KnockoutReasons, test.wantKnockout)
+			}
+		})
+	}
+}
+
+func TestEvaluateScreeningRequirementReason(t *testing.T) {
+	min := 2.0
+	q := models.ScreeningQuestion{ID: primitive.NewObjectID(), Prompt: "Years of Go", Type: models.QuestionNumeric, Knockout: true, Min: &min, Requirement: "2+ years of Go"}
+	_, result := EvaluateScreening([]models.ScreeningQuestion{q}, []models.ScreeningAnswer{{QuestionID: q.ID, Value: "1"}})
+	want := []string{`Does not meet mandatory requirement "2+ years of Go" (answered "1")`}
+	if !reflect.DeepEqual(result.KnockoutReasons, want) {
+		t.Errorf("knockout reasons = %q, want %q", result.KnockoutReasons, want)
+	}
+}
+
+func TestValidateJobScreeningRejectsNonFiniteBounds(t *testing.T) {
+	for _, bound := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		bound := bound
+		job := &models.Job{ScreeningQuestions: []models.ScreeningQuestion{
+			{Prompt: "Years", Type: models.QuestionNumeric, Knockout: true, Min: &bound},
+		}}
+		if problems := ValidateJobScreening(job); len(problems) == 0 {
+			t.Errorf("ValidateJobScreening() accepted min %v", bound)
+		}
+	}
+}
+
+func TestScreeningQuestionJSONHidesCriteria(t *testing.T) {
+	min := 3.0
+	question := models.ScreeningQuestion{ID: primitive.NewObjectID(), Prompt: "Years of Go", Type: models.QuestionNumeric, Knockout: true, Min: &min}
+
+	public, err := json.Marshal(question)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{`"knockout"`, `"min"`, `"acceptedAnswers"`} {
+		if strings.Contains(string(public), field) {
+			t.Errorf("public question %s contains %s", public, field)
+		}
+	}
+
+	details := ScreeningQuestionDetails([]models.ScreeningQuestion{question})
+	owner, err := json.Marshal(details[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(owner), `"knockout":true`) || !strings.Contains(string(owner), `"min":3`) {
+		t.Errorf("owner question %s lacks the criteria", owner)
+	}
+	if roundTrip := ScreeningQuestionsFromDetails(details); !reflect.DeepEqual(roundTrip[0], question) {
+		t.Errorf("ScreeningQuestionsFromDetails() = %+v, want %+v", roundTrip[0], question)
+	}
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// StreamTicketTTL is how long a notification stream ticket can be redeemed.
+const StreamTicketTTL = time.Minute
+
+var ErrInvalidStreamTicket = errors.New("invalid or expired stream ticket")
+
+// StreamTicket stands in for the access token on the notification stream,
+// which browsers open with EventSource and so can only authenticate through
+// the URL. A ticket is good for opening one stream within StreamTicketTTL,
+// so one that ends up in a log is of no use.
+type StreamTicket struct {
+	// ID is the SHA-256 of the ticket, the ticket itself is not stored
+	ID        string    `bson:"_id"`
+	UserID    string    `bson:"userId"`
+	Name      string    `bson:"name"`
+	Email     string    `bson:"email"`
+	Role      string    `bson:"role"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+func streamTickets() *mongo.Collection {
+	return db.DB.Collection("streamTickets")
+}
+
+// IssueStreamTicket creates a ticket for the user the claims describe.
+func IssueStreamTicket(ctx context.Context, userID, name, email, role string) (string, time.Time, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(buf)
+
+	expiresAt := time.Now().UTC().Add(StreamTicketTTL)
+	_, err := streamTickets().InsertOne(ctx, StreamTicket{
+		ID:        streamTicketID(ticket),
+		UserID:    userID,
+		Name:      name,
+		Email:     email,
+		Role:      role,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return ticket, expiresAt, nil
+}
+
+// RedeemStreamTicket uses up a ticket and returns who it was issued to.
+func RedeemStreamTicket(ctx context.Context, ticket string) (*StreamTicket, error) {
+	var redeemed StreamTicket
+	filter := bson.M{"_id": streamTicketID(ticket), "expiresAt": bson.M{"$gt": time.Now().UTC()}}
+	err := streamTickets().FindOneAndDelete(ctx, filter).Decode(&redeemed)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrInvalidStreamTicket
+	} else if err != nil {
+		return nil, err
+	}
+	return &redeemed, nil
+}
+
+func streamTicketID(ticket string) string {
+	sum := sha256.Sum256([]byte(ticket))
+	return hex.EncodeToString(sum[:])
+}
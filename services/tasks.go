@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	TaskSendNotification = "notification.send"
+	TaskWebhookEvent     = "webhook.event"
+	TaskInterviewMail    = "interview.mail"
+	TaskJobAlerts        = "job.alerts"
+	TaskDeleteOrphanFile = "gridfs.delete_orphan"
+	TaskAnalyzeResume    = "resume.analyze"
+)
+
+// orphanGracePeriod is how long an upload may stay unreferenced before the
+// guard task deletes it.
+const orphanGracePeriod = 15 * time.Minute
+
+type webhookEventTask struct {
+	OwnerID primitive.ObjectID `bson:"ownerId"`
+	EventID primitive.ObjectID `bson:"eventId"`
+	Event   string             `bson:"event"`
+	Payload string             `bson:"payload"`
+}
+
+type interviewMailTask struct {
+	InterviewID primitive.ObjectID    `bson:"interviewId"`
+	Event       string                `bson:"event"`
+	Previous    *models.InterviewSlot `bson:"previous,omitempty"`
+}
+
+type jobAlertsTask struct {
+	JobIDs []primitive.ObjectID `bson:"jobIds"`
+}
+
+type orphanFileTask struct {
+	FileID primitive.ObjectID `bson:"fileId"`
+}
+
+type analyzeResumeTask struct {
+	ApplicationID primitive.ObjectID `bson:"applicationId"`
+	FileID        primitive.ObjectID `bson:"fileId"`
+}
+
+func init() {
+	RegisterTaskHandler(TaskSendNotification, func(ctx context.Context, task models.Task) error {
+		var notification models.Notification
+		if err := DecodeTaskPayload(task, &notification); err != nil {
+			return err
+		}
+		return Notify(ctx, notification)
+	})
+
+	RegisterTaskHandler(TaskWebhookEvent, func(ctx context.Context, task models.Task) error {
+		var payload webhookEventTask
+		if err := DecodeTaskPayload(task, &payload); err != nil {
+			return err
+		}
+		return createWebhookDeliveries(ctx, payload.OwnerID, payload.EventID, payload.Event, payload.Payload)
+	})
+
+	RegisterTaskHandler(TaskInterviewMail, func(ctx context.Context, task models.Task) error {
+		var payload interviewMailTask
+		if err := DecodeTaskPayload(task, &payload); err != nil {
+			return err
+		}
+		var interview models.Interview
+		err := db.DB.Collection("interviews").FindOne(ctx, bson.M{"_id": payload.InterviewID}).Decode(&interview)
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return NotifyInterview(ctx, interview, payload.Event, payload.Previous)
+	})
+
+	RegisterTaskHandler(TaskJobAlerts, func(ctx context.Context, task models.Task) error {
+		var payload jobAlertsTask
+		if err := DecodeTaskPayload(task, &payload); err != nil {
+			return err
+		}
+		cursor, err := db.DB.Collection("jobs").Find(ctx, NotDeleted(bson.M{"_id": bson.M{"$in": payload.JobIDs}}))
+		if err != nil {
+			return err
+		}
+		var jobs []models.Job
+		if err := cursor.All(ctx, &jobs); err != nil {
+			return err
+		}
+		return NotifyJobAlerts(ctx, jobs...)
+	})
+
+	RegisterTaskHandler(TaskDeleteOrphanFile, func(ctx context.Context, task models.Task) error {
+		var payload orphanFileTask
+		if err := DecodeTaskPayload(task, &payload); err != nil {
+			return err
+		}
+		referenced, err := FileReferenced(ctx, payload.FileID)
+		if err != nil || referenced {
+			return err
+		}
+		return DeleteFile(ctx, payload.FileID)
+	})
+
+	RegisterTaskHandler(TaskAnalyzeResume, func(ctx context.Context, task models.Task) error {
+		var payload analyzeResumeTask
+		if err := DecodeTaskPayload(task, &payload); err != nil {
+			return err
+		}
+		return analyzeApplicationResume(ctx, payload.ApplicationID, payload.FileID)
+	})
+}
+
+// QueueNotification stores and pushes a notification in the background.
+func QueueNotification(ctx context.Context, notification models.Notification) error {
+	// the id is fixed now so a retried task does not notify twice
+	notification.ID = primitive.NewObjectID()
+	return EnqueueTask(ctx, TaskSendNotification, notification)
+}
+
+// QueueInterviewMail emails the participants of an interview about event.
+func QueueInterviewMail(ctx context.Context, interviewID primitive.ObjectID, event string, previous *models.InterviewSlot) error {
+	return EnqueueTask(ctx, TaskInterviewMail, interviewMailTask{InterviewID: interviewID, Event: event, Previous: previous})
+}
+
+// QueueJobAlerts matches new jobs against the saved job alerts.
+func QueueJobAlerts(ctx context.Context, jobs ...models.Job) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+	payload := jobAlertsTask{}
+	for _, job := range jobs {
+		payload.JobIDs = append(payload.JobIDs, job.ID)
+	}
+	return EnqueueTask(ctx, TaskJobAlerts, payload)
+}
+
+// QueueResumeAnalysis extracts the text of an application's resume and
+// scores it in the background, away from the request that uploaded it.
+func QueueResumeAnalysis(ctx context.Context, applicationID, fileID primitive.ObjectID) error {
+	return EnqueueTask(ctx, TaskAnalyzeResume, analyzeResumeTask{ApplicationID: applicationID, FileID: fileID})
+}
+
+// GuardUpload schedules the deletion of a freshly uploaded GridFS file in
+// case the document meant to reference it is never written. Files that are
+// referenced by then are left alone.
+func GuardUpload(ctx context.Context, fileID primitive.ObjectID) error {
+	return EnqueueTask(ctx, TaskDeleteOrphanFile, orphanFileTask{FileID: fileID}, TaskOptions{RunAt: time.Now().Add(orphanGracePeriod)})
+}
+
+// QueueFileDeletion deletes a GridFS file in the background once nothing
+// references it any more.
+func QueueFileDeletion(ctx context.Context, fileID primitive.ObjectID) error {
+	return EnqueueTask(ctx, TaskDeleteOrphanFile, orphanFileTask{FileID: fileID})
+}
+
+// FileReferenced reports whether a GridFS file is still used by an
+// application resume, a profile resume or a message attachment.
+func FileReferenced(ctx context.Context, fileID primitive.ObjectID) (bool, error) {
+	hex := fileID.Hex()
+	// resume ids were stored both as strings and as binary
+	resumeIDs := bson.A{hex, primitive.Binary{Data: []byte(hex)}}
+
+	references := []struct {
+		collection string
+		filter     bson.M
+	}{
+		{"applications", bson.M{"resume.data": bson.M{"$in": resumeIDs}}},
+		{"profiles", bson.M{"defaultResume.data": bson.M{"$in": resumeIDs}}},
+		{"messages", bson.M{"attachments.fileId": fileID}},
+	}
+	for _, reference := range references {
+		count, err := db.DB.Collection(reference.collection).CountDocuments(ctx, reference.filter)
+		if err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
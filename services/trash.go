@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	defaultTrashRetentionDays = 30
+	trashPurgeInterval        = 24 * time.Hour
+)
+
+// TrashPurgeReport counts the records a purge removed for good.
+type TrashPurgeReport struct {
+	Jobs         int64 `json:"jobs"`
+	Applications int64 `json:"applications"`
+	Users        int64 `json:"users"`
+}
+
+// NotDeleted restricts a filter to records that are not in the trash.
+func NotDeleted(filter bson.M) bson.M {
+	filter["deletedAt"] = bson.M{"$exists": false}
+	return filter
+}
+
+// Deleted restricts a filter to records that are in the trash.
+func Deleted(filter bson.M) bson.M {
+	filter["deletedAt"] = bson.M{"$exists": true}
+	return filter
+}
+
+// SoftDelete moves the record matching filter to the trash. It returns
+// mongo.ErrNoDocuments if there is no such record outside the trash.
+func SoftDelete(ctx context.Context, collection *mongo.Collection, filter bson.M, actorID primitive.ObjectID) error {
+	update := bson.M{"$set": bson.M{"deletedAt": time.Now().UTC(), "deletedBy": actorID}}
+	result, err := collection.UpdateOne(ctx, NotDeleted(filter), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Restore takes the record matching filter out of the trash. It returns
+// mongo.ErrNoDocuments if there is no such record in the trash.
+func Restore(ctx context.Context, collection *mongo.Collection, filter bson.M) error {
+	update := bson.M{"$unset": bson.M{"deletedAt": "", "deletedBy": ""}}
+	result, err := collection.UpdateOne(ctx, Deleted(filter), update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// TrashRetention is how long deleted records can be restored, read from
+// TRASH_RETENTION_DAYS.
+func TrashRetention() time.Duration {
+	days := defaultTrashRetentionDays
+	if value := os.Getenv("TRASH_RETENTION_DAYS"); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
+			days = parsed
+		} else {
+			log.Println("invalid TRASH_RETENTION_DAYS, using the default", value)
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// PurgeTrash permanently removes the jobs, applications and users deleted
+// before cutoff, together with the data that only they use.
+func PurgeTrash(ctx context.Context, cutoff time.Time) (TrashPurgeReport, error) {
+	var report TrashPurgeReport
+	expired := bson.M{"deletedAt": bson.M{"$lt": cutoff}}
+
+	result, err := db.DB.Collection("jobs").DeleteMany(ctx, expired)
+	if err != nil {
+		return report, err
+	}
+	report.Jobs = result.DeletedCount
+
+	cursor, err := db.DB.Collection("applications").Find(ctx, expired)
+	if err != nil {
+		return report, err
+	}
+	var applications []models.Application
+	if err := cursor.All(ctx, &applications); err != nil {
+		return report, err
+	}
+	for _, application := range applications {
+		if err := purgeApplication(ctx, application); err != nil {
+			return report, err
+		}
+		report.Applications++
+	}
+
+	cursor, err = db.DB.Collection("users").Find(ctx, expired)
+	if err != nil {
+		return report, err
+	}
+	var users []models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return report, err
+	}
+	for _, user := range users {
+		if err := purgeUser(ctx, user); err != nil {
+			return report, err
+		}
+		report.Users++
+	}
+
+	return report, nil
+}
+
+// purgeApplication deletes an application with its message thread. The
+// resume and attachments are deleted by the task queue.
+func purgeApplication(ctx context.Context, application models.Application) error {
+	return WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		cursor, err := db.DB.Collection("messages").Find(sc, bson.M{"applicationId": application.ID})
+		if err != nil {
+			return err
+		}
+		var messages []models.Message
+		if err := cursor.All(sc, &messages); err != nil {
+			return err
+		}
+		for _, message := range messages {
+			for _, attachment := range message.Attachments {
+				if err := QueueFileDeletion(sc, attachment.FileID); err != nil {
+					return err
+				}
+			}
+		}
+		if _, err := db.DB.Collection("messages").DeleteMany(sc, bson.M{"applicationId": application.ID}); err != nil {
+			return err
+		}
+		if _, err := db.DB.Collection("applications").DeleteOne(sc, bson.M{"_id": application.ID}); err != nil {
+			return err
+		}
+		if fileID, err := ResumeFileID(application.Resume); err == nil {
+			return QueueFileDeletion(sc, fileID)
+		}
+		return nil
+	})
+}
+
+// purgeUser deletes an account with its profile and personal lists.
+// Applications stay with the recruiters they were sent to.
+func purgeUser(ctx context.Context, user models.User) error {
+	return WithTransaction(ctx, func(sc mongo.SessionContext) error {
+		var profile models.Profile
+		err := db.DB.Collection("profiles").FindOneAndDelete(sc, bson.M{"userId": user.ID}).Decode(&profile)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+		if err == nil && profile.DefaultResume != nil {
+			if fileID, err := ResumeFileID(*profile.DefaultResume); err == nil {
+				if err := QueueFileDeletion(sc, fileID); err != nil {
+					return err
+				}
+			}
+		}
+		for _, name := range []string{"bookmarks", "searchlog", "notifications", "jobAlerts"} {
+			if _, err := db.DB.Collection(name).DeleteMany(sc, bson.M{"userId": user.ID}); err != nil {
+				return err
+			}
+		}
+		_, err = db.DB.Collection("users").DeleteOne(sc, bson.M{"_id": user.ID})
+		return err
+	})
+}
+
+// NewTrashPurger returns a worker that purges the trash once a day.
+func NewTrashPurger() *PeriodicWorker {
+	return NewPeriodicWorker("trash purge", trashPurgeInterval, func(ctx context.Context) error {
+		report, err := PurgeTrash(ctx, time.Now().Add(-TrashRetention()))
+		if report.Jobs+report.Applications+report.Users > 0 {
+			log.Printf("purged %d jobs, %d applications and %d users from the trash", report.Jobs, report.Applications, report.Users)
+		}
+		return err
+	})
+}
@@ -5,10 +5,13 @@ import (
 	"errors"
 	"time"
 
+	"github.com/weldonkipchirchir/job-listing-server/cache"
 	"github.com/weldonkipchirchir/job-listing-server/db"
 	"github.com/weldonkipchirchir/job-listing-server/models"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -16,8 +19,49 @@ var (
 	ErrEmailTaken         = errors.New("username already taken")
 	ErrUserNotFound       = errors.New("user not found")
 	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrAccountDeleted     = errors.New("account is deleted")
 )
 
+// accountCacheTTL bounds how long a token keeps working on an instance
+// after its account was deleted elsewhere.
+const (
+	accountCacheTTL  = 30 * time.Second
+	accountCacheSize = 10000
+)
+
+var accountCache cache.Cache = cache.NewLRU(accountCacheSize)
+
+// AccountActive reports whether the account a token was issued to still
+// exists outside the trash. Answers are cached for accountCacheTTL.
+func AccountActive(ctx context.Context, userID string) (bool, error) {
+	key := "account:" + userID
+	if cached, ok := accountCache.Get(ctx, key); ok && len(cached) == 1 {
+		return cached[0] == 1, nil
+	}
+
+	id, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, nil
+	}
+	count, err := db.DB.Collection("users").CountDocuments(ctx, NotDeleted(bson.M{"_id": id}), options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+	active := count > 0
+	value := byte(0)
+	if active {
+		value = 1
+	}
+	accountCache.Set(ctx, key, []byte{value}, accountCacheTTL)
+	return active, nil
+}
+
+// forgetAccount drops the cached answer of AccountActive after the account
+// was deleted, restored or removed.
+func forgetAccount(ctx context.Context, userID primitive.ObjectID) {
+	accountCache.Delete(ctx, "account:"+userID.Hex())
+}
+
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 	if err != nil {
@@ -68,6 +112,10 @@ func RegisterUser(user *models.User) error {
 	defer cancel()
 
 	_, err = db.DB.Collection("users").InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		// registered concurrently, caught by the unique email index
+		return ErrEmailTaken
+	}
 
 	return err
 }
@@ -87,6 +135,10 @@ func LoginUser(email, password string) (*models.User, error) {
 	if err != nil {
 		return nil, ErrInvalidCredentials
 	}
+
+	if user.DeletedAt != nil {
+		return nil, ErrAccountDeleted
+	}
 	return user, nil
 }
 
@@ -96,8 +148,84 @@ func UserSettings(email string) error {
 		return err
 	}
 
-	if user == nil {
+	if user == nil || user.DeletedAt != nil {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// DeleteUser moves an account to the trash. It can be restored with the
+// account's credentials until the trash is purged.
+func DeleteUser(userID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := SoftDelete(ctx, db.DB.Collection("users"), bson.M{"_id": userID}, userID)
+	if err == mongo.ErrNoDocuments {
 		return ErrUserNotFound
+	} else if err != nil {
+		return err
 	}
+	forgetAccount(ctx, userID)
 	return nil
 }
+
+// RestoreUser takes a deleted account out of the trash after checking its
+// credentials.
+func RestoreUser(email, password string) (*models.User, error) {
+	user, err := getUserByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err = Restore(ctx, db.DB.Collection("users"), bson.M{"_id": user.ID})
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	forgetAccount(ctx, user.ID)
+	user.DeletedAt = nil
+	user.DeletedBy = nil
+	return user, nil
+}
+
+// SetUserRole changes the role of the account with email and returns the
+// account as it was before.
+func SetUserRole(ctx context.Context, email, role string) (*models.User, error) {
+	return updateUserByEmail(ctx, email, bson.M{"role": role})
+}
+
+// ResetPassword replaces the password of the account with email and returns
+// the account as it was before.
+func ResetPassword(ctx context.Context, email, password string) (*models.User, error) {
+	hashedPassword, err := HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	return updateUserByEmail(ctx, email, bson.M{"password": hashedPassword})
+}
+
+func updateUserByEmail(ctx context.Context, email string, set bson.M) (*models.User, error) {
+	var user models.User
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+	err := db.DB.Collection("users").FindOneAndUpdate(ctx, NotDeleted(bson.M{"email": email}), bson.M{"$set": set}, opts).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrUserNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
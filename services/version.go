@@ -0,0 +1,27 @@
+package services
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ErrVersionConflict is returned by a conditional write when the document
+// is no longer at the version it was read at.
+var ErrVersionConflict = errors.New("document was changed concurrently")
+
+// nextVersion increments the version of a document in an update pipeline,
+// where $inc is not available.
+var nextVersion = bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}}
+
+// AtVersion narrows filter to documents still at version, so a write based
+// on an outdated read matches nothing. Documents written before versions
+// were kept have none and count as version 0.
+func AtVersion(filter bson.M, version int) bson.M {
+	if version == 0 {
+		filter["version"] = bson.M{"$in": bson.A{nil, 0}}
+	} else {
+		filter["version"] = version
+	}
+	return filter
+}
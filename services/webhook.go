@@ -0,0 +1,450 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/weldonkipchirchir/job-listing-server/db"
+	"github.com/weldonkipchirchir/job-listing-server/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	MaxWebhookAttempts   = 8
+	webhookBaseBackoff   = 30 * time.Second
+	webhookMaxBackoff    = 6 * time.Hour
+	webhookTimeout       = 10 * time.Second
+	webhookLease         = time.Minute
+	webhookPollInterval  = 2 * time.Second
+	webhookResponseLimit = 512
+)
+
+var ErrWebhookAddressNotAllowed = errors.New("webhook url must not point at a loopback, private or link-local address")
+
+// webhookBlockedPrefixes are ranges webhooks may not reach besides the
+// loopback, private and link-local ones the net package knows about.
+var webhookBlockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// webhookAddressAllowed reports whether webhooks may connect to ip. Webhook
+// urls are chosen by recruiters, and what the endpoint answers is shown to
+// them, so the server's own network is off limits.
+func webhookAddressAllowed(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() ||
+		ip.IsMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsLinkLocalMulticast() {
+		return false
+	}
+	for _, prefix := range webhookBlockedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateWebhookURL checks that a webhook url is an absolute http(s) url
+// whose host resolves to public addresses only. The dispatcher checks the
+// address again when it connects, the host may resolve differently by then.
+func ValidateWebhookURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("url must be an absolute http(s) url")
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("url host %q does not resolve", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !webhookAddressAllowed(addr) {
+			return ErrWebhookAddressNotAllowed
+		}
+	}
+	return nil
+}
+
+// newWebhookClient returns the client deliveries are sent with. It connects
+// to allowed addresses only, without a proxy, and does not follow redirects:
+// a redirect is reported as the response of the attempt.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		// Control sees the address after name resolution, right before
+		// connecting
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !webhookAddressAllowed(addrPort.Addr()) {
+				return ErrWebhookAddressNotAllowed
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: webhookTimeout,
+		Transport: &http.Transport{
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: webhookTimeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// WebhookEnvelope is the JSON body of every delivery.
+type WebhookEnvelope struct {
+	ID        primitive.ObjectID `json:"id"`
+	Type      string             `json:"type"`
+	CreatedAt time.Time          `json:"createdAt"`
+	Data      interface{}        `json:"data"`
+}
+
+// GenerateWebhookSecret returns a new random signing secret.
+func GenerateWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// SignWebhookPayload returns the X-Jobly-Signature header value. Receivers
+// compute HMAC-SHA256 over "<timestamp>.<body>" with their secret and
+// compare it to v1, rejecting old timestamps to prevent replays.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// WebhookBackoff is the delay before the next attempt after attempt
+// failed attempts.
+func WebhookBackoff(attempt int) time.Duration {
+	delay := webhookBaseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= webhookMaxBackoff {
+			return webhookMaxBackoff
+		}
+	}
+	return delay
+}
+
+// JobWebhookData is the job representation sent to webhooks.
+func JobWebhookData(job models.Job) map[string]interface{} {
+	return map[string]interface{}{
+		"_id":                   job.ID,
+		"jobName":               job.JobName,
+		"type":                  job.Type,
+		"location":              job.Location,
+		"company":               job.Company,
+		"salaryLow":             job.SalaryLow,
+		"salaryHigh":            job.SalaryHigh,
+		"currency":              job.Currency,
+		"industry":              job.Industry,
+		"mandatoryRequirements": job.MandatoryRequirements,
+		"optionalRequirements":  job.OptionalRequirements,
+		"sponsored":             job.Sponsored,
+		"externalRef":           job.ExternalRef,
+		"version":               job.Version,
+		"url":                   JobURL(job),
+	}
+}
+
+// ApplicationWebhookData is the application representation sent to
+// webhooks. The resume itself is not included, and neither is the candidate
+// while blind review of job hides them.
+func ApplicationWebhookData(job *models.Job, application models.Application) map[string]interface{} {
+	if IdentityHidden(job, application) {
+		application = MaskApplication(application)
+	}
+	data := map[string]interface{}{
+		"_id":             application.ID,
+		"jobId":           application.JobID,
+		"jobName":         application.JobName,
+		"jobVersion":      application.JobVersion,
+		"userId":          application.UserID,
+		"name":            application.Name,
+		"email":           application.Email,
+		"status":          application.Status,
+		"flagged":         application.Flagged,
+		"knockoutReasons": application.KnockoutReasons,
+	}
+	if application.Match != nil {
+		data["matchScore"] = application.Match.Score
+	}
+	if application.Blind {
+		delete(data, "userId")
+		data["blind"] = true
+	}
+	return data
+}
+
+func webhooks() *mongo.Collection {
+	return db.DB.Collection("webhooks")
+}
+
+func webhookDeliveries() *mongo.Collection {
+	return db.DB.Collection("webhookDeliveries")
+}
+
+// NewWebhookDelivery builds a pending delivery of event to webhook.
+func NewWebhookDelivery(webhook models.Webhook, eventID primitive.ObjectID, event string, payload string) models.WebhookDelivery {
+	now := time.Now().UTC()
+	return models.WebhookDelivery{
+		ID:            primitive.NewObjectID(),
+		WebhookID:     webhook.ID,
+		OwnerID:       webhook.OwnerID,
+		EventID:       eventID,
+		Event:         event,
+		Payload:       payload,
+		Status:        models.DeliveryPending,
+		Attempts:      []models.DeliveryAttempt{},
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
+
+// QueueWebhookEvent renders the event payload now and queues a task that
+// creates a delivery for every active webhook of the owner subscribed to
+// the event. The dispatcher sends the deliveries.
+func QueueWebhookEvent(ctx context.Context, ownerID primitive.ObjectID, event string, data interface{}) error {
+	envelope := WebhookEnvelope{ID: primitive.NewObjectID(), Type: event, CreatedAt: time.Now().UTC(), Data: data}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	return EnqueueTask(ctx, TaskWebhookEvent, webhookEventTask{
+		OwnerID: ownerID,
+		EventID: envelope.ID,
+		Event:   event,
+		Payload: string(payload),
+	})
+}
+
+// createWebhookDeliveries fans an event out to the subscribed webhooks. An
+// event that already has deliveries was fanned out by an earlier attempt.
+func createWebhookDeliveries(ctx context.Context, ownerID, eventID primitive.ObjectID, event, payload string) error {
+	count, err := webhookDeliveries().CountDocuments(ctx, bson.M{"eventId": eventID})
+	if err != nil || count > 0 {
+		return err
+	}
+
+	cursor, err := webhooks().Find(ctx, bson.M{"ownerId": ownerID, "active": true, "events": event})
+	if err != nil {
+		return err
+	}
+	var endpoints []models.Webhook
+	if err := cursor.All(ctx, &endpoints); err != nil {
+		return err
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	var deliveries []interface{}
+	for _, endpoint := range endpoints {
+		deliveries = append(deliveries, NewWebhookDelivery(endpoint, eventID, event, payload))
+	}
+	_, err = webhookDeliveries().InsertMany(ctx, deliveries)
+	return err
+}
+
+// WebhookDispatcher works off pending deliveries. Deliveries are claimed
+// with a lease, so several instances can run dispatchers side by side.
+type WebhookDispatcher struct {
+	client *http.Client
+	stop   chan struct{}
+	done   sync.WaitGroup
+	state  workerState
+}
+
+func NewWebhookDispatcher() *WebhookDispatcher {
+	return &WebhookDispatcher{
+		client: newWebhookClient(),
+		stop:   make(chan struct{}),
+	}
+}
+
+func (d *WebhookDispatcher) Start() {
+	d.state.setRunning(true)
+	d.done.Add(1)
+	go func() {
+		defer d.done.Done()
+		ticker := time.NewTicker(webhookPollInterval)
+		defer ticker.Stop()
+		for {
+			// drain everything that is due before waiting again
+			for d.deliverNext() {
+				select {
+				case <-d.stop:
+					return
+				default:
+				}
+			}
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (d *WebhookDispatcher) Status() WorkerStatus {
+	return d.state.status("webhook dispatcher")
+}
+
+// Stop waits for the delivery in flight to finish.
+func (d *WebhookDispatcher) Stop(ctx context.Context) error {
+	d.state.setRunning(false)
+	close(d.stop)
+	finished := make(chan struct{})
+	go func() {
+		d.done.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliverNext claims and sends one due delivery. It reports whether there
+// was one.
+func (d *WebhookDispatcher) deliverNext() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookLease)
+	defer cancel()
+
+	now := time.Now().UTC()
+	filter := bson.M{
+		"status":        models.DeliveryPending,
+		"nextAttemptAt": bson.M{"$lte": now},
+		"lockedUntil":   bson.M{"$lte": now},
+	}
+	update := bson.M{"$set": bson.M{"lockedUntil": now.Add(webhookLease)}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var delivery models.WebhookDelivery
+	err := webhookDeliveries().FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Println("error claiming webhook delivery", err)
+		}
+		return false
+	}
+
+	if err := d.Deliver(ctx, &delivery); err != nil {
+		log.Println("error recording webhook delivery", err)
+	}
+	return true
+}
+
+// Deliver sends one attempt of a delivery and records the outcome, either
+// completing it or scheduling the next attempt.
+func (d *WebhookDispatcher) Deliver(ctx context.Context, delivery *models.WebhookDelivery) error {
+	var webhook models.Webhook
+	err := webhooks().FindOne(ctx, bson.M{"_id": delivery.WebhookID}).Decode(&webhook)
+
+	var attempt models.DeliveryAttempt
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		attempt = models.DeliveryAttempt{At: time.Now().UTC(), Error: "webhook was deleted"}
+		delivery.AttemptCount = MaxWebhookAttempts - 1
+	case err != nil:
+		return err
+	case !webhook.Active:
+		attempt = models.DeliveryAttempt{At: time.Now().UTC(), Error: "webhook is disabled"}
+		delivery.AttemptCount = MaxWebhookAttempts - 1
+	default:
+		attempt = d.send(ctx, webhook, *delivery)
+	}
+
+	delivery.AttemptCount++
+	set := bson.M{"attemptCount": delivery.AttemptCount, "lockedUntil": time.Time{}}
+	switch {
+	case attempt.Error == "" && attempt.StatusCode >= 200 && attempt.StatusCode < 300:
+		set["status"] = models.DeliverySucceeded
+		set["completedAt"] = attempt.At
+	case delivery.AttemptCount >= MaxWebhookAttempts:
+		set["status"] = models.DeliveryFailed
+		set["completedAt"] = attempt.At
+	default:
+		set["nextAttemptAt"] = attempt.At.Add(WebhookBackoff(delivery.AttemptCount))
+	}
+
+	update := bson.M{"$set": set, "$push": bson.M{"attempts": attempt}}
+	_, err = webhookDeliveries().UpdateOne(ctx, bson.M{"_id": delivery.ID}, update)
+	return err
+}
+
+func (d *WebhookDispatcher) send(ctx context.Context, webhook models.Webhook, delivery models.WebhookDelivery) models.DeliveryAttempt {
+	started := time.Now()
+	attempt := models.DeliveryAttempt{At: started.UTC()}
+
+	body := []byte(delivery.Payload)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "Jobly-Webhooks/1.0")
+	request.Header.Set("X-Jobly-Event", delivery.Event)
+	request.Header.Set("X-Jobly-Delivery", delivery.ID.Hex())
+	request.Header.Set("X-Jobly-Signature", SignWebhookPayload(webhook.Secret, started.Unix(), body))
+
+	response, err := d.client.Do(request)
+	attempt.DurationMs = time.Since(started).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer response.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(response.Body, webhookResponseLimit))
+	attempt.StatusCode = response.StatusCode
+	attempt.Response = string(snippet)
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		attempt.Error = "unexpected status " + response.Status
+	}
+	return attempt
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"type":"job.created"}`)
+	got := SignWebhookPayload("whsec_test", 1700000000, body)
+
+	mac := hmac.New(sha256.New, []byte("whsec_test"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := "t=1700000000,v1=" + hex.EncodeToString(mac.Sum(nil))
+	if got != want {
+		t.Errorf("SignWebhookPayload() = %q, want %q", got, want)
+	}
+
+	if SignWebhookPayload("other", 1700000000, body) == got {
+		t.Error("signature does not depend on the secret")
+	}
+	if SignWebhookPayload("whsec_test", 1700000001, body) == got {
+		t.Error("signature does not depend on the timestamp")
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, 6 * time.Hour},
+		{100, 6 * time.Hour},
+	}
+	for _, test := range tests {
+		if got := WebhookBackoff(test.attempt); got != test.want {
+			t.Errorf("WebhookBackoff(%d) = %v, want %v", test.attempt, got, test.want)
+		}
+	}
+}
+
+func TestWebhookAddressAllowed(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.0.0.8", false},
+		{"172.16.5.4", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:169.254.169.254", false},
+		{"64:ff9b::a9fe:a9fe", false},
+		{"224.0.0.1", false},
+	}
+	for _, test := range tests {
+		if got := webhookAddressAllowed(netip.MustParseAddr(test.addr)); got != test.want {
+			t.Errorf("webhookAddressAllowed(%s) = %v, want %v", test.addr, got, test.want)
+		}
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	for _, raw := range []string{
+		"ftp://example.com/hook",
+		"/relative/hook",
+		"http://127.0.0.1/hook",
+		"http://[::1]:8080/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"http://localhost/hook",
+	} {
+		if err := ValidateWebhookURL(context.Background(), raw); err == nil {
+			t.Errorf("ValidateWebhookURL(%q) accepted", raw)
+		}
+	}
+	if err := ValidateWebhookURL(context.Background(), "https://93.184.216.34/hook"); err != nil {
+		t.Errorf("ValidateWebhookURL() error = %v", err)
+	}
+}
+
+func TestRedactWebhookPayload(t *testing.T) {
+	payload := `{"id":"1","type":"application.submitted","data":{"name":"Jane Doe","email":"jane@example.com","status":"Pending"}}`
+	redacted, err := redactWebhookPayload(payload)
+	if err != nil {
+		t.Fatalf("redactWebhookPayload() error = %v", err)
+	}
+	if strings.Contains(redacted, "Jane") || strings.Contains(redacted, "jane@example.com") {
+		t.Errorf("redactWebhookPayload() = %s still names the candidate", redacted)
+	}
+	if !strings.Contains(redacted, `"status":"Pending"`) {
+		t.Errorf("redactWebhookPayload() = %s lost other fields", redacted)
+	}
+}
@@ -0,0 +1,512 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	ErrNotPDF         = errors.New("file is not a pdf")
+	pdfStreamPattern  = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n`)
+	pdfBfCharPattern  = regexp.MustCompile(`(?s)beginbfchar(.*?)endbfchar`)
+	pdfBfRangePattern = regexp.MustCompile(`(?s)beginbfrange(.*?)endbfrange`)
+	pdfHexPattern     = regexp.MustCompile(`<([0-9A-Fa-f\s]*)>`)
+)
+
+// Uploads are untrusted, so the work done for one file is bounded: a
+// compressed stream or a CMap range can expand to far more than its size.
+const (
+	maxPDFStreamSize = 16 << 20
+	// maxPDFDecodedSize bounds the decompressed size of all streams together
+	maxPDFDecodedSize = 32 << 20
+	// maxCMapEntries bounds the codes all ToUnicode CMaps together may map
+	maxCMapEntries = 1 << 16
+	// maxPDFTextSize bounds the extracted text
+	maxPDFTextSize = 1 << 20
+)
+
+// ExtractPDFText pulls the plain text out of a PDF. It understands
+// uncompressed and FlateDecode content streams, literal and hex strings, and
+// ToUnicode CMaps for fonts with two byte codes. Layout is approximated:
+// text objects and line moves become line breaks.
+func ExtractPDFText(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF")) {
+		return "", ErrNotPDF
+	}
+
+	streams := pdfStreams(data)
+
+	cmap := map[string]string{}
+	for _, stream := range streams {
+		if bytes.Contains(stream, []byte("beginbfchar")) || bytes.Contains(stream, []byte("beginbfrange")) {
+			parseToUnicode(stream, cmap)
+		}
+	}
+
+	var out strings.Builder
+	for _, stream := range streams {
+		if out.Len() >= maxPDFTextSize {
+			break
+		}
+		if !bytes.Contains(stream, []byte("BT")) {
+			continue
+		}
+		extractContentText(stream, cmap, &out)
+	}
+
+	return normalizeExtractedText(out.String()), nil
+}
+
+// pdfStreams returns the decoded bodies of every stream in the file that is
+// not an image or an embedded font, until maxPDFDecodedSize is used up.
+func pdfStreams(data []byte) [][]byte {
+	var streams [][]byte
+	budget := int64(maxPDFDecodedSize)
+
+	for _, loc := range pdfStreamPattern.FindAllSubmatchIndex(data, -1) {
+		dict := string(data[loc[2]:loc[3]])
+		start := loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			continue
+		}
+		body := data[start : start+end]
+
+		if strings.Contains(dict, "/Image") || strings.Contains(dict, "/FontFile") || strings.Contains(dict, "/Length1") {
+			continue
+		}
+
+		if strings.Contains(dict, "/FlateDecode") {
+			if budget <= 0 {
+				break
+			}
+			reader, err := zlib.NewReader(bytes.NewReader(body))
+			if err != nil {
+				continue
+			}
+			limit := int64(maxPDFStreamSize)
+			if budget < limit {
+				limit = budget
+			}
+			decoded, err := io.ReadAll(io.LimitReader(reader, limit))
+			reader.Close()
+			budget -= int64(len(decoded))
+			// A truncated stream still yields useful text
+			if err != nil && len(decoded) == 0 {
+				continue
+			}
+			body = decoded
+		} else if strings.Contains(dict, "/Filter") {
+			// Other filters (LZW, ASCII85, DCT, ...) are not supported
+			continue
+		}
+
+		streams = append(streams, body)
+	}
+
+	return streams
+}
+
+// parseToUnicode adds the mappings of a ToUnicode CMap to cmap until it
+// holds maxCMapEntries codes.
+func parseToUnicode(stream []byte, cmap map[string]string) {
+	for _, block := range pdfBfCharPattern.FindAllSubmatch(stream, -1) {
+		hexes := pdfHexPattern.FindAllSubmatch(block[1], -1)
+		for i := 0; i+1 < len(hexes); i += 2 {
+			if len(cmap) >= maxCMapEntries {
+				return
+			}
+			src := cleanHex(hexes[i][1])
+			cmap[src] = decodeUTF16Hex(cleanHex(hexes[i+1][1]))
+		}
+	}
+
+	for _, block := range pdfBfRangePattern.FindAllSubmatch(stream, -1) {
+		for _, line := range bytes.Split(block[1], []byte("\n")) {
+			if len(cmap) >= maxCMapEntries {
+				return
+			}
+			hexes := pdfHexPattern.FindAllSubmatch(line, -1)
+			if len(hexes) < 3 {
+				continue
+			}
+			low := cleanHex(hexes[0][1])
+			lowValue, err1 := strconv.ParseUint(low, 16, 32)
+			highValue, err2 := strconv.ParseUint(cleanHex(hexes[1][1]), 16, 32)
+			if err1 != nil || err2 != nil || highValue < lowValue || highValue-lowValue > 0xFFFF {
+				continue
+			}
+
+			// <lo> <hi> [<dst1> <dst2> ...] maps each code to its own string
+			if bytes.Contains(line, []byte("[")) {
+				for i, dst := range hexes[2:] {
+					code := lowValue + uint64(i)
+					if code > highValue || len(cmap) >= maxCMapEntries {
+						break
+					}
+					cmap[formatCode(code, len(low))] = decodeUTF16Hex(cleanHex(dst[1]))
+				}
+				continue
+			}
+
+			dst := []rune(decodeUTF16Hex(cleanHex(hexes[2][1])))
+			if len(dst) == 0 {
+				continue
+			}
+			for code := lowValue; code <= highValue && len(cmap) < maxCMapEntries; code++ {
+				mapped := append([]rune{}, dst...)
+				mapped[len(mapped)-1] += rune(code - lowValue)
+				cmap[formatCode(code, len(low))] = string(mapped)
+			}
+		}
+	}
+}
+
+func formatCode(code uint64, width int) string {
+	return fmt.Sprintf("%0*X", width, code)
+}
+
+func cleanHex(raw []byte) string {
+	return strings.ToUpper(strings.Join(strings.Fields(string(raw)), ""))
+}
+
+func decodeUTF16Hex(hex string) string {
+	if len(hex)%2 == 1 {
+		hex += "0"
+	}
+	var units []uint16
+	for i := 0; i+4 <= len(hex); i += 4 {
+		value, err := strconv.ParseUint(hex[i:i+4], 16, 16)
+		if err != nil {
+			return ""
+		}
+		units = append(units, uint16(value))
+	}
+	if len(hex) == 2 {
+		value, _ := strconv.ParseUint(hex, 16, 8)
+		return string(rune(value))
+	}
+	return string(utf16.Decode(units))
+}
+
+// extractContentText runs a minimal interpreter over a content stream and
+// writes the text shown by Tj, TJ, ' and " operators, stopping once out
+// holds maxPDFTextSize bytes.
+func extractContentText(stream []byte, cmap map[string]string, out *strings.Builder) {
+	var operands []string
+	i := 0
+	n := len(stream)
+	fontSize := 12.0
+	lastShown := 0
+
+	for i < n && out.Len() < maxPDFTextSize {
+		ch := stream[i]
+		switch {
+		case ch == '%':
+			for i < n && stream[i] != '\n' && stream[i] != '\r' {
+				i++
+			}
+		case ch == '(':
+			str, next := readLiteralString(stream, i)
+			operands = append(operands, decodeLiteral(str, cmap))
+			i = next
+		case ch == '<' && i+1 < n && stream[i+1] == '<':
+			// Inline dictionaries (marked content properties) carry no text
+			depth := 0
+			for i < n {
+				if i+1 < n && stream[i] == '<' && stream[i+1] == '<' {
+					depth++
+					i += 2
+					continue
+				}
+				if i+1 < n && stream[i] == '>' && stream[i+1] == '>' {
+					depth--
+					i += 2
+					if depth == 0 {
+						break
+					}
+					continue
+				}
+				i++
+			}
+		case ch == '<':
+			end := bytes.IndexByte(stream[i:], '>')
+			if end < 0 {
+				return
+			}
+			operands = append(operands, decodeHexString(cleanHex(stream[i+1:i+end]), cmap))
+			i += end + 1
+		case ch == '[':
+			operands = append(operands, "\x00[")
+			i++
+		case ch == ']':
+			// Collapse the array into a single operand. Large negative kerning
+			// values inside TJ arrays are word gaps.
+			var parts []string
+			for len(operands) > 0 {
+				last := operands[len(operands)-1]
+				operands = operands[:len(operands)-1]
+				if last == "\x00[" {
+					break
+				}
+				parts = append([]string{last}, parts...)
+			}
+			var text strings.Builder
+			for _, part := range parts {
+				if strings.HasPrefix(part, "\x00num:") {
+					if value, ok := numberOperand(part); ok && value < -200 {
+						text.WriteByte(' ')
+					}
+					continue
+				}
+				text.WriteString(part)
+			}
+			operands = append(operands, text.String())
+			i++
+		case isPDFSpace(ch):
+			i++
+		case ch == '-' || ch == '+' || ch == '.' || (ch >= '0' && ch <= '9'):
+			start := i
+			i++
+			for i < n && (stream[i] == '.' || (stream[i] >= '0' && stream[i] <= '9')) {
+				i++
+			}
+			operands = append(operands, "\x00num:"+string(stream[start:i]))
+		case ch == '/':
+			start := i
+			i++
+			for i < n && !isPDFSpace(stream[i]) && !isPDFDelimiter(stream[i]) {
+				i++
+			}
+			operands = append(operands, "\x00name:"+string(stream[start:i]))
+		default:
+			start := i
+			for i < n && !isPDFSpace(stream[i]) && !isPDFDelimiter(stream[i]) {
+				i++
+			}
+			if i == start {
+				i++
+				continue
+			}
+			op := string(stream[start:i])
+
+			switch op {
+			case "Tj", "TJ":
+				if len(operands) > 0 {
+					text := textOperand(operands[len(operands)-1])
+					lastShown = utf8.RuneCountInString(text)
+					out.WriteString(text)
+				}
+			case "'", "\"":
+				out.WriteByte('\n')
+				if len(operands) > 0 {
+					out.WriteString(textOperand(operands[len(operands)-1]))
+				}
+			case "Tf":
+				if len(operands) >= 1 {
+					if size, ok := numberOperand(operands[len(operands)-1]); ok && size > 0 {
+						fontSize = size
+					}
+				}
+			case "Td", "TD":
+				if len(operands) >= 2 {
+					x, _ := numberOperand(operands[len(operands)-2])
+					y, _ := numberOperand(operands[len(operands)-1])
+					// Moves that only cover the text just shown are glyph by
+					// glyph positioning, not a gap between words
+					advance := fontSize * 0.95 * float64(lastShown)
+					switch {
+					case y != 0:
+						out.WriteByte('\n')
+					case x < 0 || x > advance:
+						out.WriteByte(' ')
+					}
+				}
+			case "T*", "ET":
+				out.WriteByte('\n')
+			case "Tm":
+				out.WriteByte(' ')
+			case "BI":
+				// Skip inline image data
+				end := bytes.Index(stream[i:], []byte("EI"))
+				if end < 0 {
+					return
+				}
+				i += end + 2
+			}
+			operands = operands[:0]
+		}
+	}
+}
+
+func numberOperand(operand string) (float64, bool) {
+	if !strings.HasPrefix(operand, "\x00num:") {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(operand[5:], 64)
+	return value, err == nil
+}
+
+func textOperand(operand string) string {
+	if strings.HasPrefix(operand, "\x00") {
+		return ""
+	}
+	return operand
+}
+
+func readLiteralString(stream []byte, i int) ([]byte, int) {
+	var buf []byte
+	depth := 0
+	n := len(stream)
+	for i < n {
+		ch := stream[i]
+		switch ch {
+		case '\\':
+			if i+1 >= n {
+				return buf, n
+			}
+			i++
+			switch esc := stream[i]; esc {
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'b':
+				buf = append(buf, '\b')
+			case 'f':
+				buf = append(buf, '\f')
+			case '\r', '\n':
+				// Line continuation
+				if esc == '\r' && i+1 < n && stream[i+1] == '\n' {
+					i++
+				}
+			default:
+				if esc >= '0' && esc <= '7' {
+					end := i
+					for end < n && end < i+3 && stream[end] >= '0' && stream[end] <= '7' {
+						end++
+					}
+					value, _ := strconv.ParseUint(string(stream[i:end]), 8, 16)
+					buf = append(buf, byte(value))
+					i = end - 1
+				} else {
+					buf = append(buf, esc)
+				}
+			}
+		case '(':
+			depth++
+			if depth > 1 {
+				buf = append(buf, ch)
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return buf, i + 1
+			}
+			buf = append(buf, ch)
+		default:
+			buf = append(buf, ch)
+		}
+		i++
+	}
+	return buf, n
+}
+
+func decodeLiteral(raw []byte, cmap map[string]string) string {
+	// UTF-16BE with byte order mark
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		var hex strings.Builder
+		for _, b := range raw[2:] {
+			hex.WriteString(strconv.FormatUint(uint64(b)|0x100, 16)[1:])
+		}
+		return decodeUTF16Hex(strings.ToUpper(hex.String()))
+	}
+
+	var hex strings.Builder
+	for _, b := range raw {
+		hex.WriteString(strings.ToUpper(strconv.FormatUint(uint64(b)|0x100, 16)[1:]))
+	}
+	if mapped, ok := mapCodes(hex.String(), cmap); ok {
+		return mapped
+	}
+
+	if utf8.Valid(raw) {
+		return string(raw)
+	}
+	runes := make([]rune, len(raw))
+	for i, b := range raw {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+func decodeHexString(hex string, cmap map[string]string) string {
+	if len(hex)%2 == 1 {
+		hex += "0"
+	}
+	if mapped, ok := mapCodes(hex, cmap); ok {
+		return mapped
+	}
+	var raw []byte
+	for i := 0; i+2 <= len(hex); i += 2 {
+		value, _ := strconv.ParseUint(hex[i:i+2], 16, 8)
+		raw = append(raw, byte(value))
+	}
+	return decodeLiteral(raw, nil)
+}
+
+// mapCodes translates character codes through the ToUnicode CMap, trying
+// two byte codes first and falling back to single bytes.
+func mapCodes(hex string, cmap map[string]string) (string, bool) {
+	if len(cmap) == 0 {
+		return "", false
+	}
+	for _, width := range []int{4, 2} {
+		if len(hex)%width != 0 {
+			continue
+		}
+		var out strings.Builder
+		ok := true
+		for i := 0; i < len(hex); i += width {
+			mapped, found := cmap[hex[i:i+width]]
+			if !found {
+				ok = false
+				break
+			}
+			out.WriteString(mapped)
+		}
+		if ok {
+			return out.String(), true
+		}
+	}
+	return "", false
+}
+
+func isPDFSpace(ch byte) bool {
+	return ch == ' ' || ch == '\n' || ch == '\r' || ch == '\t' || ch == '\f' || ch == 0
+}
+
+func isPDFDelimiter(ch byte) bool {
+	return strings.IndexByte("()<>[]{}/%", ch) >= 0
+}
+
+func normalizeExtractedText(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,166 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// pdfStream is one stream object of a test file.
+type pdfStream struct {
+	dict string
+	body []byte
+}
+
+func buildPDF(streams ...pdfStream) []byte {
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	for i, stream := range streams {
+		fmt.Fprintf(&out, "%d 0 obj\n<< /Length %d %s >>\nstream\n", i+1, len(stream.body), stream.dict)
+		out.Write(stream.body)
+		out.WriteString("\nendstream\nendobj\n")
+	}
+	out.WriteString("%%EOF\n")
+	return out.Bytes()
+}
+
+func deflate(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	writer := zlib.NewWriter(&out)
+	if _, err := writer.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestExtractPDFText(t *testing.T) {
+	content := []byte("BT /F1 12 Tf 72 712 Td (Jane Doe) Tj 0 -14 Td (Go developer) Tj ET")
+
+	tests := []struct {
+		name string
+		pdf  []byte
+		want string
+	}{
+		{
+			name: "uncompressed",
+			pdf:  buildPDF(pdfStream{body: content}),
+			want: "Jane Doe\nGo developer",
+		},
+		{
+			name: "flate",
+			pdf:  buildPDF(pdfStream{dict: "/Filter /FlateDecode", body: deflate(t, content)}),
+			want: "Jane Doe\nGo developer",
+		},
+		{
+			name: "kerned array",
+			pdf:  buildPDF(pdfStream{body: []byte("BT [(Hel)20(lo)-300(World)] TJ ET")}),
+			want: "Hello World",
+		},
+		{
+			name: "escaped literal",
+			pdf:  buildPDF(pdfStream{body: []byte(`BT (C\(++\) \101PI) Tj ET`)}),
+			want: "C(++) API",
+		},
+		{
+			name: "to unicode cmap",
+			pdf: buildPDF(
+				pdfStream{body: []byte("beginbfrange\n<0001> <0003> <0041>\nendbfrange\nbeginbfchar\n<0004> <00E9>\nendbfchar")},
+				pdfStream{body: []byte("BT <0001000200030004> Tj ET")},
+			),
+			want: "ABCé",
+		},
+		{
+			name: "unsupported filter",
+			pdf:  buildPDF(pdfStream{dict: "/Filter /LZWDecode", body: content}),
+			want: "",
+		},
+		{
+			name: "image",
+			pdf:  buildPDF(pdfStream{dict: "/Subtype /Image", body: content}),
+			want: "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ExtractPDFText(test.pdf)
+			if err != nil {
+				t.Fatalf("ExtractPDFText() error = %v", err)
+			}
+			if got != test.want {
+				t.Errorf("ExtractPDFText() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestExtractPDFTextNotPDF(t *testing.T) {
+	for _, data := range [][]byte{nil, []byte("hello"), []byte("PK\x03\x04")} {
+		if _, err := ExtractPDFText(data); err != ErrNotPDF {
+			t.Errorf("ExtractPDFText(%q) error = %v, want ErrNotPDF", data, err)
+		}
+	}
+}
+
+func TestExtractPDFTextMalformed(t *testing.T) {
+	inputs := [][]byte{
+		buildPDF(pdfStream{dict: "/Filter /FlateDecode", body: []byte("not zlib")}),
+		buildPDF(pdfStream{body: []byte("BT (unterminated Tj")}),
+		buildPDF(pdfStream{body: []byte("BT <00")}),
+		buildPDF(pdfStream{body: []byte("BT ] ] TJ ET")}),
+		[]byte("%PDF-1.4\n<< >>\nstream\nBT (no end) Tj ET"),
+	}
+	for _, data := range inputs {
+		if _, err := ExtractPDFText(data); err != nil {
+			t.Errorf("ExtractPDFText(%q) error = %v", data, err)
+		}
+	}
+}
+
+func TestPDFStreamsDecodedBudget(t *testing.T) {
+	// each stream inflates to more than a single stream may, and together
+	// to more than the whole file may
+	bomb := deflate(t, make([]byte, maxPDFStreamSize+1024))
+	var streams []pdfStream
+	for i := 0; i < 3; i++ {
+		streams = append(streams, pdfStream{dict: "/Filter /FlateDecode", body: bomb})
+	}
+
+	total := 0
+	for _, stream := range pdfStreams(buildPDF(streams...)) {
+		if len(stream) > maxPDFStreamSize {
+			t.Errorf("stream decoded to %d bytes, limit is %d", len(stream), maxPDFStreamSize)
+		}
+		total += len(stream)
+	}
+	if total > maxPDFDecodedSize {
+		t.Errorf("streams decoded to %d bytes, limit is %d", total, maxPDFDecodedSize)
+	}
+}
+
+func TestParseToUnicodeEntryLimit(t *testing.T) {
+	cmap := map[string]string{}
+	stream := []byte("beginbfrange\n<0000> <FFFF> <0041>\n<10000> <1FFFF> <0041>\nendbfrange")
+	parseToUnicode(stream, cmap)
+	if len(cmap) > maxCMapEntries {
+		t.Errorf("cmap holds %d codes, limit is %d", len(cmap), maxCMapEntries)
+	}
+}
+
+func TestExtractPDFTextSizeLimit(t *testing.T) {
+	line := "(" + strings.Repeat("x", 1000) + ") Tj\n"
+	content := "BT " + strings.Repeat(line, 2*maxPDFTextSize/1000) + "ET"
+	text, err := ExtractPDFText(buildPDF(pdfStream{body: []byte(content)}))
+	if err != nil {
+		t.Fatalf("ExtractPDFText() error = %v", err)
+	}
+	if len(text) > maxPDFTextSize+1000 {
+		t.Errorf("extracted %d bytes, limit is %d", len(text), maxPDFTextSize)
+	}
+}
@@ -0,0 +1,15 @@
+package utils
+
+import "unicode/utf8"
+
+// TruncateString cuts value to at most max bytes without splitting a UTF-8
+// sequence.
+func TruncateString(value string, max int) string {
+	if len(value) <= max {
+		return value
+	}
+	for max > 0 && !utf8.RuneStart(value[max]) {
+		max--
+	}
+	return value[:max]
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		value string
+		max   int
+		want  string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 5, "hello"},
+		{"hello", 3, "hel"},
+		{"héllo", 2, "h"},
+		{"héllo", 3, "hé"},
+		{"日本語", 4, "日"},
+		{"hello", 0, ""},
+	}
+	for _, test := range tests {
+		got := TruncateString(test.value, test.max)
+		if got != test.want {
+			t.Errorf("TruncateString(%q, %d) = %q, want %q", test.value, test.max, got, test.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("TruncateString(%q, %d) = %q is not valid UTF-8", test.value, test.max, got)
+		}
+	}
+}